@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+//***************************************************************************************************
+//**																							   **
+//**							The following section Manages the Attribute Based Access           **
+//**                                                                                               **
+//***************************************************************************************************
+
+//-------------------------------------Attribute Based Access----------------------------------------
+//Fabric CA can embed attributes in the enrollment certificates it issues. A certificate with the
+//tenant.id attribute is the identity of that tenant, enrolling the tenant binds it to the identity
+//and from then on only that identity, another certificate the CA issued for the tenant or an identity
+//given the tenant role can act for it. Tenants enrolled without the attribute stay unbound and open to
+//everyone, as before. On top of that, a platform admin can give a tenant or delegation transaction an
+//access policy, a list of attribute rules of which the certificate of the caller must satisfy one
+
+//object type of the composite key of the access policies
+const accessPolicyObjectType = "accesspolicy"
+
+//attrTenantID is the certificate attribute that names the tenant an identity was enrolled for
+const attrTenantID = "tenant.id"
+
+//partyPlaceholder is the rule value that stands for the party a transaction acts for
+const partyPlaceholder = "$party"
+
+//policedTransactions are the transactions that can be given an access policy. The party of the tenant
+//transactions is the tenant, of the Register, Suspend and Resume transactions the grandor, of the Revoke
+//transactions the revoker, of AcceptGrant, DeclineGrant and TransferDelegation the recipient and of
+//ApproveTransfer the grandor
+var policedTransactions = []string{
+	"Enroll", "Update", "DestroyTenant", "EraseTenant",
+	"RegisterDelegation", "SuspendDelegation", "ResumeDelegation", "RevokeDelegation",
+	"RegisterSubDelegation", "SuspendSubDelegation", "ResumeSubDelegation", "RevokeSubDelegation",
+	"AcceptGrant", "DeclineGrant", "TransferDelegation", "ApproveTransfer",
+}
+
+//AttributeRule is satisfied by a certificate whose attribute has the value, $party as value must be
+//the party the transaction acts for. An attribute can hold a comma separated list of values
+type AttributeRule struct {
+	Attribute string `json:"attribute"`
+	Value     string `json:"value"`
+}
+
+//AccessPolicy lists the attribute rules of a transaction, the caller must satisfy one of them
+type AccessPolicy struct {
+	Transaction string          `json:"transaction"`
+	Rules       []AttributeRule `json:"rules"`
+	Type        string          `json:"Type"` //AP for AccessPolicy
+}
+
+//attributeValues returns the values of a certificate attribute of the submitting client, nil when its
+//certificate does not have it
+func attributeValues(ctx contractapi.TransactionContextInterface, name string) ([]string, error) {
+	value, found, err := cid.GetAttributeValue(ctx.GetStub(), name)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read the attributes of the client. %s", err.Error())
+	}
+	if !found {
+		return nil, nil
+	}
+
+	values := []string{}
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values, nil
+}
+
+//accessPolicyKey returns the key of the access policy of a transaction
+func accessPolicyKey(ctx contractapi.TransactionContextInterface, transaction string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(accessPolicyObjectType, []string{transaction})
+	if err != nil {
+		return "", fmt.Errorf("Failed to create the access policy key. %s", err.Error())
+	}
+	return key, nil
+}
+
+//checkAccessPolicy checks the certificate of the submitting client against the access policy of a
+//transaction acting for party, transactions without a policy are not restricted
+func checkAccessPolicy(ctx contractapi.TransactionContextInterface, transaction string, party string) error {
+	key, err := accessPolicyKey(ctx, transaction)
+	if err != nil {
+		return err
+	}
+	policyAsBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if policyAsBytes == nil {
+		return nil
+	}
+
+	policy := new(AccessPolicy)
+	_ = json.Unmarshal(policyAsBytes, policy)
+
+	var wanted []string
+	for _, rule := range policy.Rules {
+		value := rule.Value
+		if value == partyPlaceholder {
+			value = party
+		}
+
+		values, err := attributeValues(ctx, rule.Attribute)
+		if err != nil {
+			return err
+		}
+		if stringInSlice(value, values) {
+			return nil
+		}
+		wanted = append(wanted, rule.Attribute+"="+value)
+	}
+
+	return fmt.Errorf("%s needs a certificate with one of the attributes %s", transaction, strings.Join(wanted, ", "))
+}
+
+//bindingIdentity returns the identity and organization an enrolling tenant is bound to, empty when the
+//certificate of the client was not issued for a tenant. Such a certificate can only enroll its tenant
+func bindingIdentity(ctx contractapi.TransactionContextInterface, pck string) (string, string, error) {
+	tenantids, err := attributeValues(ctx, attrTenantID)
+	if err != nil || tenantids == nil {
+		return "", "", err
+	}
+	if !stringInSlice(pck, tenantids) {
+		return "", "", fmt.Errorf("The certificate of the client was issued for %s, not for %s", strings.Join(tenantids, ", "), pck)
+	}
+
+	identity, err := clientID(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	mspid, err := clientMSPID(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	return identity, mspid, nil
+}
+
+//authorizeTenant checks that the submitting identity can act for pck when it is a tenant bound to an
+//identity or a group, services and unbound tenants are not checked
+func (s *SmartContract) authorizeTenant(ctx contractapi.TransactionContextInterface, pck string) error {
+	tenant, err := s.IsTenant(ctx, pck)
+	if err != nil {
+		return err
+	}
+	if tenant.Type == "G" {
+		group, err := s.IsGroup(ctx, pck)
+		if err != nil {
+			return err
+		}
+		return s.authorizeMember(ctx, group)
+	}
+	if tenant.Type != "T" || tenant.Identity == "" {
+		return nil
+	}
+
+	identity, err := clientID(ctx)
+	if err != nil {
+		return err
+	}
+	mspid, err := clientMSPID(ctx)
+	if err != nil {
+		return err
+	}
+	if mspid == tenant.IdentityMSP {
+		if identity == tenant.Identity {
+			return nil
+		}
+		tenantids, err := attributeValues(ctx, attrTenantID)
+		if err != nil {
+			return err
+		}
+		if stringInSlice(pck, tenantids) {
+			return nil
+		}
+	}
+
+	bound, err := hasRole(ctx, roleTenant, pck)
+	if err != nil || bound {
+		return err
+	}
+
+	return fmt.Errorf("%s is bound to an identity of %s, the client cannot act for it", pck, tenant.IdentityMSP)
+}
+
+//SetAccessPolicy sets the attribute rules of one of the policedTransactions, rules is a JSON list such
+//as [{"attribute":"tenant.id","value":"$party"},{"attribute":"role","value":"operator"}], an empty
+//list removes the policy. Only a platform admin can set it
+func (s *SmartContract) SetAccessPolicy(ctx contractapi.TransactionContextInterface, transaction string, rules string) error {
+	if err := requirePlatformAdmin(ctx); err != nil {
+		return err
+	}
+	if !stringInSlice(transaction, policedTransactions) {
+		return fmt.Errorf("%s cannot be given an access policy", transaction)
+	}
+
+	policy := AccessPolicy{Transaction: transaction, Rules: []AttributeRule{}, Type: "AP"}
+	decoder := json.NewDecoder(bytes.NewReader([]byte(rules)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&policy.Rules); err != nil {
+		return fmt.Errorf("rules must be a JSON list of attribute rules. %s", err.Error())
+	}
+	for _, rule := range policy.Rules {
+		if rule.Attribute == "" || rule.Value == "" {
+			return fmt.Errorf("Every rule needs an attribute and a value")
+		}
+	}
+
+	key, err := accessPolicyKey(ctx, transaction)
+	if err != nil {
+		return err
+	}
+	if len(policy.Rules) == 0 {
+		return ctx.GetStub().DelState(key)
+	}
+
+	policyAsBytes, _ := json.Marshal(policy)
+
+	return ctx.GetStub().PutState(key, policyAsBytes)
+}
+
+//GetAccessPolicies returns the access policy of every transaction that has one
+func (s *SmartContract) GetAccessPolicies(ctx contractapi.TransactionContextInterface) ([]*AccessPolicy, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(accessPolicyObjectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	defer iterator.Close()
+
+	policies := []*AccessPolicy{}
+	for iterator.HasNext() {
+		response, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+		}
+
+		policy := new(AccessPolicy)
+		_ = json.Unmarshal(response.Value, policy)
+		policies = append(policies, policy)
+	}
+
+	return policies, nil
+}
+
+//--------------------------------------End Of Attribute Based Access--------------------------------
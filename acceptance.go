@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+//***************************************************************************************************
+//**																							   **
+//**							The following section Manages the Acceptance of Grants             **
+//**                                                                                               **
+//***************************************************************************************************
+
+//-------------------------------------Grant Acceptance----------------------------------------------
+//a Delegation or SubDelegation is Pending from its creation until its recipient accepts it. A pending
+//grant gives no access, cannot be used, charged or subdelegated, but it already holds its share of the
+//budget of its parent. The recipient can decline it instead, a declined grant gives its slot and its
+//resources back to its parent and the hold placed on the wallet of its payer is released. Grants
+//created before acceptance existed, and Delegations created from an accepted proposal, are not pending
+
+//authorizeRecipient checks that the submitting identity can act for the recipient of a grant. Only the
+//owning organization can for a service, only its identity for a tenant bound to one and any bound
+//member for a group, whichever kind of grant it received
+func (s *SmartContract) authorizeRecipient(ctx contractapi.TransactionContextInterface, grant *Delegation) error {
+	return s.authorizeParty(ctx, grant.Recipient)
+}
+
+//pendingGrant returns the grant with given Pck (Key) when its recipient can still accept or decline it
+func (s *SmartContract) pendingGrant(ctx contractapi.TransactionContextInterface, pck string) (*Delegation, error) {
+	grant, err := s.IsDelegation(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+	if grant.Type != "D" && grant.Type != "SD" {
+		return nil, fmt.Errorf("%s is not a Delegation or SubDelegation", pck)
+	}
+	if !grant.Pending {
+		return nil, fmt.Errorf("%s is not waiting for its recipient", pck)
+	}
+	if grant.Revoked {
+		return nil, fmt.Errorf("%s has been revoked", pck)
+	}
+
+	if err := s.authorizeRecipient(ctx, grant); err != nil {
+		return nil, err
+	}
+
+	return grant, nil
+}
+
+//putGrant stores a grant and emits it as event
+func putGrant(ctx contractapi.TransactionContextInterface, grant *Delegation, event string) error {
+	grantAsBytes, _ := json.Marshal(grant)
+	if err := ctx.GetStub().PutState(grant.Pck, grantAsBytes); err != nil {
+		return err
+	}
+
+	return ctx.GetStub().SetEvent(event, grantAsBytes)
+}
+
+//AcceptGrant accepts the Delegation or SubDelegation with given Pck (Key) on behalf of its recipient,
+//from then on it gives access and is charged. An expired grant cannot be accepted any more, declining it
+//releases its hold
+func (s *SmartContract) AcceptGrant(ctx contractapi.TransactionContextInterface, pck string) error {
+	grant, err := s.pendingGrant(ctx, pck)
+	if err != nil {
+		return err
+	}
+	if err := checkAccessPolicy(ctx, "AcceptGrant", grant.Recipient); err != nil {
+		return err
+	}
+	now, err := txSeconds(ctx)
+	if err != nil {
+		return err
+	}
+	if grant.Expiry <= uint64(now) {
+		return fmt.Errorf("%s has expired, it can only be declined", pck)
+	}
+
+	grant.Pending = false
+
+	return putGrant(ctx, grant, "GrantAccepted")
+}
+
+//DeclineGrant declines the Delegation or SubDelegation with given Pck (Key) on behalf of its recipient,
+//its budget returns to its parent and the hold placed for it is released
+func (s *SmartContract) DeclineGrant(ctx contractapi.TransactionContextInterface, pck string) error {
+	grant, err := s.pendingGrant(ctx, pck)
+	if err != nil {
+		return err
+	}
+	if err := checkAccessPolicy(ctx, "DeclineGrant", grant.Recipient); err != nil {
+		return err
+	}
+
+	payer, err := s.payingTenant(ctx, grant.Recipient)
+	if err != nil {
+		return err
+	}
+	hold, err := findHold(ctx, payer, pck)
+	if err != nil {
+		return err
+	}
+	if hold != nil {
+		hold.Amount.Amount = 0
+		if err := putHold(ctx, hold); err != nil {
+			return err
+		}
+	}
+
+	grant.Pending = false
+	grant.Declined = true
+
+	return putGrant(ctx, grant, "GrantDeclined")
+}
+
+//--------------------------------------End Of Grant Acceptance--------------------------------------
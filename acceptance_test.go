@@ -0,0 +1,17 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAnExpiredGrantCanOnlyBeDeclined(t *testing.T) {
+	l := initLedger(t)
+	l.grant("D1", "S1", "S2", nil)
+	now := time.Now()
+	l.ok("RegisterSubDelegation", l.RegisterSubDelegation("SD1", "D1", "T3", 1, now.Add(-time.Hour), now.Add(time.Second), nil))
+	time.Sleep(1100 * time.Millisecond)
+
+	l.refused("accepting an expired grant", l.AcceptGrant("SD1"))
+	l.ok("DeclineGrant", l.DeclineGrant("SD1"))
+}
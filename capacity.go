@@ -0,0 +1,226 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+//***************************************************************************************************
+//**																							   **
+//**							The following section Manages the Capacity Ledger                  **
+//**                                                                                               **
+//***************************************************************************************************
+
+//-------------------------------------Capacity Management-------------------------------------------
+//every Delegation and SubDelegation has its own Capacity record which holds how deep the grant
+//can be subdelegated and how many direct children it can have. Each child that is created is
+//recorded as its own Allocation entry, so the consumed and available counts are never stored
+//but always recomputed from the allocations and the state of the children they point to
+
+//object types used to build the composite keys of the capacity ledger
+const (
+	capacityObjectType   = "capacity"
+	allocationObjectType = "allocation"
+)
+
+//Capacity describes the subdelegation budget of a Delegation or SubDelegation
+type Capacity struct {
+	Grant       string `json:"grant"`       //pck of the Delegation or SubDelegation
+	MaxDepth    uint8  `json:"maxdepth"`    //how many levels of subdelegation are allowed below the grant
+	MaxChildren uint8  `json:"maxchildren"` //how many direct children the grant can have at the same time
+	Consumed    uint8  `json:"consumed"`    //computed on read, children that are still active
+	Available   uint8  `json:"available"`   //computed on read, MaxChildren - Consumed
+	Type        string `json:"Type"`        //C for Capacity
+}
+
+//Allocation records a single child that has been created under a grant
+type Allocation struct {
+	Grant   string `json:"grant"`   //pck of the parent grant
+	Child   string `json:"child"`   //pck of the SubDelegation created under the grant
+	Depth   uint8  `json:"depth"`   //the subdel given to the child
+	Created uint64 `json:"created"` //timestamp of the allocation
+	Active  bool   `json:"active"`  //computed on read, true while the child holds a slot
+	Type    string `json:"Type"`    //AL for Allocation
+}
+
+//newCapacity builds the capacity record of a freshly created grant, by default a grant can have as
+//many direct children as its subdel, which is the most the old in place budget ever allowed
+func newCapacity(pck string, subdel uint8) Capacity {
+	return Capacity{
+		Grant:       pck,
+		MaxDepth:    subdel,
+		MaxChildren: subdel,
+		Type:        "C",
+	}
+}
+
+//putCapacity stores the capacity record of a grant under its composite key
+func putCapacity(ctx contractapi.TransactionContextInterface, capacity Capacity) error {
+	key, err := ctx.GetStub().CreateCompositeKey(capacityObjectType, []string{capacity.Grant})
+	if err != nil {
+		return fmt.Errorf("Failed to create the capacity key. %s", err.Error())
+	}
+
+	//the computed counters are never stored
+	capacity.Consumed = 0
+	capacity.Available = 0
+
+	capacityAsBytes, _ := json.Marshal(capacity)
+
+	return ctx.GetStub().PutState(key, capacityAsBytes)
+}
+
+//recordAllocation stores a new allocation entry of child under grant
+func recordAllocation(ctx contractapi.TransactionContextInterface, grant string, child string, depth uint8) error {
+	key, err := ctx.GetStub().CreateCompositeKey(allocationObjectType, []string{grant, child})
+	if err != nil {
+		return fmt.Errorf("Failed to create the allocation key. %s", err.Error())
+	}
+
+	created, err := txSeconds(ctx)
+	if err != nil {
+		return err
+	}
+
+	allocation := Allocation{
+		Grant:   grant,
+		Child:   child,
+		Depth:   depth,
+		Created: uint64(created),
+		Type:    "AL",
+	}
+
+	allocationAsBytes, _ := json.Marshal(allocation)
+
+	return ctx.GetStub().PutState(key, allocationAsBytes)
+}
+
+//holdsSlot checks if a child still consumes a slot of its parent, suspended, revoked and
+//expired children give their slot back and take it again if they become active once more,
+//declined children give it back for good
+func (s *SmartContract) holdsSlot(ctx contractapi.TransactionContextInterface, child string) (bool, error) {
+	subdelegation, err := s.IsSubDelegation(ctx, child)
+	if err != nil {
+		return false, err
+	}
+
+	now, err := txSeconds(ctx)
+	if err != nil {
+		return false, err
+	}
+	timenow := uint64(now)
+
+	return !subdelegation.Suspended && !subdelegation.Revoked && !subdelegation.Declined && subdelegation.Expiry > timenow, nil
+}
+
+//GetAllocations returns every allocation entry recorded under the grant with given Pck (Key)
+func (s *SmartContract) GetAllocations(ctx contractapi.TransactionContextInterface, pck string) ([]*Allocation, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(allocationObjectType, []string{pck})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	defer iterator.Close()
+
+	allocations := []*Allocation{}
+	for iterator.HasNext() {
+		response, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+		}
+
+		allocation := new(Allocation)
+		_ = json.Unmarshal(response.Value, allocation)
+
+		allocation.Active, err = s.holdsSlot(ctx, allocation.Child)
+		if err != nil {
+			return nil, err
+		}
+
+		allocations = append(allocations, allocation)
+	}
+
+	return allocations, nil
+}
+
+//GetCapacity returns the capacity of the grant with given Pck (Key) with the consumed and
+//available counts recomputed from its allocations
+func (s *SmartContract) GetCapacity(ctx contractapi.TransactionContextInterface, pck string) (*Capacity, error) {
+	//the grant itself must exist, Delegations and SubDelegations share the same fields
+	delegation, err := s.IsDelegation(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(capacityObjectType, []string{pck})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create the capacity key. %s", err.Error())
+	}
+
+	capacityAsBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+
+	//grants created before the capacity ledger existed get the default capacity of their subdel
+	capacity := new(Capacity)
+	if capacityAsBytes == nil {
+		*capacity = newCapacity(pck, delegation.Subdel)
+	} else {
+		_ = json.Unmarshal(capacityAsBytes, capacity)
+	}
+
+	allocations, err := s.GetAllocations(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+
+	var consumed uint8
+	for _, allocation := range allocations {
+		if allocation.Active {
+			consumed++
+		}
+	}
+
+	capacity.Consumed = consumed
+	if capacity.MaxChildren > consumed {
+		capacity.Available = capacity.MaxChildren - consumed
+	}
+
+	return capacity, nil
+}
+
+//SetMaxChildren changes how many direct children the grant with given Pck (Key) can have,
+//on behalf of its grandor, it cannot be set below the number of children that are currently active
+func (s *SmartContract) SetMaxChildren(ctx contractapi.TransactionContextInterface, pck string, maxchildren string) error {
+	tempmaxchildren, err := strconv.ParseUint(maxchildren, 10, 8)
+	if err != nil {
+		return fmt.Errorf("maxchildren must be a number between 0 and 255")
+	}
+
+	//only the grandor decides how far the recipient can subdelegate
+	grant, err := s.IsDelegation(ctx, pck)
+	if err != nil {
+		return err
+	}
+	if err := s.authorizeParty(ctx, grant.Grandor); err != nil {
+		return err
+	}
+
+	capacity, err := s.GetCapacity(ctx, pck)
+	if err != nil {
+		return err
+	}
+
+	if uint8(tempmaxchildren) < capacity.Consumed {
+		return fmt.Errorf("%s already has %d active children", pck, capacity.Consumed)
+	}
+
+	capacity.MaxChildren = uint8(tempmaxchildren)
+
+	return putCapacity(ctx, *capacity)
+}
+
+//--------------------------------------End Of Capacity Management-----------------------------------
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/Kthanasis/Tenant-Service-Cloud-Management-using-Blockchain/client"
+	"github.com/Kthanasis/Tenant-Service-Cloud-Management-using-Blockchain/client/local"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+//the tests run the contract through the client package over the in-process chaincode of client/local,
+//whose writes only reach the world state once a transaction succeeds, as on a peer
+
+//attributeOID is the certificate extension the Fabric CA puts the attributes of an identity in
+var attributeOID = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 7, 8, 1}
+
+//certificate returns a self signed PEM certificate for cn, an admin has the admin organizational unit
+//and attrs is the JSON of the Fabric CA attributes, such as {"attrs":{"tenant.id":"T9"}}
+func certificate(t *testing.T, cn string, admin bool, attrs string) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subject := pkix.Name{CommonName: cn}
+	if admin {
+		subject.OrganizationalUnit = []string{"admin"}
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      subject,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	if attrs != "" {
+		template.ExtraExtensions = []pkix.Extension{{Id: attributeOID, Value: []byte(attrs)}}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+//ledger is a client over a fresh in-process chaincode and the identities the tests switch between
+type ledger struct {
+	*client.Client
+	t         *testing.T
+	transport *local.ChaincodeTransport
+	admin     []byte //admin of Org1MSP, the deploying organization and platform admin
+}
+
+//newLedger returns a ledger whose InitLedger has not run yet, the admin of Org1MSP submits
+func newLedger(t *testing.T) *ledger {
+	chaincode, err := contractapi.NewChaincode(new(SmartContract))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := &ledger{
+		t:         t,
+		transport: local.NewChaincodeTransport(chaincode),
+		admin:     certificate(t, "admin", true, ""),
+	}
+	l.Client = client.New(l.transport)
+	l.asAdmin()
+
+	return l
+}
+
+//initLedger returns a ledger with the base tenants and services, bootstrapped by the admin of Org1MSP
+func initLedger(t *testing.T) *ledger {
+	l := newLedger(t)
+	l.ok("InitLedger", l.InitLedger(""))
+	return l
+}
+
+//as makes the following transactions come from the identity with certificate cert in mspid
+func (l *ledger) as(mspid string, cert []byte) {
+	l.t.Helper()
+	if err := l.transport.SetIdentity(mspid, cert); err != nil {
+		l.t.Fatal(err)
+	}
+}
+
+//asAdmin makes the following transactions come from the platform admin
+func (l *ledger) asAdmin() {
+	l.as("Org1MSP", l.admin)
+}
+
+//ok fails the test when what failed
+func (l *ledger) ok(what string, err error) {
+	l.t.Helper()
+	if err != nil {
+		l.t.Fatalf("%s: %v", what, err)
+	}
+}
+
+//refused fails the test when what did not fail
+func (l *ledger) refused(what string, err error) {
+	l.t.Helper()
+	if err == nil {
+		l.t.Fatalf("%s was not refused", what)
+	}
+}
+
+//grant registers a Delegation from grandor to recipient issued three hours ago and valid for the next
+//hour, accepted by its recipient
+func (l *ledger) grant(pck string, grandor string, recipient string, scope *client.Scope) {
+	l.t.Helper()
+	now := time.Now()
+	l.ok("RegisterDelegation "+pck, l.RegisterDelegation(pck, grandor, recipient, 3, now.Add(-3*time.Hour), now.Add(time.Hour), scope))
+	l.ok("AcceptGrant "+pck, l.AcceptGrant(pck))
+}
+
+//subgrant registers a SubDelegation of exdelegation to recipient issued an hour ago and valid for the
+//next hour, accepted by its recipient
+func (l *ledger) subgrant(pck string, exdelegation string, recipient string, subdel uint8, scope *client.Scope) {
+	l.t.Helper()
+	now := time.Now()
+	l.ok("RegisterSubDelegation "+pck, l.RegisterSubDelegation(pck, exdelegation, recipient, subdel, now.Add(-time.Hour), now.Add(time.Hour), scope))
+	l.ok("AcceptGrant "+pck, l.AcceptGrant(pck))
+}
+
+//overdue waits until the invoices due now are overdue with no grace, transactions take their time from
+//the clock
+func overdue() {
+	time.Sleep(1100 * time.Millisecond)
+}
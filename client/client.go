@@ -0,0 +1,1068 @@
+package client
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+//Client wraps the transactions of the tenant service chaincode with typed methods
+type Client struct {
+	transport Transport
+}
+
+//New returns a Client that sends its transactions through the given Transport
+func New(transport Transport) *Client {
+	return &Client{transport: transport}
+}
+
+//--------------------------------------------Helping Functions-------------------------------------
+
+//unix turns a time into the Unix seconds string the contract expects
+func unix(t time.Time) string {
+	return strconv.FormatInt(t.Unix(), 10)
+}
+
+//scopeArgument turns a scope into the JSON argument the contract expects, nil is an empty argument
+func scopeArgument(scope *Scope) (string, error) {
+	if scope == nil {
+		return "", nil
+	}
+	scopeAsBytes, err := json.Marshal(scope)
+	if err != nil {
+		return "", err
+	}
+	return string(scopeAsBytes), nil
+}
+
+func (c *Client) submit(name string, args ...string) error {
+	_, err := c.transport.Submit(name, args...)
+	return err
+}
+
+func (c *Client) submitJSON(out interface{}, name string, args ...string) error {
+	payload, err := c.transport.Submit(name, args...)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(payload, out); err != nil {
+		return fmt.Errorf("%s returned an invalid payload: %s", name, err.Error())
+	}
+
+	return nil
+}
+
+func (c *Client) evaluateJSON(out interface{}, name string, args ...string) error {
+	payload, err := c.transport.Evaluate(name, args...)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(payload, out); err != nil {
+		return fmt.Errorf("%s returned an invalid payload: %s", name, err.Error())
+	}
+
+	return nil
+}
+
+func (c *Client) evaluateBool(name string, args ...string) (bool, error) {
+	payload, err := c.transport.Evaluate(name, args...)
+	if err != nil {
+		return false, err
+	}
+
+	return strconv.ParseBool(string(payload))
+}
+
+//--------------------------------------------Ledger------------------------------------------------
+
+//InitLedger adds the base set of tenants and services to the ledger. The first call bootstraps the role
+//registry, it must come from an admin of adminmsp, the deploying organization, or of its own organization
+//when adminmsp is empty, who becomes the platform admin. Later calls need a platform admin
+func (c *Client) InitLedger(adminmsp string) error {
+	return c.submit("InitLedger", adminmsp)
+}
+
+//IndexGrants indexes the grants stored before the grant index existed, once after an upgrade. Only a
+//platform admin can run it
+func (c *Client) IndexGrants() error {
+	return c.submit("IndexGrants")
+}
+
+//--------------------------------------------Roles-------------------------------------------------
+
+//AssignRole gives role to the client identity of the organization msp, scope is the service of a
+//service-operator or the tenant of a tenant role and empty for the others. Only a platform admin assigns
+//the platform-admin, billing and auditor roles
+func (c *Client) AssignRole(identity string, msp string, role string, scope string) error {
+	return c.submit("AssignRole", identity, msp, role, scope)
+}
+
+//RevokeRole takes role with scope away from the client identity
+func (c *Client) RevokeRole(identity string, role string, scope string) error {
+	return c.submit("RevokeRole", identity, role, scope)
+}
+
+//WhoAmI returns the identity the client submits with and the roles it holds
+func (c *Client) WhoAmI() (*CallerIdentity, error) {
+	caller := new(CallerIdentity)
+	if err := c.evaluateJSON(caller, "WhoAmI"); err != nil {
+		return nil, err
+	}
+	return caller, nil
+}
+
+//GetRoleAssignments returns every assignment of the role registry, to auditors and admins
+func (c *Client) GetRoleAssignments() ([]*RoleAssignment, error) {
+	var assignments []*RoleAssignment
+	if err := c.evaluateJSON(&assignments, "GetRoleAssignments"); err != nil {
+		return nil, err
+	}
+	return assignments, nil
+}
+
+//GetRoleChanges returns the role audit trail in the order the changes were made, to auditors and admins
+func (c *Client) GetRoleChanges() ([]*RoleChange, error) {
+	var changes []*RoleChange
+	if err := c.evaluateJSON(&changes, "GetRoleChanges"); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+//SetAccessPolicy sets the attribute rules the certificate of the caller of a tenant or delegation
+//transaction must satisfy one of, no rules remove the policy. Only a platform admin can set it
+func (c *Client) SetAccessPolicy(transaction string, rules []AttributeRule) error {
+	if rules == nil {
+		rules = []AttributeRule{}
+	}
+	rulesAsBytes, err := json.Marshal(rules)
+	if err != nil {
+		return err
+	}
+	return c.submit("SetAccessPolicy", transaction, string(rulesAsBytes))
+}
+
+//GetAccessPolicies returns the access policy of every transaction that has one
+func (c *Client) GetAccessPolicies() ([]*AccessPolicy, error) {
+	var policies []*AccessPolicy
+	if err := c.evaluateJSON(&policies, "GetAccessPolicies"); err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+//-----------------------------------------Grant Policy---------------------------------------------
+
+//SetGrantRule allows or forbids grants of type grant (D or SD) from the entity type from to the entity type
+//to (S, T or G). Only a platform admin can set it
+func (c *Client) SetGrantRule(grant string, from string, to string, allowed bool) error {
+	return c.submit("SetGrantRule", grant, from, to, strconv.FormatBool(allowed))
+}
+
+//GetGrantPolicy returns every cell of the grant policy matrix
+func (c *Client) GetGrantPolicy() ([]*GrantRule, error) {
+	var rules []*GrantRule
+	if err := c.evaluateJSON(&rules, "GetGrantPolicy"); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+//------------------------------------Deregistration Cascade----------------------------------------
+
+//SetCascadePolicy sets what happens to the active grants of a principal of type principal (T, S or G)
+//when it is deregistered, mode is revoke, suspend or block
+func (c *Client) SetCascadePolicy(principal string, mode string) error {
+	return c.submit("SetCascadePolicy", principal, mode)
+}
+
+//GetCascadePolicies returns the cascade policy of every entity type that can be deregistered
+func (c *Client) GetCascadePolicies() ([]*CascadePolicy, error) {
+	var policies []*CascadePolicy
+	if err := c.evaluateJSON(&policies, "GetCascadePolicies"); err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+//GetDependentGrants returns the active grants a tenant, service or group is the grandor or recipient
+//of, the grants its deregistration would revoke, suspend or be blocked by
+func (c *Client) GetDependentGrants(pck string) ([]*Delegation, error) {
+	var grants []*Delegation
+	if err := c.evaluateJSON(&grants, "GetDependentGrants", pck); err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+//--------------------------------------------Tenants-----------------------------------------------
+
+//contactTransient puts the contact details in the transient map the contract reads them from, with
+//the random salt of their public hash
+func contactTransient(contact *Contact) (map[string][]byte, error) {
+	if contact == nil {
+		return nil, nil
+	}
+
+	contactAsBytes, err := json.Marshal(contact)
+	if err != nil {
+		return nil, err
+	}
+	transient, err := saltTransient()
+	if err != nil {
+		return nil, err
+	}
+	transient["contact"] = contactAsBytes
+	return transient, nil
+}
+
+//saltTransient returns a transient map holding a new random salt for the contact hash
+func saltTransient() (map[string][]byte, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("generating the contact salt failed: %s", err.Error())
+	}
+	return map[string][]byte{"salt": salt}, nil
+}
+
+//Enroll adds a new tenant with the given name, the contact details are optional and are sent
+//through the transient map so they only reach the private data collection. A certificate with the
+//tenant.id attribute can only enroll that tenant and binds a new tenant to the identity. Enrolling an
+//existing tenant again keeps its binding, a deregistered tenant cannot be enrolled again
+func (c *Client) Enroll(pck string, name string, contact *Contact) error {
+	transient, err := contactTransient(contact)
+	if err != nil {
+		return err
+	}
+	_, err = c.transport.SubmitTransient("Enroll", transient, pck, name)
+	return err
+}
+
+//Update replaces the name of a tenant, and its contact details when contact is not nil
+func (c *Client) Update(pck string, name string, contact *Contact) error {
+	transient, err := contactTransient(contact)
+	if err != nil {
+		return err
+	}
+	_, err = c.transport.SubmitTransient("Update", transient, pck, name)
+	return err
+}
+
+//GetTenantContact returns the private contact details of a tenant, only organizations allowed by
+//the contract can read them
+func (c *Client) GetTenantContact(pck string) (*TenantContact, error) {
+	contact := new(TenantContact)
+	if err := c.evaluateJSON(contact, "GetTenantContact", pck); err != nil {
+		return nil, err
+	}
+	return contact, nil
+}
+
+//MigrateTenantContact moves the contact details of a tenant enrolled by an older contract from
+//its public record to the private data collection, on behalf of the tenant or a platform admin
+func (c *Client) MigrateTenantContact(pck string) error {
+	transient, err := saltTransient()
+	if err != nil {
+		return err
+	}
+	_, err = c.transport.SubmitTransient("MigrateTenantContact", transient, pck)
+	return err
+}
+
+//DestroyTenant marks a tenant as no longer registered, the cascade policy of tenants applies to its grants
+func (c *Client) DestroyTenant(pck string) error {
+	return c.submit("DestroyTenant", pck)
+}
+
+//EraseTenant erases the personal data of a tenant, the cascade policy of tenants applies to its grants
+//first and the grants it still receives are transferred to successor or revoked when successor is empty
+func (c *Client) EraseTenant(pck string, successor string) error {
+	return c.submit("EraseTenant", pck, successor)
+}
+
+//GetErasureReceipt returns the receipt recorded when a tenant was erased
+func (c *Client) GetErasureReceipt(pck string) (*ErasureReceipt, error) {
+	receipt := new(ErasureReceipt)
+	if err := c.evaluateJSON(receipt, "GetErasureReceipt", pck); err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+//IsTenant returns the tenant with the given pck
+func (c *Client) IsTenant(pck string) (*Tenant, error) {
+	tenant := new(Tenant)
+	if err := c.evaluateJSON(tenant, "IsTenant", pck); err != nil {
+		return nil, err
+	}
+	return tenant, nil
+}
+
+//---------------------------------------Organizations and Groups-----------------------------------
+
+//RegisterOrganization creates an organization managed by the owner tenant, its first member
+func (c *Client) RegisterOrganization(pck string, name string, owner string) error {
+	return c.submit("RegisterOrganization", pck, name, owner)
+}
+
+//RegisterGroup creates a group of an organization, on behalf of the owner of the organization
+func (c *Client) RegisterGroup(pck string, name string, organization string) error {
+	return c.submit("RegisterGroup", pck, name, organization)
+}
+
+//UnRegisterGroup unregisters a group, its grants no longer give its members access
+func (c *Client) UnRegisterGroup(pck string) error {
+	return c.submit("UnRegisterGroup", pck)
+}
+
+//AddMember adds a tenant to an organization or group, the members of a group must be members of its
+//organization. A tenant the caller cannot act for is only invited and joins with AcceptMembership
+func (c *Client) AddMember(pck string, tenant string) error {
+	return c.submit("AddMember", pck, tenant)
+}
+
+//AcceptMembership accepts the invitation of a tenant to an organization or group on behalf of the tenant
+func (c *Client) AcceptMembership(pck string, tenant string) error {
+	return c.submit("AcceptMembership", pck, tenant)
+}
+
+//RemoveMember removes a tenant from an organization or group, leaving an organization leaves its groups
+func (c *Client) RemoveMember(pck string, tenant string) error {
+	return c.submit("RemoveMember", pck, tenant)
+}
+
+//IsOrganization returns the organization with the given pck
+func (c *Client) IsOrganization(pck string) (*Organization, error) {
+	organization := new(Organization)
+	if err := c.evaluateJSON(organization, "IsOrganization", pck); err != nil {
+		return nil, err
+	}
+	return organization, nil
+}
+
+//IsGroup returns the group with the given pck
+func (c *Client) IsGroup(pck string) (*Group, error) {
+	group := new(Group)
+	if err := c.evaluateJSON(group, "IsGroup", pck); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+//GetMemberships returns the organizations and groups a tenant is a member of
+func (c *Client) GetMemberships(tenant string) ([]*Membership, error) {
+	var memberships []*Membership
+	if err := c.evaluateJSON(&memberships, "GetMemberships", tenant); err != nil {
+		return nil, err
+	}
+	return memberships, nil
+}
+
+//CheckMemberAccess reports whether a Delegation or SubDelegation gives a tenant access now, as its
+//recipient or as a member of the group that received it
+func (c *Client) CheckMemberAccess(pck string, tenant string) (bool, error) {
+	return c.evaluateBool("CheckMemberAccess", pck, tenant)
+}
+
+//--------------------------------------------Services----------------------------------------------
+
+//RegisterService creates a service with the given pck and name owned by the owner tenant, the
+//organization of the submitting identity becomes the owning organization
+func (c *Client) RegisterService(pck string, name string, owner string) error {
+	return c.submit("Register_Service", pck, name, owner)
+}
+
+//UnRegisterService marks a service as no longer registered, the cascade policy of services applies to
+//its grants
+func (c *Client) UnRegisterService(pck string) error {
+	return c.submit("UnRegister_Service", pck)
+}
+
+//TransferServiceOwnership names newowner as the next owner of a service
+func (c *Client) TransferServiceOwnership(pck string, newowner string) error {
+	return c.submit("TransferServiceOwnership", pck, newowner)
+}
+
+//AcceptServiceOwnership completes a pending ownership transfer for the organization of the
+//submitting identity
+func (c *Client) AcceptServiceOwnership(pck string) error {
+	return c.submit("AcceptServiceOwnership", pck)
+}
+
+//SetServicePrice changes the price per core and hour of a service, price is a decimal amount with an
+//optional currency such as "0.35 USD", without one the service keeps its currency
+func (c *Client) SetServicePrice(pck string, price string) error {
+	return c.submit("SetServicePrice", pck, price)
+}
+
+//SetServiceStoragePrice changes the price per GB and hour of the storage the grants of a service use,
+//price is a decimal amount with an optional currency, 0 makes the storage free
+func (c *Client) SetServiceStoragePrice(pck string, price string) error {
+	return c.submit("SetServiceStoragePrice", pck, price)
+}
+
+//SetServiceRounding sets the rounding rule a service charges with, empty uses the rule of its currency
+func (c *Client) SetServiceRounding(pck string, rounding string) error {
+	return c.submit("SetServiceRounding", pck, rounding)
+}
+
+//IsService returns the service with the given pck
+func (c *Client) IsService(pck string) (*Service, error) {
+	service := new(Service)
+	if err := c.evaluateJSON(service, "IsService", pck); err != nil {
+		return nil, err
+	}
+	return service, nil
+}
+
+//--------------------------------------------Delegations-------------------------------------------
+
+//RegisterDelegation creates a Delegation from the grandor service to the recipient service, a nil
+//scope grants everything
+func (c *Client) RegisterDelegation(pck string, grandor string, recipient string, subdel uint8, issue time.Time, expiry time.Time, scope *Scope) error {
+	scopeArg, err := scopeArgument(scope)
+	if err != nil {
+		return err
+	}
+	return c.submit("RegisterDelegation", pck, grandor, recipient, strconv.FormatUint(uint64(subdel), 10), unix(issue), unix(expiry), scopeArg)
+}
+
+//SuspendDelegation suspends a Delegation, on behalf of its grandor or by a billing identity
+func (c *Client) SuspendDelegation(pck string) error {
+	return c.submit("SuspendDelegation", pck)
+}
+
+//ResumeDelegation lifts the suspension of a Delegation, on behalf of its grandor or by a billing
+//identity. A Delegation still held by a dunning or whose payer is overdrawn stays suspended
+func (c *Client) ResumeDelegation(pck string) error {
+	return c.submit("ResumeDelegation", pck)
+}
+
+//RevokeDelegation revokes a Delegation on behalf of revoker, the Delegation stays valid until its
+//revocation policy is satisfied
+func (c *Client) RevokeDelegation(pck string, revoker string) error {
+	return c.submit("RevokeDelegation", pck, revoker)
+}
+
+//AcceptGrant accepts a pending Delegation or SubDelegation on behalf of its recipient, until then
+//the grant gives no access and is not charged. An expired grant can only be declined
+func (c *Client) AcceptGrant(pck string) error {
+	return c.submit("AcceptGrant", pck)
+}
+
+//DeclineGrant declines a pending Delegation or SubDelegation on behalf of its recipient, its budget
+//returns to its parent
+func (c *Client) DeclineGrant(pck string) error {
+	return c.submit("DeclineGrant", pck)
+}
+
+//IsDelegation returns the Delegation with the given pck
+func (c *Client) IsDelegation(pck string) (*Delegation, error) {
+	delegation := new(Delegation)
+	if err := c.evaluateJSON(delegation, "IsDelegation", pck); err != nil {
+		return nil, err
+	}
+	return delegation, nil
+}
+
+//IsValid reports whether a Delegation is inside its validity window and neither suspended nor revoked
+func (c *Client) IsValid(pck string) (bool, error) {
+	return c.evaluateBool("IsValid", pck)
+}
+
+//IsExpired reports whether a Delegation is past its expiry
+func (c *Client) IsExpired(pck string) (bool, error) {
+	return c.evaluateBool("IsExpired", pck)
+}
+
+//IsSuspended reports whether a Delegation has been suspended
+func (c *Client) IsSuspended(pck string) (bool, error) {
+	return c.evaluateBool("IsSuspended", pck)
+}
+
+//IsRevoked reports whether a Delegation has been revoked
+func (c *Client) IsRevoked(pck string) (bool, error) {
+	return c.evaluateBool("IsRevoked", pck)
+}
+
+//ChargingDel returns the cost of a Delegation computed from its recorded usage, in the currency of
+//its service
+func (c *Client) ChargingDel(pck string) (*Money, error) {
+	cost := new(Money)
+	if err := c.evaluateJSON(cost, "ChargingDel", pck); err != nil {
+		return nil, err
+	}
+	return cost, nil
+}
+
+//RecordUsage records a usage sample of a grant, it must be submitted by the organization that owns
+//the service at the root of the grant and a sample id can only be recorded once
+func (c *Client) RecordUsage(pck string, sample UsageSample) error {
+	return c.submit("RecordUsage", pck, sample.SampleID, strconv.FormatUint(sample.Timestamp, 10),
+		strconv.FormatUint(sample.Cores, 10), strconv.FormatUint(sample.Hours, 10), strconv.FormatUint(sample.Storage, 10))
+}
+
+//GetUsage returns the usage samples recorded for a grant
+func (c *Client) GetUsage(pck string) ([]*UsageSample, error) {
+	var samples []*UsageSample
+	if err := c.evaluateJSON(&samples, "GetUsage", pck); err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
+
+//IsInScope reports whether the scope of a grant allows the operation in the region, empty values
+//are not checked
+func (c *Client) IsInScope(pck string, operation string, region string) (bool, error) {
+	return c.evaluateBool("IsInScope", pck, operation, region)
+}
+
+//--------------------------------------------Proposals---------------------------------------------
+
+//ProposeDelegation proposes a Delegation which is created once the organization of the recipient
+//accepts it and, when the grandor has a countersigner, that organization countersigns it. The proposal
+//stays open for validity, the contract default when 0
+func (c *Client) ProposeDelegation(pck string, grandor string, recipient string, subdel uint8, issue time.Time, expiry time.Time, scope *Scope, validity time.Duration) (*Proposal, error) {
+	scopeArg, err := scopeArgument(scope)
+	if err != nil {
+		return nil, err
+	}
+	validityArg := ""
+	if validity > 0 {
+		validityArg = strconv.FormatInt(int64(validity/time.Second), 10)
+	}
+	proposal := new(Proposal)
+	if err := c.submitJSON(proposal, "ProposeDelegation", pck, grandor, recipient, strconv.FormatUint(uint64(subdel), 10), unix(issue), unix(expiry), scopeArg, validityArg); err != nil {
+		return nil, err
+	}
+	return proposal, nil
+}
+
+//SetCountersigner names the organization that must countersign the Delegations proposed from a service,
+//an empty mspid lets them be created without. Only a platform admin can set it
+func (c *Client) SetCountersigner(pck string, mspid string) error {
+	return c.submit("SetCountersigner", pck, mspid)
+}
+
+//GetCountersigner returns the organization that must countersign the Delegations proposed from a
+//service, empty when none has to
+func (c *Client) GetCountersigner(pck string) (string, error) {
+	payload, err := c.transport.Evaluate("GetCountersigner", pck)
+	if err != nil {
+		return "", err
+	}
+	return string(payload), nil
+}
+
+//AcceptProposal accepts a proposal on behalf of the organization that owns the recipient service
+func (c *Client) AcceptProposal(pck string) (*Proposal, error) {
+	return c.proposalStep("AcceptProposal", pck)
+}
+
+//CountersignProposal countersigns a proposal on behalf of the countersigning organization
+func (c *Client) CountersignProposal(pck string) (*Proposal, error) {
+	return c.proposalStep("CountersignProposal", pck)
+}
+
+//DeclineProposal declines a proposal on behalf of the recipient or the countersigning organization
+func (c *Client) DeclineProposal(pck string) (*Proposal, error) {
+	return c.proposalStep("DeclineProposal", pck)
+}
+
+//WithdrawProposal withdraws a proposal on behalf of the organization that owns the grandor
+func (c *Client) WithdrawProposal(pck string) (*Proposal, error) {
+	return c.proposalStep("WithdrawProposal", pck)
+}
+
+//ExpireProposal closes a proposal that was not agreed to within its validity
+func (c *Client) ExpireProposal(pck string) (*Proposal, error) {
+	return c.proposalStep("ExpireProposal", pck)
+}
+
+//proposalStep submits a transaction that moves a proposal on and returns it
+func (c *Client) proposalStep(name string, pck string) (*Proposal, error) {
+	proposal := new(Proposal)
+	if err := c.submitJSON(proposal, name, pck); err != nil {
+		return nil, err
+	}
+	return proposal, nil
+}
+
+//GetProposal returns the proposal of a Delegation
+func (c *Client) GetProposal(pck string) (*Proposal, error) {
+	proposal := new(Proposal)
+	if err := c.evaluateJSON(proposal, "GetProposal", pck); err != nil {
+		return nil, err
+	}
+	return proposal, nil
+}
+
+//GetProposals returns every proposal, open or not
+func (c *Client) GetProposals() ([]*Proposal, error) {
+	var proposals []*Proposal
+	if err := c.evaluateJSON(&proposals, "GetProposals"); err != nil {
+		return nil, err
+	}
+	return proposals, nil
+}
+
+//SetApprovalThreshold sets the most cores a Delegation from a service can grant without being
+//proposed and accepted, 0 lets every Delegation be registered directly
+func (c *Client) SetApprovalThreshold(pck string, cores uint64) error {
+	return c.submit("SetApprovalThreshold", pck, strconv.FormatUint(cores, 10))
+}
+
+//---------------------------------------Revocation Policies----------------------------------------
+
+//SetRevocationPolicy sets the rule the revokers of a Delegation or SubDelegation have to satisfy to
+//revoke it, on behalf of its grandor. The signatures collected under the previous policy are cleared
+func (c *Client) SetRevocationPolicy(pck string, policy RevocationPolicy) error {
+	policy.Grant = pck
+	policy.Signed = nil
+	policyAsBytes, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+	return c.submit("SetRevocationPolicy", pck, string(policyAsBytes))
+}
+
+//GetRevocationPolicy returns the revocation policy of a Delegation or SubDelegation, any one of its
+//revokers when none was set
+func (c *Client) GetRevocationPolicy(pck string) (*RevocationPolicy, error) {
+	policy := new(RevocationPolicy)
+	if err := c.evaluateJSON(policy, "GetRevocationPolicy", pck); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+//-------------------------------------------Schedules----------------------------------------------
+
+//SetSchedule sets the windows in which a Delegation or SubDelegation is active, on behalf of its
+//grandor. A schedule without windows removes it
+func (c *Client) SetSchedule(pck string, schedule Schedule) error {
+	schedule.Grant = pck
+	if schedule.Windows == nil {
+		schedule.Windows = []ScheduleWindow{}
+	}
+	scheduleAsBytes, err := json.Marshal(schedule)
+	if err != nil {
+		return err
+	}
+	return c.submit("SetSchedule", pck, string(scheduleAsBytes))
+}
+
+//GetSchedule returns the schedule of a Delegation or SubDelegation, without windows when it is active
+//for its whole validity
+func (c *Client) GetSchedule(pck string) (*Schedule, error) {
+	schedule := new(Schedule)
+	if err := c.evaluateJSON(schedule, "GetSchedule", pck); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+//NextActivation returns when a Delegation or SubDelegation is next active, now when it is, and the zero
+//time when it will not be active again
+func (c *Client) NextActivation(pck string) (time.Time, error) {
+	payload, err := c.transport.Evaluate("NextActivation", pck)
+	if err != nil {
+		return time.Time{}, err
+	}
+	next, err := strconv.ParseUint(string(payload), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("NextActivation returned an invalid payload: %s", err.Error())
+	}
+	if next == 0 {
+		return time.Time{}, nil
+	}
+	return time.Unix(int64(next), 0), nil
+}
+
+//--------------------------------------------Transfers---------------------------------------------
+
+//TransferDelegation asks to move a Delegation or SubDelegation to a new recipient, on behalf of its
+//current recipient. The grandor has to approve it
+func (c *Client) TransferDelegation(pck string, recipient string) error {
+	return c.submit("TransferDelegation", pck, recipient)
+}
+
+//ApproveTransfer moves a grant to the recipient of its open transfer, on behalf of its grandor. The grant is
+//pending until the new recipient accepts it with AcceptGrant
+func (c *Client) ApproveTransfer(pck string) error {
+	return c.submit("ApproveTransfer", pck)
+}
+
+//RejectTransfer closes the open transfer of a grant without moving it, on behalf of its grandor or of
+//the recipient that requested it
+func (c *Client) RejectTransfer(pck string) error {
+	return c.submit("RejectTransfer", pck)
+}
+
+//GetTransfers returns every transfer of a grant in the order they were requested
+func (c *Client) GetTransfers(pck string) ([]*GrantTransfer, error) {
+	var transfers []*GrantTransfer
+	if err := c.evaluateJSON(&transfers, "GetTransfers", pck); err != nil {
+		return nil, err
+	}
+	return transfers, nil
+}
+
+//--------------------------------------------SubDelegations----------------------------------------
+
+//RegisterSubDelegation creates a SubDelegation of parent, a Delegation or SubDelegation, to the recipient tenant,
+//the scope must be a subset of the scope of parent and a nil scope inherits it
+func (c *Client) RegisterSubDelegation(pck string, parent string, recipient string, subdel uint8, issue time.Time, expiry time.Time, scope *Scope) error {
+	scopeArg, err := scopeArgument(scope)
+	if err != nil {
+		return err
+	}
+	return c.submit("RegisterSubDelegation", pck, parent, recipient, strconv.FormatUint(uint64(subdel), 10), unix(issue), unix(expiry), scopeArg)
+}
+
+//SuspendSubDelegation suspends a SubDelegation, on behalf of its grandor or by a billing identity
+func (c *Client) SuspendSubDelegation(pck string) error {
+	return c.submit("SuspendSubDelegation", pck)
+}
+
+//ResumeSubDelegation lifts the suspension of a SubDelegation, on behalf of its grandor or by a billing
+//identity. A SubDelegation still held by a dunning or whose payer is overdrawn stays suspended
+func (c *Client) ResumeSubDelegation(pck string) error {
+	return c.submit("ResumeSubDelegation", pck)
+}
+
+//RevokeSubDelegation revokes a SubDelegation on behalf of revoker, the SubDelegation stays valid
+//until its revocation policy is satisfied
+func (c *Client) RevokeSubDelegation(pck string, revoker string) error {
+	return c.submit("RevokeSubDelegation", pck, revoker)
+}
+
+//IsSubDelegation returns the SubDelegation with the given pck
+func (c *Client) IsSubDelegation(pck string) (*SubDelegation, error) {
+	subdelegation := new(SubDelegation)
+	if err := c.evaluateJSON(subdelegation, "IsSubDelegation", pck); err != nil {
+		return nil, err
+	}
+	return subdelegation, nil
+}
+
+//IsSubValid reports whether a SubDelegation and every grant above it are currently valid
+func (c *Client) IsSubValid(pck string) (bool, error) {
+	return c.evaluateBool("IsSubValid", pck)
+}
+
+//IsSubExpired reports whether a SubDelegation is past its expiry. It is submitted because the
+//contract suspends a SubDelegation the first time it is found expired
+func (c *Client) IsSubExpired(pck string) (bool, error) {
+	payload, err := c.transport.Submit("IsSubExpired", pck)
+	if err != nil {
+		return false, err
+	}
+	return strconv.ParseBool(string(payload))
+}
+
+//IsSubSuspended reports whether a SubDelegation or a grant above it has been suspended
+func (c *Client) IsSubSuspended(pck string) (bool, error) {
+	return c.evaluateBool("IsSubSuspended", pck)
+}
+
+//IsSubRevoked reports whether a SubDelegation or a grant above it has been revoked
+func (c *Client) IsSubRevoked(pck string) (bool, error) {
+	return c.evaluateBool("IsSubRevoked", pck)
+}
+
+//CheckAccess reports whether the grant with the given pck currently gives access, it works for
+//both Delegations and SubDelegations by picking the matching validity check
+func (c *Client) CheckAccess(pck string) (bool, error) {
+	delegation, err := c.IsDelegation(pck)
+	if err != nil {
+		return false, err
+	}
+
+	switch delegation.Type {
+	case "D":
+		return c.IsValid(pck)
+	case "SD":
+		return c.IsSubValid(pck)
+	default:
+		return false, fmt.Errorf("%s is not a Delegation or SubDelegation", pck)
+	}
+}
+
+//--------------------------------------------Capacity----------------------------------------------
+
+//GetCapacity returns the subdelegation budget of a grant
+func (c *Client) GetCapacity(pck string) (*Capacity, error) {
+	capacity := new(Capacity)
+	if err := c.evaluateJSON(capacity, "GetCapacity", pck); err != nil {
+		return nil, err
+	}
+	return capacity, nil
+}
+
+//GetAllocations returns every child allocation recorded under a grant
+func (c *Client) GetAllocations(pck string) ([]*Allocation, error) {
+	var allocations []*Allocation
+	if err := c.evaluateJSON(&allocations, "GetAllocations", pck); err != nil {
+		return nil, err
+	}
+	return allocations, nil
+}
+
+//SetMaxChildren changes how many direct children a grant can have
+func (c *Client) SetMaxChildren(pck string, maxchildren uint8) error {
+	return c.submit("SetMaxChildren", pck, strconv.FormatUint(uint64(maxchildren), 10))
+}
+
+//--------------------------------------------Money-------------------------------------------------
+
+//SetCurrency sets the decimals and the rounding rule of a currency. Only a platform admin can set it
+func (c *Client) SetCurrency(code string, decimals uint8, rounding string) error {
+	return c.submit("SetCurrency", code, strconv.FormatUint(uint64(decimals), 10), rounding)
+}
+
+//GetCurrency returns the settings of a currency
+func (c *Client) GetCurrency(code string) (*Currency, error) {
+	currency := new(Currency)
+	if err := c.evaluateJSON(currency, "GetCurrency", code); err != nil {
+		return nil, err
+	}
+	return currency, nil
+}
+
+//SetExchangeRate records how many units of to one unit of from is worth, rate is a decimal number. Only
+//a platform admin can set it
+func (c *Client) SetExchangeRate(from string, to string, rate string) error {
+	return c.submit("SetExchangeRate", from, to, rate)
+}
+
+//GetExchangeRate returns the rate from one currency to another
+func (c *Client) GetExchangeRate(from string, to string) (*ExchangeRate, error) {
+	rate := new(ExchangeRate)
+	if err := c.evaluateJSON(rate, "GetExchangeRate", from, to); err != nil {
+		return nil, err
+	}
+	return rate, nil
+}
+
+//GetTenantStatement returns the charges of a tenant consolidated in currency
+func (c *Client) GetTenantStatement(pck string, currency string) (*Statement, error) {
+	statement := new(Statement)
+	if err := c.evaluateJSON(statement, "GetTenantStatement", pck, currency); err != nil {
+		return nil, err
+	}
+	return statement, nil
+}
+
+//--------------------------------------------Wallets-----------------------------------------------
+
+//OpenWallet opens a prepaid wallet in currency for a tenant, on behalf of the tenant
+func (c *Client) OpenWallet(pck string, currency string) error {
+	return c.submit("OpenWallet", pck, currency)
+}
+
+//GetWallet returns the balance, credit limit, held and available amounts of a tenant
+func (c *Client) GetWallet(pck string) (*Wallet, error) {
+	wallet := new(Wallet)
+	if err := c.evaluateJSON(wallet, "GetWallet", pck); err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
+
+//Deposit pays amount, such as "50" or "50 USD", into the wallet of a tenant. Billing identities and
+//platform admins can deposit
+func (c *Client) Deposit(pck string, amount string, reference string) (*Wallet, error) {
+	wallet := new(Wallet)
+	if err := c.submitJSON(wallet, "Deposit", pck, amount, reference); err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
+
+//Debit takes amount out of the wallet of a tenant against reference, such as an invoice. Billing
+//identities and platform admins can debit, grants are billed with BillUsage
+func (c *Client) Debit(pck string, amount string, reference string) (*Wallet, error) {
+	wallet := new(Wallet)
+	if err := c.submitJSON(wallet, "Debit", pck, amount, reference); err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
+
+//SetCreditLimit sets how far below zero the balance of a tenant may go, by a billing identity or a
+//platform admin
+func (c *Client) SetCreditLimit(pck string, limit string) (*Wallet, error) {
+	wallet := new(Wallet)
+	if err := c.submitJSON(wallet, "SetCreditLimit", pck, limit); err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
+
+//PlaceHold reserves amount on the wallet of a tenant under holdid, by a billing identity or a platform
+//admin. The holds of grants are placed and released with their grants
+func (c *Client) PlaceHold(pck string, holdid string, amount string, reason string) (*Wallet, error) {
+	wallet := new(Wallet)
+	if err := c.submitJSON(wallet, "PlaceHold", pck, holdid, amount, reason); err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
+
+//ReleaseHold gives back what is left of a hold on the wallet of a tenant, by a billing identity or a
+//platform admin
+func (c *Client) ReleaseHold(pck string, holdid string) (*Wallet, error) {
+	wallet := new(Wallet)
+	if err := c.submitJSON(wallet, "ReleaseHold", pck, holdid); err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
+
+//GetHolds returns every hold on the wallet of a tenant
+func (c *Client) GetHolds(pck string) ([]*Hold, error) {
+	var holds []*Hold
+	if err := c.evaluateJSON(&holds, "GetHolds", pck); err != nil {
+		return nil, err
+	}
+	return holds, nil
+}
+
+//GetWalletEntries returns the movements of the wallet of a tenant, oldest first
+func (c *Client) GetWalletEntries(pck string) ([]*WalletEntry, error) {
+	var entries []*WalletEntry
+	if err := c.evaluateJSON(&entries, "GetWalletEntries", pck); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+//BillUsage debits the paying tenant of a grant with the usage charged since it was last billed, the
+//grant is suspended when the wallet goes past its credit limit
+func (c *Client) BillUsage(pck string) (*Wallet, error) {
+	wallet := new(Wallet)
+	if err := c.submitJSON(wallet, "BillUsage", pck); err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
+
+//--------------------------------------------Invoices----------------------------------------------
+
+//IssueInvoice bills amount, such as "40" or "40 USD", to a tenant to be paid by due. Billing identities
+//and platform admins can issue invoices
+func (c *Client) IssueInvoice(pck string, invoiceid string, amount string, due time.Time) error {
+	return c.submit("IssueInvoice", pck, invoiceid, amount, strconv.FormatInt(due.Unix(), 10))
+}
+
+//GetInvoice returns an invoice of a tenant
+func (c *Client) GetInvoice(pck string, invoiceid string) (*Invoice, error) {
+	invoice := new(Invoice)
+	if err := c.evaluateJSON(invoice, "GetInvoice", pck, invoiceid); err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+//GetInvoices returns every invoice of a tenant
+func (c *Client) GetInvoices(pck string) ([]*Invoice, error) {
+	var invoices []*Invoice
+	if err := c.evaluateJSON(&invoices, "GetInvoices", pck); err != nil {
+		return nil, err
+	}
+	return invoices, nil
+}
+
+//PayInvoice records the payment of an invoice, from the wallet of the tenant when it has one or else
+//with the reference of the payment. The tenant can only pay from its wallet, a billing identity can
+//record either
+func (c *Client) PayInvoice(pck string, invoiceid string, reference string) (*Invoice, error) {
+	invoice := new(Invoice)
+	if err := c.submitJSON(invoice, "PayInvoice", pck, invoiceid, reference); err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+//RunDunning suspends the grants of a tenant that has invoices unpaid longer than grace after they were due,
+//by a billing identity or a platform admin
+func (c *Client) RunDunning(pck string, grace time.Duration) (*Dunning, error) {
+	dunning := new(Dunning)
+	if err := c.submitJSON(dunning, "RunDunning", pck, strconv.FormatInt(int64(grace/time.Second), 10)); err != nil {
+		return nil, err
+	}
+	return dunning, nil
+}
+
+//GetDunning returns the dunning record of a tenant
+func (c *Client) GetDunning(pck string) (*Dunning, error) {
+	dunning := new(Dunning)
+	if err := c.evaluateJSON(dunning, "GetDunning", pck); err != nil {
+		return nil, err
+	}
+	return dunning, nil
+}
+
+//--------------------------------------------Quotes------------------------------------------------
+
+//QuoteGrant estimates the most a grant of cores from grandor over issue to expiry costs, grandor is
+//the Service of a Delegation or the grant subdelegated by a SubDelegation
+func (c *Client) QuoteGrant(grandor string, cores uint64, issue time.Time, expiry time.Time) (*Quote, error) {
+	quote := new(Quote)
+	if err := c.evaluateJSON(quote, "QuoteGrant", grandor, strconv.FormatUint(cores, 10), strconv.FormatInt(issue.Unix(), 10), strconv.FormatInt(expiry.Unix(), 10)); err != nil {
+		return nil, err
+	}
+	return quote, nil
+}
+
+//IssueQuote estimates a grant and keeps the quote for the pck the grant will have, creating the grant
+//with the same grandor, window and cores locks the quoted price in. Only the grandor can issue a quote
+//and an issued quote cannot be replaced
+func (c *Client) IssueQuote(pck string, grandor string, cores uint64, issue time.Time, expiry time.Time) (*Quote, error) {
+	quote := new(Quote)
+	if err := c.submitJSON(quote, "IssueQuote", pck, grandor, strconv.FormatUint(cores, 10), strconv.FormatInt(issue.Unix(), 10), strconv.FormatInt(expiry.Unix(), 10)); err != nil {
+		return nil, err
+	}
+	return quote, nil
+}
+
+//GetQuote returns the quote issued for a grant
+func (c *Client) GetQuote(pck string) (*Quote, error) {
+	quote := new(Quote)
+	if err := c.evaluateJSON(quote, "GetQuote", pck); err != nil {
+		return nil, err
+	}
+	return quote, nil
+}
+
+//--------------------------------------------Quotas------------------------------------------------
+
+//GetQuota returns the total, allocated and free resources of a Service, Delegation or SubDelegation
+func (c *Client) GetQuota(pck string) (*Quota, error) {
+	quota := new(Quota)
+	if err := c.evaluateJSON(quota, "GetQuota", pck); err != nil {
+		return nil, err
+	}
+	return quota, nil
+}
+
+//SetServiceQuota declares the total resources of a Service, a zero amount is unlimited
+func (c *Client) SetServiceQuota(pck string, total Resources) error {
+	return c.submit("SetServiceQuota", pck, strconv.FormatUint(total.Cores, 10), strconv.FormatUint(total.Memory, 10), strconv.FormatUint(total.Storage, 10))
+}
+
+//--------------------------------------------Delegation Trees--------------------------------------
+
+//GetDelegationTree returns the complete tree of subdelegations under a grant
+func (c *Client) GetDelegationTree(pck string) (*DelegationTreeNode, error) {
+	tree := new(DelegationTreeNode)
+	if err := c.evaluateJSON(tree, "GetDelegationTree", pck); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
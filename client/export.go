@@ -0,0 +1,106 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+//the exporters below render a delegation tree for Graphviz and Mermaid, so it can be pasted
+//into incident reviews. Every node shows its grandor, recipient, status, validity window and
+//remaining budget, and is coloured by its status
+
+//statusColours maps the status of a grant to the fill colour of its node
+var statusColours = map[string]string{
+	"active":       "#c8e6c9",
+	"pending":      "#fff9c4",
+	"unaccepted":   "#bbdefb",
+	"expired":      "#e0e0e0",
+	"suspended":    "#ffe0b2",
+	"revoked":      "#ffcdd2",
+	"declined":     "#d7ccc8",
+	"deregistered": "#f8bbd0",
+	"inactive":     "#b2dfdb",
+}
+
+//Walk calls visit for the node and every node below it, parent is nil for the node itself
+func (n *DelegationTreeNode) Walk(visit func(node *DelegationTreeNode, parent *DelegationTreeNode)) {
+	var walk func(node *DelegationTreeNode, parent *DelegationTreeNode)
+	walk = func(node *DelegationTreeNode, parent *DelegationTreeNode) {
+		visit(node, parent)
+		for _, child := range node.Children {
+			walk(child, node)
+		}
+	}
+	walk(n, nil)
+}
+
+//label returns the lines describing the node in the exported graphs
+func (n *DelegationTreeNode) label() []string {
+	validity := "valid"
+	if !n.Valid {
+		validity = "not valid"
+	}
+	return []string{
+		fmt.Sprintf("%s (%s)", n.Pck, n.Type),
+		fmt.Sprintf("%s -> %s", n.Grandor, n.Recipient),
+		fmt.Sprintf("%s, %s", n.Status, validity),
+		fmt.Sprintf("%s - %s", exportTime(n.Issue), exportTime(n.Expiry)),
+		fmt.Sprintf("budget %d/%d, depth %d", n.Available, n.MaxChildren, n.MaxDepth),
+	}
+}
+
+func exportTime(seconds uint64) string {
+	return time.Unix(int64(seconds), 0).UTC().Format("2006-01-02 15:04")
+}
+
+//DOT renders the tree as a Graphviz digraph
+func (n *DelegationTreeNode) DOT() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "digraph %q {\n", n.Pck)
+	b.WriteString("  rankdir=TB;\n")
+	b.WriteString("  node [shape=box, style=\"rounded,filled\", fontname=\"Helvetica\"];\n")
+
+	n.Walk(func(node *DelegationTreeNode, parent *DelegationTreeNode) {
+		label := strings.Join(node.label(), "\\n")
+		style := ""
+		if !node.Valid {
+			style = ", style=\"rounded,filled,dashed\""
+		}
+		fmt.Fprintf(&b, "  %q [label=\"%s\", fillcolor=%q%s];\n", node.Pck, strings.Replace(label, "\"", "\\\"", -1), statusColours[node.Status], style)
+		if parent != nil {
+			fmt.Fprintf(&b, "  %q -> %q;\n", parent.Pck, node.Pck)
+		}
+	})
+
+	b.WriteString("}\n")
+	return b.String()
+}
+
+//Mermaid renders the tree as a Mermaid flowchart
+func (n *DelegationTreeNode) Mermaid() string {
+	var b strings.Builder
+
+	b.WriteString("flowchart TD\n")
+
+	ids := map[string]string{}
+	n.Walk(func(node *DelegationTreeNode, parent *DelegationTreeNode) {
+		//pcks are free form strings so every node gets a generated identifier
+		id := fmt.Sprintf("n%d", len(ids))
+		ids[node.Pck] = id
+
+		label := strings.Replace(strings.Join(node.label(), "<br/>"), "\"", "#quot;", -1)
+		fmt.Fprintf(&b, "  %s[\"%s\"]\n", id, label)
+		if parent != nil {
+			fmt.Fprintf(&b, "  %s --> %s\n", ids[parent.Pck], id)
+		}
+		fmt.Fprintf(&b, "  class %s %s\n", id, node.Status)
+	})
+
+	for _, status := range []string{"active", "pending", "unaccepted", "expired", "suspended", "revoked", "declined", "deregistered", "inactive"} {
+		fmt.Fprintf(&b, "  classDef %s fill:%s\n", status, statusColours[status])
+	}
+
+	return b.String()
+}
@@ -0,0 +1,177 @@
+//Package local runs the client package against an in-process chaincode backed by the in-memory world
+//state of the shim mock stub. It is kept out of the client package so that services and the deployed
+//chaincode do not link the mock stub, only tests and the development server import it
+package local
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"github.com/hyperledger/fabric-protos-go/msp"
+)
+
+//ChaincodeTransport is a client.Transport that runs the transactions against an in-process chaincode
+//backed by an in-memory world state, it is meant for local tests where no Fabric network is available
+type ChaincodeTransport struct {
+	mutex     sync.Mutex
+	chaincode shim.Chaincode
+	stub      *localStub
+	txid      uint64
+}
+
+//localStub completes the mock stub of the shim with the transient map and private data deletion. As on
+//a peer, the writes of a transaction are only applied once it succeeds and the transaction itself keeps
+//reading the state it started with, so code that reads back its own writes fails here as it would there
+type localStub struct {
+	*shimtest.MockStub
+	args      [][]byte
+	transient map[string][]byte
+	writes    map[string][]byte //writes of the running transaction, nil for a deletion
+	order     []string          //keys of writes in the order they were made
+}
+
+//PutState records a write of the running transaction
+func (stub *localStub) PutState(key string, value []byte) error {
+	if key == "" {
+		return fmt.Errorf("key must not be an empty string")
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	stub.write(key, value)
+	return nil
+}
+
+//DelState records a deletion of the running transaction
+func (stub *localStub) DelState(key string) error {
+	stub.write(key, nil)
+	return nil
+}
+
+//write keeps the last value written at key, the keys are committed in the order they were first written
+func (stub *localStub) write(key string, value []byte) {
+	if _, ok := stub.writes[key]; !ok {
+		stub.order = append(stub.order, key)
+	}
+	stub.writes[key] = value
+}
+
+//commit applies the writes of a successful transaction to the world state
+func (stub *localStub) commit() {
+	for _, key := range stub.order {
+		if value := stub.writes[key]; value != nil {
+			stub.MockStub.PutState(key, value)
+		} else {
+			stub.MockStub.DelState(key)
+		}
+	}
+}
+
+//GetArgs returns the arguments of the running transaction
+func (stub *localStub) GetArgs() [][]byte {
+	return stub.args
+}
+
+//GetStringArgs returns the arguments of the running transaction as strings
+func (stub *localStub) GetStringArgs() []string {
+	args := make([]string, 0, len(stub.args))
+	for _, arg := range stub.args {
+		args = append(args, string(arg))
+	}
+	return args
+}
+
+//GetFunctionAndParameters splits the arguments into the transaction name and its parameters
+func (stub *localStub) GetFunctionAndParameters() (string, []string) {
+	args := stub.GetStringArgs()
+	if len(args) == 0 {
+		return "", []string{}
+	}
+	return args[0], args[1:]
+}
+
+//GetTransient returns the transient map of the running transaction
+func (stub *localStub) GetTransient() (map[string][]byte, error) {
+	return stub.transient, nil
+}
+
+//DelPrivateData removes a key from a private data collection
+func (stub *localStub) DelPrivateData(collection string, key string) error {
+	delete(stub.PvtState[collection], key)
+	return nil
+}
+
+//NewChaincodeTransport returns a Transport over the given chaincode, usually the result of
+//contractapi.NewChaincode(new(SmartContract)) in the chaincode package
+func NewChaincodeTransport(chaincode shim.Chaincode) *ChaincodeTransport {
+	return &ChaincodeTransport{
+		chaincode: chaincode,
+		stub:      &localStub{MockStub: shimtest.NewMockStub("tenant", chaincode)},
+	}
+}
+
+//SetIdentity changes the identity that the following transactions are invoked with, certificate
+//is the PEM encoded X.509 certificate of the identity
+func (t *ChaincodeTransport) SetIdentity(mspid string, certificate []byte) error {
+	creator, err := proto.Marshal(&msp.SerializedIdentity{Mspid: mspid, IdBytes: certificate})
+	if err != nil {
+		return err
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.stub.Creator = creator
+	return nil
+}
+
+//Submit invokes the transaction on the in-memory world state
+func (t *ChaincodeTransport) Submit(name string, args ...string) ([]byte, error) {
+	return t.invoke(name, nil, args)
+}
+
+//SubmitTransient invokes the transaction on the in-memory world state with the given transient map
+func (t *ChaincodeTransport) SubmitTransient(name string, transient map[string][]byte, args ...string) ([]byte, error) {
+	return t.invoke(name, transient, args)
+}
+
+//Evaluate invokes the transaction on the in-memory world state, queries are run exactly like
+//submitted transactions since there is no ordering service to skip
+func (t *ChaincodeTransport) Evaluate(name string, args ...string) ([]byte, error) {
+	return t.invoke(name, nil, args)
+}
+
+func (t *ChaincodeTransport) invoke(name string, transient map[string][]byte, args []string) ([]byte, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	input := make([][]byte, 0, len(args)+1)
+	input = append(input, []byte(name))
+	for _, arg := range args {
+		input = append(input, []byte(arg))
+	}
+
+	t.txid++
+	txid := strconv.FormatUint(t.txid, 10)
+
+	t.stub.args = input
+	t.stub.transient = transient
+	t.stub.writes = map[string][]byte{}
+	t.stub.order = nil
+	t.stub.MockTransactionStart(txid)
+	response := t.chaincode.Invoke(t.stub)
+	if response.Status == shim.OK {
+		t.stub.commit()
+	}
+	t.stub.MockTransactionEnd(txid)
+
+	if response.Status != shim.OK {
+		return nil, fmt.Errorf("%s failed: %s", name, response.Message)
+	}
+
+	return response.Payload, nil
+}
@@ -0,0 +1,169 @@
+package client
+
+import (
+	"strconv"
+	"strings"
+)
+
+//moneyDecimals is the number of decimals the contract keeps in an amount of money
+const moneyDecimals = 6
+
+//rateDecimals is the number of decimals the contract keeps in an exchange rate
+const rateDecimals = 9
+
+//Money is an amount of a currency in millionths of its unit
+type Money struct {
+	Amount   int64  `json:"amount"`   //millionths of the currency unit
+	Currency string `json:"currency"` //ISO 4217 code
+}
+
+//String prints the amount without trailing zeros beyond two decimals, followed by its currency
+func (m Money) String() string {
+	return formatDecimal(m.Amount, moneyDecimals, 2) + " " + m.Currency
+}
+
+//Currency holds the decimals and the rounding rule of a currency
+type Currency struct {
+	Code     string `json:"code"`
+	Decimals uint8  `json:"decimals"`
+	Rounding string `json:"rounding"` //half-even, half-up, down or up
+	Type     string `json:"Type"`
+}
+
+//ExchangeRate is the value of one unit of From in units of To
+type ExchangeRate struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Rate      int64  `json:"rate"` //billionths of a unit of To
+	Timestamp int64  `json:"timestamp"`
+	SetBy     string `json:"setby"`
+	Type      string `json:"Type"`
+}
+
+//String prints the rate as a decimal number
+func (r ExchangeRate) String() string {
+	return formatDecimal(r.Rate, rateDecimals, 0)
+}
+
+//StatementLine is the charge of a single grant in a statement
+type StatementLine struct {
+	Grant     string `json:"grant"`
+	Service   string `json:"service"`
+	Charge    Money  `json:"charge"`
+	Rate      int64  `json:"rate"` //billionths of the statement currency
+	Converted Money  `json:"converted"`
+}
+
+//Statement is the consolidated charges of a tenant in one currency
+type Statement struct {
+	Tenant   string          `json:"tenant"`
+	Currency string          `json:"currency"`
+	Lines    []StatementLine `json:"lines"`
+	Total    Money           `json:"total"`
+	Type     string          `json:"Type"`
+}
+
+//Wallet is the prepaid balance of a tenant
+type Wallet struct {
+	Tenant      string `json:"tenant"`
+	Balance     Money  `json:"balance"`
+	CreditLimit Money  `json:"creditlimit"`
+	Held        Money  `json:"held"`
+	Available   Money  `json:"available"` //Balance - Held + CreditLimit
+	Type        string `json:"Type"`
+}
+
+//Hold is an amount reserved on a wallet
+type Hold struct {
+	Tenant    string `json:"tenant"`
+	HoldID    string `json:"holdid"`
+	Amount    Money  `json:"amount"`
+	Reason    string `json:"reason"`
+	Timestamp int64  `json:"timestamp"`
+	Type      string `json:"Type"`
+}
+
+//WalletEntry is a movement of the balance of a wallet
+type WalletEntry struct {
+	Tenant    string `json:"tenant"`
+	TxID      string `json:"txid"`
+	Kind      string `json:"kind"` //deposit, debit or usage
+	Amount    Money  `json:"amount"`
+	Reference string `json:"reference"`
+	Balance   Money  `json:"balance"`
+	Timestamp int64  `json:"timestamp"`
+	Type      string `json:"Type"`
+}
+
+//Invoice is an amount owed by a tenant
+type Invoice struct {
+	Tenant    string `json:"tenant"`
+	InvoiceID string `json:"invoiceid"`
+	Amount    Money  `json:"amount"`
+	Issued    int64  `json:"issued"`
+	Due       int64  `json:"due"`
+	Paid      bool   `json:"paid"`
+	PaidAt    int64  `json:"paidat"`
+	Payment   string `json:"payment"` //wallet for invoices paid from the wallet
+	Type      string `json:"Type"`
+}
+
+//Dunning records the grants suspended because a tenant did not pay
+type Dunning struct {
+	Tenant       string   `json:"tenant"`
+	Active       bool     `json:"active"`
+	Grace        int64    `json:"grace"` //seconds
+	Invoices     []string `json:"invoices"`
+	Suspended    []string `json:"suspended"`
+	DunnedAt     int64    `json:"dunnedat"`
+	ReinstatedAt int64    `json:"reinstatedat"`
+	Type         string   `json:"Type"`
+}
+
+//QuoteItem is the estimated cost of a grant over one day of its window
+type QuoteItem struct {
+	From    uint64 `json:"from"`
+	To      uint64 `json:"to"`
+	Cores   uint64 `json:"cores"`
+	Seconds uint64 `json:"seconds"`
+	Amount  Money  `json:"amount"`
+}
+
+//Quote is the itemized estimate of a prospective grant
+type Quote struct {
+	Pck        string      `json:"pck"` //empty for an estimate
+	Grandor    string      `json:"grandor"`
+	Service    string      `json:"service"`
+	Cores      uint64      `json:"cores"`
+	Issue      uint64      `json:"issue"`
+	Expiry     uint64      `json:"expiry"`
+	Price      Money       `json:"price"` //per core and hour
+	Items      []QuoteItem `json:"items"`
+	Total      Money       `json:"total"`
+	ValidUntil int64       `json:"validuntil"`
+	Locked     bool        `json:"locked"`
+	Type       string      `json:"Type"`
+}
+
+//formatDecimal prints a fixed-point integer, trailing zeros are dropped down to keep decimals
+func formatDecimal(value int64, decimals int, keep int) string {
+	sign := ""
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+
+	digits := strconv.FormatInt(value, 10)
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+	whole, fraction := digits[:len(digits)-decimals], digits[len(digits)-decimals:]
+	for len(fraction) > keep && strings.HasSuffix(fraction, "0") {
+		fraction = fraction[:len(fraction)-1]
+	}
+	if fraction == "" {
+		return sign + whole
+	}
+
+	return sign + whole + "." + fraction
+}
@@ -0,0 +1,148 @@
+package client
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	gateway "github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+//Profile holds everything needed to reach the chaincode through a Fabric Gateway peer,
+//relative paths are resolved against the directory of the profile file
+type Profile struct {
+	PeerEndpoint string `json:"peerEndpoint"` //host:port of the gateway peer, e.g. localhost:7051
+	PeerHostname string `json:"peerHostname"` //TLS server name of the peer, e.g. peer0.org1.example.com
+	TLSCertPath  string `json:"tlsCertPath"`  //CA certificate of the peer TLS
+	MSPID        string `json:"mspId"`        //MSP of the client identity, e.g. Org1MSP
+	CertPath     string `json:"certPath"`     //signing certificate of the client identity
+	KeyPath      string `json:"keyPath"`      //private key file, or a keystore directory holding a single key
+	Channel      string `json:"channel"`      //e.g. mychannel
+	Chaincode    string `json:"chaincode"`    //e.g. fabcar
+}
+
+//LoadProfile reads a connection profile from a JSON file
+func LoadProfile(path string) (*Profile, error) {
+	profileAsBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read the connection profile. %s", err.Error())
+	}
+
+	profile := new(Profile)
+	if err := json.Unmarshal(profileAsBytes, profile); err != nil {
+		return nil, fmt.Errorf("Failed to parse the connection profile %s. %s", path, err.Error())
+	}
+
+	base := filepath.Dir(path)
+	for _, p := range []*string{&profile.TLSCertPath, &profile.CertPath, &profile.KeyPath} {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(base, *p)
+		}
+	}
+
+	return profile, nil
+}
+
+//Connection is an open gateway connection described by a Profile
+type Connection struct {
+	conn    *grpc.ClientConn
+	gateway *gateway.Gateway
+	client  *Client
+}
+
+//Dial opens a gRPC connection to the gateway peer of the profile and signs every transaction
+//with the client identity of the profile
+func Dial(profile *Profile) (*Connection, error) {
+	tlsCertAsBytes, err := ioutil.ReadFile(profile.TLSCertPath)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read the peer TLS certificate. %s", err.Error())
+	}
+	tlsCert, err := identity.CertificateFromPEM(tlsCertAsBytes)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(tlsCert)
+
+	conn, err := grpc.Dial(profile.PeerEndpoint, grpc.WithTransportCredentials(credentials.NewClientTLSFromCert(pool, profile.PeerHostname)))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to connect to %s. %s", profile.PeerEndpoint, err.Error())
+	}
+
+	id, sign, err := loadIdentity(profile)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	gw, err := gateway.Connect(id, gateway.WithSign(sign), gateway.WithClientConnection(conn))
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	contract := gw.GetNetwork(profile.Channel).GetContract(profile.Chaincode)
+
+	return &Connection{
+		conn:    conn,
+		gateway: gw,
+		client:  New(NewGatewayTransport(contract)),
+	}, nil
+}
+
+//Client returns the typed client bound to the connection
+func (c *Connection) Client() *Client {
+	return c.client
+}
+
+//Close closes the gateway and the underlying gRPC connection
+func (c *Connection) Close() error {
+	c.gateway.Close()
+	return c.conn.Close()
+}
+
+func loadIdentity(profile *Profile) (*identity.X509Identity, identity.Sign, error) {
+	certAsBytes, err := ioutil.ReadFile(profile.CertPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to read the client certificate. %s", err.Error())
+	}
+	cert, err := identity.CertificateFromPEM(certAsBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	id, err := identity.NewX509Identity(profile.MSPID, cert)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	//the keystore of a Fabric CA enrollment is a directory with a single generated file name
+	keyPath := profile.KeyPath
+	if info, err := os.Stat(keyPath); err == nil && info.IsDir() {
+		files, err := ioutil.ReadDir(keyPath)
+		if err != nil || len(files) == 0 {
+			return nil, nil, fmt.Errorf("No private key found in %s", keyPath)
+		}
+		keyPath = filepath.Join(keyPath, files[0].Name())
+	}
+
+	keyAsBytes, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to read the client private key. %s", err.Error())
+	}
+	key, err := identity.PrivateKeyFromPEM(keyAsBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	sign, err := identity.NewPrivateKeySign(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return id, sign, nil
+}
@@ -0,0 +1,41 @@
+//Package client exposes the transactions of the tenant service chaincode as typed Go methods,
+//so services no longer have to build the peer chaincode invoke commands by hand
+package client
+
+import (
+	gateway "github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+//Transport carries a transaction to the contract and returns its raw payload.
+//Submit is used for transactions that update the world state and Evaluate for queries,
+//SubmitTransient also passes data through the transient map so it is not recorded on the ledger
+type Transport interface {
+	Submit(name string, args ...string) ([]byte, error)
+	SubmitTransient(name string, transient map[string][]byte, args ...string) ([]byte, error)
+	Evaluate(name string, args ...string) ([]byte, error)
+}
+
+//GatewayTransport sends the transactions to a Fabric network through the Fabric Gateway API
+type GatewayTransport struct {
+	contract *gateway.Contract
+}
+
+//NewGatewayTransport returns a Transport over a contract obtained from a connected gateway
+func NewGatewayTransport(contract *gateway.Contract) *GatewayTransport {
+	return &GatewayTransport{contract: contract}
+}
+
+//Submit endorses the transaction, sends it to the orderer and waits for it to be committed
+func (t *GatewayTransport) Submit(name string, args ...string) ([]byte, error) {
+	return t.contract.SubmitTransaction(name, args...)
+}
+
+//SubmitTransient submits the transaction with the given transient map
+func (t *GatewayTransport) SubmitTransient(name string, transient map[string][]byte, args ...string) ([]byte, error) {
+	return t.contract.Submit(name, gateway.WithArguments(args...), gateway.WithTransient(transient))
+}
+
+//Evaluate runs the transaction on a single peer without updating the ledger
+func (t *GatewayTransport) Evaluate(name string, args ...string) ([]byte, error) {
+	return t.contract.EvaluateTransaction(name, args...)
+}
@@ -0,0 +1,342 @@
+package client
+
+//the types below mirror the records stored by the chaincode in the world state
+
+//Tenant describes basic details of what makes up a tenant
+type Tenant struct {
+	Pck         string `json:"pck"`
+	Name        string `json:"name"`
+	ContactHash string `json:"contacthash"` //salted hash of the contact details kept in the private data collection
+	Identity    string `json:"identity"`    //client identity the tenant is bound to, empty if not bound
+	IdentityMSP string `json:"identitymsp"` //organization of that identity
+	Registered  bool   `json:"registered"`  //false if not, true if Registered
+	Type        string `json:"type"`        //T for tenants
+}
+
+//Contact holds the personal details of a tenant that are sent through the transient map
+type Contact struct {
+	Email string `json:"email"`
+	Phone string `json:"phone"`
+}
+
+//TenantContact describes the personal details of a tenant kept in the private data collection
+type TenantContact struct {
+	Pck   string `json:"pck"`
+	Email string `json:"email"`
+	Phone string `json:"phone"`
+	Salt  string `json:"salt"`
+	Type  string `json:"Type"`
+}
+
+//ErasureReceipt records the erasure of a tenant
+type ErasureReceipt struct {
+	Tenant      string        `json:"tenant"`
+	TxID        string        `json:"txid"`
+	Timestamp   int64         `json:"timestamp"`
+	RequestedBy string        `json:"requestedby"`
+	Successor   string        `json:"successor"`
+	Revoked     []string      `json:"revoked"`
+	Suspended   []string      `json:"suspended"`
+	Transferred []string      `json:"transferred"`
+	Cascade     CascadeReport `json:"cascade"`
+	Type        string        `json:"Type"`
+}
+
+//GrantRule is a cell of the grant policy matrix, it allows or forbids a kind of grant from one entity
+//type to another
+type GrantRule struct {
+	Grant   string `json:"grant"` //D or SD
+	From    string `json:"from"`  //entity type of the grandor, S, T or G
+	To      string `json:"to"`    //entity type of the recipient
+	Allowed bool   `json:"allowed"`
+	Default bool   `json:"default"` //true if no platform admin has set the rule
+	Type    string `json:"Type"`
+}
+
+//CascadeReport is what the cascade policy did to the grants of a deregistered principal
+type CascadeReport struct {
+	Principal string   `json:"principal"`
+	Mode      string   `json:"mode"`
+	Grants    []string `json:"grants"`
+}
+
+//CascadePolicy is what happens to the active grants of a principal of one entity type when it is
+//deregistered
+type CascadePolicy struct {
+	Principal string `json:"principal"` //entity type, T, S or G
+	Mode      string `json:"mode"`      //revoke, suspend or block
+	Default   bool   `json:"default"`   //true if no platform admin has set the policy
+	Type      string `json:"Type"`
+}
+
+//Organization gathers the tenants of one customer, managed through its owner tenant
+type Organization struct {
+	Pck        string   `json:"pck"`
+	Name       string   `json:"name"`
+	Owner      string   `json:"owner"`   //tenant that manages the organization and pays for its groups
+	Members    []string `json:"members"` //the owner included
+	Groups     []string `json:"groups"`
+	Registered bool     `json:"registered"`
+	Type       string   `json:"Type"` //O for organizations
+}
+
+//Group is a team of tenants of an organization that can receive grants, its members are resolved
+//when access is checked
+type Group struct {
+	Pck          string   `json:"pck"`
+	Name         string   `json:"name"`
+	Organization string   `json:"organization"`
+	Members      []string `json:"members"` //all members of the organization
+	Registered   bool     `json:"registered"`
+	Type         string   `json:"Type"` //G for groups
+}
+
+//Membership records that a tenant belongs to an organization or group
+type Membership struct {
+	Tenant    string `json:"tenant"`
+	Container string `json:"container"` //pck of the organization or group
+	Kind      string `json:"kind"`      //O or G
+	Type      string `json:"Type"`
+}
+
+//RoleAssignment gives a role of the role registry to a client identity
+type RoleAssignment struct {
+	Identity  string `json:"identity"` //the client identity, subject and issuer of its certificate
+	MSP       string `json:"msp"`
+	Role      string `json:"role"`  //platform-admin, org-admin, service-operator, auditor, billing or tenant
+	Scope     string `json:"scope"` //service of a service-operator, tenant of a tenant role
+	GrantedBy string `json:"grantedby"`
+	GrantedAt int64  `json:"grantedat"`
+	Type      string `json:"Type"`
+}
+
+//RoleChange is an entry of the role audit trail
+type RoleChange struct {
+	TxID      string `json:"txid"`
+	Timestamp int64  `json:"timestamp"`
+	Actor     string `json:"actor"`
+	ActorMSP  string `json:"actormsp"`
+	Action    string `json:"action"` //bootstrap, assign or revoke
+	Identity  string `json:"identity"`
+	MSP       string `json:"msp"`
+	Role      string `json:"role"`
+	Scope     string `json:"scope"`
+	Type      string `json:"Type"`
+}
+
+//CallerIdentity describes the identity a client submits with and the roles it holds
+type CallerIdentity struct {
+	Identity string            `json:"identity"`
+	MSP      string            `json:"msp"`
+	Roles    []*RoleAssignment `json:"roles"`
+}
+
+//AttributeRule is satisfied by a certificate whose attribute has the value, $party stands for the
+//party the transaction acts for
+type AttributeRule struct {
+	Attribute string `json:"attribute"`
+	Value     string `json:"value"`
+}
+
+//AccessPolicy lists the attribute rules of a transaction, the caller must satisfy one of them
+type AccessPolicy struct {
+	Transaction string          `json:"transaction"`
+	Rules       []AttributeRule `json:"rules"`
+	Type        string          `json:"Type"`
+}
+
+//Service describes basic details of what makes up a service
+type Service struct {
+	Pck           string    `json:"pck"`
+	Name          string    `json:"name"`
+	Registered    bool      `json:"registered"`    //true if registered false if not
+	Owner         string    `json:"owner"`         //pck of the tenant that owns the service
+	OwnerMSP      string    `json:"ownermsp"`      //organization allowed to manage the service
+	PendingOwner  string    `json:"pendingowner"`  //tenant the ownership is being transferred to
+	CostPerHour   uint64    `json:"costperhour"`   //whole unit price of services priced before Price existed
+	Price         Money     `json:"price"`         //price per core and hour
+	StoragePrice  Money     `json:"storageprice"`  //price per GB and hour of the storage used, free when zero
+	Rounding      string    `json:"rounding"`      //rounding rule of the charges, the one of the currency when empty
+	Quota         Resources `json:"quota"`         //total resources the service can delegate, 0 is unlimited
+	ApprovalCores uint64    `json:"approvalcores"` //delegations of more cores must be proposed and accepted, 0 never
+	Type          string    `json:"Type"`          //S for Services
+}
+
+//Resources is an amount of cores, memory in MB and storage in GB
+type Resources struct {
+	Cores   uint64 `json:"cores"`
+	Memory  uint64 `json:"memory"`
+	Storage uint64 `json:"storage"`
+}
+
+//Quota describes the total, allocated and free resources of a Service or of a grant
+type Quota struct {
+	Pck       string    `json:"pck"`
+	Total     Resources `json:"total"`
+	Allocated Resources `json:"allocated"`
+	Free      Resources `json:"free"`
+	Type      string    `json:"Type"`
+}
+
+//Delegation describes basic details of what makes up a Delegation
+type Delegation struct {
+	Pck             string   `json:"pck"`
+	Grandor         string   `json:"grandor"`
+	Recipient       string   `json:"recipient"`
+	Subdel          uint8    `json:"subdel"`
+	Issue           uint64   `json:"issue"`
+	Expiry          uint64   `json:"expiry"`
+	Suspended       bool     `json:"suspended"`       //false if not, true if suspended
+	Revoked         bool     `json:"revoked"`         //false if not, true if revoked
+	Pending         bool     `json:"pending"`         //true until the recipient accepts the grant
+	Declined        bool     `json:"declined"`        //true if the recipient declined the grant
+	Revokers        []string `json:"revokers"`        //list of tenants & services who can revoke the delegation
+	DelegationChain []string `json:"delegationchain"` //pcks of the grants from the root Delegation down to this one
+	Scope           Scope    `json:"scope"`           //what the grant allows the recipient to use
+	Type            string   `json:"Type"`            //D is for Delegation
+}
+
+//Proposal describes a Delegation waiting to be accepted by the recipient and countersigned
+type Proposal struct {
+	Pck             string `json:"pck"`
+	Grandor         string `json:"grandor"`
+	Recipient       string `json:"recipient"`
+	Subdel          string `json:"subdel"`
+	Issue           string `json:"issue"`
+	Expiry          string `json:"expiry"`
+	Scope           string `json:"scope"`
+	Proposer        string `json:"proposer"`        //organization that proposed the Delegation
+	GrandorOwner    string `json:"grandorowner"`    //organization that owned the grandor when it was proposed
+	Countersigner   string `json:"countersigner"`   //organization that must countersign, empty when none has to
+	AcceptedBy      string `json:"acceptedby"`      //organization that accepted for the recipient
+	CountersignedBy string `json:"countersignedby"` //organization that countersigned
+	ProposedAt      int64  `json:"proposedat"`
+	ExpiresAt       int64  `json:"expiresat"` //Unix seconds the proposal must be agreed to by
+	Status          string `json:"status"`    //open, active, declined, withdrawn or expired
+	Type            string `json:"Type"`
+}
+
+//RevocationPolicy describes who has to revoke a Delegation or SubDelegation for it to be revoked
+type RevocationPolicy struct {
+	Grant     string   `json:"grant"`
+	Rule      string   `json:"rule"`             //any-of, all-of, k-of-n or org-admin
+	Parties   []string `json:"parties"`          //revokers the rule counts, all of the revokers when empty
+	K         uint8    `json:"k"`                //signatures a k-of-n rule needs
+	NotBefore uint64   `json:"notbefore"`        //Unix seconds before which the grant cannot be revoked, 0 for none
+	Signed    []string `json:"signed,omitempty"` //revokers that have signed the revocation so far
+	Type      string   `json:"Type,omitempty"`
+}
+
+//ScheduleWindow is a period in which a scheduled grant is active, a weekly or monthly window that ends
+//before it starts runs past midnight
+type ScheduleWindow struct {
+	Kind  string   `json:"kind"`            //weekly, monthly or once
+	Days  []string `json:"days,omitempty"`  //mon to sun, for weekly windows
+	Day   uint8    `json:"day,omitempty"`   //day of the month, for monthly windows
+	Start string   `json:"start,omitempty"` //HH:MM the window opens
+	End   string   `json:"end,omitempty"`   //HH:MM the window closes
+	From  uint64   `json:"from,omitempty"`  //Unix seconds a once window opens
+	Until uint64   `json:"until,omitempty"` //Unix seconds a once window closes
+}
+
+//GrantTransfer records a request to move a grant to a new recipient, the history of a grant lists them
+type GrantTransfer struct {
+	Grant       string `json:"grant"`
+	From        string `json:"from"`        //recipient when the transfer was requested
+	To          string `json:"to"`          //new recipient
+	Status      string `json:"status"`      //open, completed or rejected
+	RequestedBy string `json:"requestedby"` //MSP of the identity that requested the transfer
+	Requested   int64  `json:"requested"`
+	ClosedBy    string `json:"closedby"` //MSP of the identity that approved or rejected it
+	Closed      int64  `json:"closed"`
+	TxID        string `json:"txid"`
+	Type        string `json:"Type"`
+}
+
+//Schedule lists the windows in which a grant is active, it is always active when there are none
+type Schedule struct {
+	Grant     string           `json:"grant"`
+	UTCOffset int              `json:"utcoffset"` //minutes the time of the windows is ahead of UTC
+	Windows   []ScheduleWindow `json:"windows"`
+	Type      string           `json:"Type,omitempty"`
+}
+
+//SubDelegation describes basic details of what makes up a SubDelegation
+type SubDelegation struct {
+	Pck             string   `json:"pck"`
+	Grandor         string   `json:"grandor"`
+	Recipient       string   `json:"recipient"`
+	Subdel          uint8    `json:"subdel"`
+	Issue           uint64   `json:"issue"`
+	Expiry          uint64   `json:"expiry"`
+	Suspended       bool     `json:"suspended"`       //false if not, true if suspended
+	Revoked         bool     `json:"revoked"`         //false if not, true if revoked
+	Pending         bool     `json:"pending"`         //true until the recipient accepts the grant
+	Declined        bool     `json:"declined"`        //true if the recipient declined the grant
+	Revokers        []string `json:"revokers"`        //list of tenants & services who can revoke the subdelegation
+	DelegationChain []string `json:"delegationchain"` //pcks of the grants from the root Delegation down to this one
+	Scope           Scope    `json:"scope"`           //what the grant allows the recipient to use
+	Type            string   `json:"Type"`            //SD is for SubDelegation
+}
+
+//Scope describes what a Delegation or SubDelegation allows, zero values and empty lists are not
+//restricted and the empty fields of a SubDelegation are inherited from its parent
+type Scope struct {
+	ResourceType string   `json:"resourcetype"`
+	MaxCores     uint64   `json:"maxcores"`
+	Memory       uint64   `json:"memory"`  //MB
+	Storage      uint64   `json:"storage"` //GB
+	Regions      []string `json:"regions,omitempty"`
+	Operations   []string `json:"operations,omitempty"`
+}
+
+//UsageSample is the usage of a grant over a period as reported by its service
+type UsageSample struct {
+	Grant     string `json:"grant"`
+	SampleID  string `json:"sampleid"`
+	Timestamp uint64 `json:"timestamp"` //end of the sampled period in Unix seconds
+	Cores     uint64 `json:"cores"`
+	Hours     uint64 `json:"hours"`
+	Storage   uint64 `json:"storage"` //GB
+	Submitter string `json:"submitter"`
+	SignerID  string `json:"signerid"`
+	TxID      string `json:"txid"`
+	Type      string `json:"Type"`
+}
+
+//Capacity describes the subdelegation budget of a Delegation or SubDelegation
+type Capacity struct {
+	Grant       string `json:"grant"`
+	MaxDepth    uint8  `json:"maxdepth"`
+	MaxChildren uint8  `json:"maxchildren"`
+	Consumed    uint8  `json:"consumed"`
+	Available   uint8  `json:"available"`
+	Type        string `json:"Type"`
+}
+
+//Allocation records a single child that has been created under a grant
+type Allocation struct {
+	Grant   string `json:"grant"`
+	Child   string `json:"child"`
+	Depth   uint8  `json:"depth"`
+	Created uint64 `json:"created"`
+	Active  bool   `json:"active"`
+	Type    string `json:"Type"`
+}
+
+//DelegationTreeNode describes a grant of a delegation tree together with the grants below it
+type DelegationTreeNode struct {
+	Pck         string                `json:"pck"`
+	Type        string                `json:"Type"`
+	Grandor     string                `json:"grandor"`
+	Recipient   string                `json:"recipient"`
+	Status      string                `json:"status"` //active, pending, unaccepted, expired, suspended, revoked, declined, deregistered or inactive, by the grant itself
+	Valid       bool                  `json:"valid"`  //true if the grant and every grant above it give access now
+	Issue       uint64                `json:"issue"`
+	Expiry      uint64                `json:"expiry"`
+	MaxDepth    uint8                 `json:"maxdepth"`
+	MaxChildren uint8                 `json:"maxchildren"`
+	Consumed    uint8                 `json:"consumed"`
+	Available   uint8                 `json:"available"`
+	Children    []*DelegationTreeNode `json:"children"`
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/Kthanasis/Tenant-Service-Cloud-Management-using-Blockchain/client"
+)
+
+var accessCommands = map[string]command{
+	"set":  accessSet,
+	"list": accessList,
+}
+
+//formatRules prints attribute rules as attribute=value pairs
+func formatRules(rules []client.AttributeRule) string {
+	var pairs []string
+	for _, rule := range rules {
+		pairs = append(pairs, rule.Attribute+"="+rule.Value)
+	}
+	return strings.Join(pairs, ", ")
+}
+
+func accessSet(c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("access set", flag.ContinueOnError)
+	rules := fs.String("rules", "", "comma separated attribute=value rules, $party stands for the party of the transaction, none removes the policy")
+	pos, err := parse(fs, args, "transaction")
+	if err != nil {
+		return err
+	}
+
+	var parsed []client.AttributeRule
+	for _, pair := range splitList(*rules) {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("cannot understand the rule %q, expected attribute=value", pair)
+		}
+		parsed = append(parsed, client.AttributeRule{Attribute: parts[0], Value: parts[1]})
+	}
+
+	if err := c.SetAccessPolicy(pos[0], parsed); err != nil {
+		return err
+	}
+	if len(parsed) == 0 {
+		return out.done("access policy of %s removed", pos[0])
+	}
+	return out.done("%s needs one of %s", pos[0], formatRules(parsed))
+}
+
+func accessList(c *client.Client, out *printer, args []string) error {
+	if _, err := parse(flag.NewFlagSet("access list", flag.ContinueOnError), args); err != nil {
+		return err
+	}
+	policies, err := c.GetAccessPolicies()
+	if err != nil {
+		return err
+	}
+	var rows [][]string
+	for _, policy := range policies {
+		rows = append(rows, []string{policy.Transaction, formatRules(policy.Rules)})
+	}
+	return out.table(policies, []string{"TRANSACTION", "RULES"}, rows)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Kthanasis/Tenant-Service-Cloud-Management-using-Blockchain/client"
+)
+
+//parse parses the flags of an action, flags may come before or after the positional arguments
+//and exactly want positional arguments are required
+func parse(fs *flag.FlagSet, args []string, want ...string) ([]string, error) {
+	fs.SetOutput(ioutil.Discard)
+
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+
+	if len(positional) != len(want) {
+		return nil, fmt.Errorf("%s expects the arguments <%s>", fs.Name(), strings.Join(want, "> <"))
+	}
+
+	return positional, nil
+}
+
+//required checks that every named string flag has been given a value
+func required(fs *flag.FlagSet, flags ...string) error {
+	for _, name := range flags {
+		if fs.Lookup(name).Value.String() == "" {
+			return fmt.Errorf("%s requires -%s", fs.Name(), name)
+		}
+	}
+	return nil
+}
+
+//timeFormats are the absolute date formats accepted by parseTime
+var timeFormats = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+//parseTime turns a human friendly time into a time.Time. Durations are counted from base,
+//so an expiry of 72h means 72 hours after the issue
+func parseTime(value string, base time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	if value == "now" {
+		return time.Now(), nil
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+
+	for _, format := range timeFormats {
+		if t, err := time.ParseInLocation(format, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	if d, err := parseDuration(strings.TrimPrefix(value, "+")); err == nil {
+		return base.Add(d), nil
+	}
+
+	return time.Time{}, fmt.Errorf("cannot understand the time %q", value)
+}
+
+//parseDuration extends time.ParseDuration with d for days and w for weeks
+func parseDuration(value string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if strings.HasSuffix(value, suffix) {
+			n, err := strconv.ParseFloat(strings.TrimSuffix(value, suffix), 64)
+			if err != nil {
+				return 0, err
+			}
+			return time.Duration(n * float64(unit)), nil
+		}
+	}
+	return time.ParseDuration(value)
+}
+
+//window parses the -issue and -expires flags of a create action
+func window(issue string, expires string) (time.Time, time.Time, error) {
+	issued, err := parseTime(issue, time.Now())
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	expiry, err := parseTime(expires, issued)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return issued, expiry, nil
+}
+
+//scopeFlags registers the flags that describe the scope of a grant, the returned function gives
+//the scope after parsing or nil when none of the flags was given
+func scopeFlags(fs *flag.FlagSet) func() *client.Scope {
+	resource := fs.String("resource", "", "resource type the grant allows")
+	cores := fs.Uint64("cores", 0, "most cores the grant allows")
+	memory := fs.Uint64("memory", 0, "most memory in MB the grant allows")
+	storage := fs.Uint64("storage", 0, "most storage in GB the grant allows")
+	regions := fs.String("regions", "", "comma separated regions the grant allows")
+	operations := fs.String("operations", "", "comma separated operations the grant allows")
+
+	return func() *client.Scope {
+		scope := client.Scope{
+			ResourceType: *resource,
+			MaxCores:     *cores,
+			Memory:       *memory,
+			Storage:      *storage,
+			Regions:      splitList(*regions),
+			Operations:   splitList(*operations),
+		}
+		if scope.ResourceType == "" && scope.MaxCores == 0 && scope.Memory == 0 && scope.Storage == 0 && scope.Regions == nil && scope.Operations == nil {
+			return nil
+		}
+		return &scope
+	}
+}
+
+//splitList splits a comma separated flag value, an empty value gives nil
+func splitList(value string) []string {
+	var list []string
+	for _, x := range strings.Split(value, ",") {
+		if x = strings.TrimSpace(x); x != "" {
+			list = append(list, x)
+		}
+	}
+	return list
+}
+
+//amountText joins an amount and an optional -currency flag into the form the contract reads
+func amountText(amount string, currency string) string {
+	if currency == "" {
+		return amount
+	}
+	return amount + " " + currency
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/Kthanasis/Tenant-Service-Cloud-Management-using-Blockchain/client"
+)
+
+var cascadePolicyCommands = map[string]command{
+	"set":  cascadePolicySet,
+	"show": cascadePolicyShow,
+}
+
+func cascadePolicySet(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("cascade-policy set", flag.ContinueOnError), args, "principal", "mode")
+	if err != nil {
+		return err
+	}
+	if err := c.SetCascadePolicy(pos[0], pos[1]); err != nil {
+		return err
+	}
+	return out.done("deregistering a %s now applies %s to its grants", pos[0], pos[1])
+}
+
+func cascadePolicyShow(c *client.Client, out *printer, args []string) error {
+	if _, err := parse(flag.NewFlagSet("cascade-policy show", flag.ContinueOnError), args); err != nil {
+		return err
+	}
+	policies, err := c.GetCascadePolicies()
+	if err != nil {
+		return err
+	}
+	var rows [][]string
+	for _, policy := range policies {
+		rows = append(rows, []string{policy.Principal, policy.Mode, formatBool(policy.Default)})
+	}
+	return out.table(policies, []string{"PRINCIPAL", "MODE", "DEFAULT"}, rows)
+}
+
+//dependentGrants returns the command that lists the active grants of a tenant, service or group, kind
+//names the resource
+func dependentGrants(kind string) command {
+	return func(c *client.Client, out *printer, args []string) error {
+		pos, err := parse(flag.NewFlagSet(kind+" dependent-grants", flag.ContinueOnError), args, "pck")
+		if err != nil {
+			return err
+		}
+		grants, err := c.GetDependentGrants(pos[0])
+		if err != nil {
+			return err
+		}
+		var rows [][]string
+		for _, grant := range grants {
+			rows = append(rows, []string{grant.Pck, grant.Type, grant.Grandor, grant.Recipient, formatUnix(grant.Expiry)})
+		}
+		return out.table(grants, []string{"GRANT", "TYPE", "GRANDOR", "RECIPIENT", "EXPIRY"}, rows)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"strconv"
+
+	"github.com/Kthanasis/Tenant-Service-Cloud-Management-using-Blockchain/client"
+)
+
+var currencyCommands = map[string]command{
+	"set":      currencySet,
+	"show":     currencyShow,
+	"set-rate": currencySetRate,
+	"rate":     currencyRate,
+}
+
+func currencySet(c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("currency set", flag.ContinueOnError)
+	decimals := fs.Uint("decimals", 2, "decimals amounts of the currency are rounded to")
+	rounding := fs.String("rounding", "half-even", "half-even, half-up, down or up")
+	pos, err := parse(fs, args, "code")
+	if err != nil {
+		return err
+	}
+	if err := c.SetCurrency(pos[0], uint8(*decimals), *rounding); err != nil {
+		return err
+	}
+	return out.done("currency %s rounds %s to %d decimals", pos[0], *rounding, *decimals)
+}
+
+func currencyShow(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("currency show", flag.ContinueOnError), args, "code")
+	if err != nil {
+		return err
+	}
+	currency, err := c.GetCurrency(pos[0])
+	if err != nil {
+		return err
+	}
+	return out.record(currency, [][2]string{
+		{"Code", currency.Code},
+		{"Decimals", strconv.Itoa(int(currency.Decimals))},
+		{"Rounding", currency.Rounding},
+	})
+}
+
+func currencySetRate(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("currency set-rate", flag.ContinueOnError), args, "from", "to", "rate")
+	if err != nil {
+		return err
+	}
+	if err := c.SetExchangeRate(pos[0], pos[1], pos[2]); err != nil {
+		return err
+	}
+	return out.done("1 %s is worth %s %s", pos[0], pos[2], pos[1])
+}
+
+func currencyRate(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("currency rate", flag.ContinueOnError), args, "from", "to")
+	if err != nil {
+		return err
+	}
+	rate, err := c.GetExchangeRate(pos[0], pos[1])
+	if err != nil {
+		return err
+	}
+	return out.record(rate, [][2]string{
+		{"From", rate.From},
+		{"To", rate.To},
+		{"Rate", rate.String()},
+		{"SetBy", rate.SetBy},
+		{"Timestamp", formatUnix(uint64(rate.Timestamp))},
+	})
+}
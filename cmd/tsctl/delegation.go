@@ -0,0 +1,425 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Kthanasis/Tenant-Service-Cloud-Management-using-Blockchain/client"
+)
+
+var delegationCommands = map[string]command{
+	"create":                delegationCreate,
+	"suspend":               delegationSuspend,
+	"resume":                delegationResume,
+	"revoke":                delegationRevoke,
+	"accept":                delegationAccept,
+	"decline":               delegationDecline,
+	"show":                  delegationShow,
+	"status":                delegationStatus,
+	"charge":                delegationCharge,
+	"tree":                  delegationTree,
+	"capacity":              delegationCapacity,
+	"in-scope":              delegationInScope,
+	"access":                memberAccess("delegation"),
+	"record-usage":          delegationRecordUsage,
+	"usage":                 delegationUsage,
+	"bill":                  delegationBill,
+	"quota":                 delegationQuota,
+	"set-max-children":      delegationSetMaxChildren,
+	"revocation-policy":     revocationPolicyShow("delegation"),
+	"set-revocation-policy": revocationPolicySet("delegation"),
+	"schedule":              scheduleShow("delegation"),
+	"set-schedule":          scheduleSet("delegation"),
+	"next-activation":       nextActivation("delegation"),
+	"transfer":              transferRequest("delegation"),
+	"approve-transfer":      transferApprove("delegation"),
+	"reject-transfer":       transferReject("delegation"),
+	"transfers":             transferHistory("delegation"),
+}
+
+func delegationCreate(c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("delegation create", flag.ContinueOnError)
+	from := fs.String("from", "", "grandor service")
+	to := fs.String("to", "", "recipient service")
+	subdel := fs.Uint("subdel", 0, "how many levels the delegation can be subdelegated")
+	issue := fs.String("issue", "now", "start of the validity window")
+	expires := fs.String("expires", "", "end of the validity window, a date or a duration after -issue")
+	scope := scopeFlags(fs)
+	pos, err := parse(fs, args, "pck")
+	if err != nil {
+		return err
+	}
+	if err := required(fs, "from", "to", "expires"); err != nil {
+		return err
+	}
+	if *subdel > 255 {
+		return fmt.Errorf("-subdel must be between 0 and 255")
+	}
+	issued, expiry, err := window(*issue, *expires)
+	if err != nil {
+		return err
+	}
+
+	if err := c.RegisterDelegation(pos[0], *from, *to, uint8(*subdel), issued, expiry, scope()); err != nil {
+		return err
+	}
+	return out.done("delegation %s created, valid from %s until %s once %s accepts it", pos[0], issued.Format("2006-01-02 15:04:05"), expiry.Format("2006-01-02 15:04:05"), *to)
+}
+
+func delegationSuspend(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("delegation suspend", flag.ContinueOnError), args, "pck")
+	if err != nil {
+		return err
+	}
+	if err := c.SuspendDelegation(pos[0]); err != nil {
+		return err
+	}
+	return out.done("delegation %s suspended", pos[0])
+}
+
+func delegationResume(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("delegation resume", flag.ContinueOnError), args, "pck")
+	if err != nil {
+		return err
+	}
+	if err := c.ResumeDelegation(pos[0]); err != nil {
+		return err
+	}
+	return out.done("delegation %s resumed", pos[0])
+}
+
+func delegationRevoke(c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("delegation revoke", flag.ContinueOnError)
+	by := fs.String("by", "", "revoker, must be in the Revokers of the delegation")
+	pos, err := parse(fs, args, "pck")
+	if err != nil {
+		return err
+	}
+	if err := required(fs, "by"); err != nil {
+		return err
+	}
+	if err := c.RevokeDelegation(pos[0], *by); err != nil {
+		return err
+	}
+	delegation, err := c.IsDelegation(pos[0])
+	if err != nil {
+		return err
+	}
+	return revokedMessage(out, "delegation", pos[0], *by, delegation.Revoked)
+}
+
+func delegationAccept(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("delegation accept", flag.ContinueOnError), args, "pck")
+	if err != nil {
+		return err
+	}
+	if err := c.AcceptGrant(pos[0]); err != nil {
+		return err
+	}
+	return out.done("delegation %s accepted", pos[0])
+}
+
+func delegationDecline(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("delegation decline", flag.ContinueOnError), args, "pck")
+	if err != nil {
+		return err
+	}
+	if err := c.DeclineGrant(pos[0]); err != nil {
+		return err
+	}
+	return out.done("delegation %s declined", pos[0])
+}
+
+func delegationShow(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("delegation show", flag.ContinueOnError), args, "pck")
+	if err != nil {
+		return err
+	}
+	delegation, err := c.IsDelegation(pos[0])
+	if err != nil {
+		return err
+	}
+	return out.record(delegation, grantFields(delegation))
+}
+
+//grantFields lists the fields shared by Delegations and SubDelegations
+func grantFields(d *client.Delegation) [][2]string {
+	return [][2]string{
+		{"Pck", d.Pck},
+		{"Type", d.Type},
+		{"Grandor", d.Grandor},
+		{"Recipient", d.Recipient},
+		{"Subdel", strconv.Itoa(int(d.Subdel))},
+		{"Issue", formatUnix(d.Issue)},
+		{"Expiry", formatUnix(d.Expiry)},
+		{"Suspended", formatBool(d.Suspended)},
+		{"Revoked", formatBool(d.Revoked)},
+		{"Pending", formatBool(d.Pending)},
+		{"Declined", formatBool(d.Declined)},
+		{"Revokers", strings.Join(d.Revokers, ", ")},
+		{"Chain", strings.Join(d.DelegationChain, " > ")},
+		{"Scope", formatScope(d.Scope)},
+	}
+}
+
+//formatScope prints the restricted fields of a scope, an unrestricted scope prints as any
+func formatScope(scope client.Scope) string {
+	var parts []string
+	if scope.ResourceType != "" {
+		parts = append(parts, "resource="+scope.ResourceType)
+	}
+	if scope.MaxCores != 0 {
+		parts = append(parts, "cores="+strconv.FormatUint(scope.MaxCores, 10))
+	}
+	if scope.Memory != 0 {
+		parts = append(parts, "memory="+strconv.FormatUint(scope.Memory, 10)+"MB")
+	}
+	if scope.Storage != 0 {
+		parts = append(parts, "storage="+strconv.FormatUint(scope.Storage, 10)+"GB")
+	}
+	if len(scope.Regions) > 0 {
+		parts = append(parts, "regions="+strings.Join(scope.Regions, ","))
+	}
+	if len(scope.Operations) > 0 {
+		parts = append(parts, "operations="+strings.Join(scope.Operations, ","))
+	}
+	if len(parts) == 0 {
+		return "any"
+	}
+	return strings.Join(parts, " ")
+}
+
+//status is the combined result of the status queries of a grant
+type status struct {
+	Pck       string `json:"pck"`
+	Valid     bool   `json:"valid"`
+	Expired   bool   `json:"expired"`
+	Suspended bool   `json:"suspended"`
+	Revoked   bool   `json:"revoked"`
+}
+
+func delegationStatus(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("delegation status", flag.ContinueOnError), args, "pck")
+	if err != nil {
+		return err
+	}
+
+	st := status{Pck: pos[0]}
+	if st.Valid, err = c.IsValid(pos[0]); err != nil {
+		return err
+	}
+	if st.Expired, err = c.IsExpired(pos[0]); err != nil {
+		return err
+	}
+	if st.Suspended, err = c.IsSuspended(pos[0]); err != nil {
+		return err
+	}
+	if st.Revoked, err = c.IsRevoked(pos[0]); err != nil {
+		return err
+	}
+	return out.record(st, statusFields(st))
+}
+
+func statusFields(st status) [][2]string {
+	return [][2]string{
+		{"Pck", st.Pck},
+		{"Valid", formatBool(st.Valid)},
+		{"Expired", formatBool(st.Expired)},
+		{"Suspended", formatBool(st.Suspended)},
+		{"Revoked", formatBool(st.Revoked)},
+	}
+}
+
+func delegationCharge(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("delegation charge", flag.ContinueOnError), args, "pck")
+	if err != nil {
+		return err
+	}
+	cost, err := c.ChargingDel(pos[0])
+	if err != nil {
+		return err
+	}
+	return out.record(map[string]interface{}{"pck": pos[0], "cost": cost}, [][2]string{
+		{"Pck", pos[0]},
+		{"Cost", cost.String()},
+	})
+}
+
+func delegationBill(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("delegation bill", flag.ContinueOnError), args, "pck")
+	if err != nil {
+		return err
+	}
+	wallet, err := c.BillUsage(pos[0])
+	if err != nil {
+		return err
+	}
+	return printWallet(out, wallet)
+}
+
+func delegationRecordUsage(c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("delegation record-usage", flag.ContinueOnError)
+	sample := fs.String("sample", "", "id of the sample, a sample id is only recorded once")
+	at := fs.String("at", "now", "end of the sampled period")
+	cores := fs.Uint64("cores", 0, "cores used during the period")
+	hours := fs.Uint64("hours", 1, "length of the period in hours")
+	storage := fs.Uint64("storage", 0, "storage in GB used during the period")
+	pos, err := parse(fs, args, "pck")
+	if err != nil {
+		return err
+	}
+	if err := required(fs, "sample"); err != nil {
+		return err
+	}
+	timestamp, err := parseTime(*at, time.Now())
+	if err != nil {
+		return err
+	}
+
+	err = c.RecordUsage(pos[0], client.UsageSample{SampleID: *sample, Timestamp: uint64(timestamp.Unix()), Cores: *cores, Hours: *hours, Storage: *storage})
+	if err != nil {
+		return err
+	}
+	return out.done("sample %s of %s recorded", *sample, pos[0])
+}
+
+func delegationUsage(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("delegation usage", flag.ContinueOnError), args, "pck")
+	if err != nil {
+		return err
+	}
+	samples, err := c.GetUsage(pos[0])
+	if err != nil {
+		return err
+	}
+	var rows [][]string
+	for _, sample := range samples {
+		rows = append(rows, []string{sample.SampleID, formatUnix(sample.Timestamp), strconv.FormatUint(sample.Cores, 10),
+			strconv.FormatUint(sample.Hours, 10), strconv.FormatUint(sample.Storage, 10), sample.Submitter})
+	}
+	return out.table(samples, []string{"SAMPLE", "TIMESTAMP", "CORES", "HOURS", "STORAGE", "SUBMITTER"}, rows)
+}
+
+func delegationInScope(c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("delegation in-scope", flag.ContinueOnError)
+	operation := fs.String("operation", "", "operation to check")
+	region := fs.String("region", "", "region to check")
+	pos, err := parse(fs, args, "pck")
+	if err != nil {
+		return err
+	}
+	allowed, err := c.IsInScope(pos[0], *operation, *region)
+	if err != nil {
+		return err
+	}
+	return out.record(map[string]interface{}{"pck": pos[0], "operation": *operation, "region": *region, "allowed": allowed}, [][2]string{
+		{"Pck", pos[0]},
+		{"Operation", *operation},
+		{"Region", *region},
+		{"Allowed", formatBool(allowed)},
+	})
+}
+
+func delegationTree(c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("delegation tree", flag.ContinueOnError)
+	format := fs.String("format", "table", "table, dot or mermaid, the global -output json prints the tree as JSON")
+	pos, err := parse(fs, args, "pck")
+	if err != nil {
+		return err
+	}
+
+	root, err := c.GetDelegationTree(pos[0])
+	if err != nil {
+		return err
+	}
+
+	if out.json {
+		return out.value(root)
+	}
+
+	switch *format {
+	case "dot":
+		fmt.Print(root.DOT())
+		return nil
+	case "mermaid":
+		fmt.Print(root.Mermaid())
+		return nil
+	case "table":
+		var rows [][]string
+		flatten(root, "", "", &rows)
+		return out.table(root, []string{"GRANT", "GRANDOR", "RECIPIENT", "STATUS", "VALID", "ISSUE", "EXPIRY", "BUDGET"}, rows)
+	default:
+		return fmt.Errorf("unknown tree format %q, expected table, dot or mermaid", *format)
+	}
+}
+
+func flatten(node *client.DelegationTreeNode, prefix string, branch string, rows *[][]string) {
+	*rows = append(*rows, []string{
+		prefix + branch + node.Pck,
+		node.Grandor,
+		node.Recipient,
+		node.Status,
+		formatBool(node.Valid),
+		formatUnix(node.Issue),
+		formatUnix(node.Expiry),
+		fmt.Sprintf("%d/%d", node.Available, node.MaxChildren),
+	})
+
+	switch branch {
+	case "├─ ":
+		prefix += "│  "
+	case "└─ ":
+		prefix += "   "
+	}
+	for i, child := range node.Children {
+		if i == len(node.Children)-1 {
+			flatten(child, prefix, "└─ ", rows)
+		} else {
+			flatten(child, prefix, "├─ ", rows)
+		}
+	}
+}
+
+func delegationCapacity(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("delegation capacity", flag.ContinueOnError), args, "pck")
+	if err != nil {
+		return err
+	}
+	capacity, err := c.GetCapacity(pos[0])
+	if err != nil {
+		return err
+	}
+	return out.record(capacity, [][2]string{
+		{"Grant", capacity.Grant},
+		{"MaxDepth", strconv.Itoa(int(capacity.MaxDepth))},
+		{"MaxChildren", strconv.Itoa(int(capacity.MaxChildren))},
+		{"Consumed", strconv.Itoa(int(capacity.Consumed))},
+		{"Available", strconv.Itoa(int(capacity.Available))},
+	})
+}
+
+func delegationQuota(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("delegation quota", flag.ContinueOnError), args, "pck")
+	if err != nil {
+		return err
+	}
+	return printQuota(c, out, pos[0])
+}
+
+func delegationSetMaxChildren(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("delegation set-max-children", flag.ContinueOnError), args, "pck", "maxchildren")
+	if err != nil {
+		return err
+	}
+	n, err := strconv.ParseUint(pos[1], 10, 8)
+	if err != nil {
+		return fmt.Errorf("maxchildren must be between 0 and 255")
+	}
+	if err := c.SetMaxChildren(pos[0], uint8(n)); err != nil {
+		return err
+	}
+	return out.done("%s can now have %d direct children", pos[0], n)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/Kthanasis/Tenant-Service-Cloud-Management-using-Blockchain/client"
+)
+
+var grantPolicyCommands = map[string]command{
+	"allow":  grantRuleSet(true),
+	"forbid": grantRuleSet(false),
+	"show":   grantPolicyShow,
+}
+
+//grantRuleSet returns the command that allows or forbids a kind of grant between two entity types
+func grantRuleSet(allowed bool) command {
+	action, done := "forbid", "forbidden"
+	if allowed {
+		action, done = "allow", "allowed"
+	}
+	return func(c *client.Client, out *printer, args []string) error {
+		pos, err := parse(flag.NewFlagSet("grant-policy "+action, flag.ContinueOnError), args, "grant", "from", "to")
+		if err != nil {
+			return err
+		}
+		if err := c.SetGrantRule(pos[0], pos[1], pos[2], allowed); err != nil {
+			return err
+		}
+		return out.done("%s from %s to %s %s", pos[0], pos[1], pos[2], done)
+	}
+}
+
+func grantPolicyShow(c *client.Client, out *printer, args []string) error {
+	if _, err := parse(flag.NewFlagSet("grant-policy show", flag.ContinueOnError), args); err != nil {
+		return err
+	}
+	rules, err := c.GetGrantPolicy()
+	if err != nil {
+		return err
+	}
+	var rows [][]string
+	for _, rule := range rules {
+		rows = append(rows, []string{rule.Grant, rule.From, rule.To, formatBool(rule.Allowed), formatBool(rule.Default)})
+	}
+	return out.table(rules, []string{"GRANT", "FROM", "TO", "ALLOWED", "DEFAULT"}, rows)
+}
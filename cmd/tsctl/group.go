@@ -0,0 +1,190 @@
+package main
+
+import (
+	"flag"
+	"strings"
+
+	"github.com/Kthanasis/Tenant-Service-Cloud-Management-using-Blockchain/client"
+)
+
+var organizationCommands = map[string]command{
+	"create": organizationCreate,
+	"show":   organizationShow,
+	"add":    memberAdd("organization"),
+	"accept": memberAccept("organization"),
+	"remove": memberRemove("organization"),
+}
+
+var groupCommands = map[string]command{
+	"create":           groupCreate,
+	"show":             groupShow,
+	"destroy":          groupDestroy,
+	"add":              memberAdd("group"),
+	"accept":           memberAccept("group"),
+	"remove":           memberRemove("group"),
+	"dependent-grants": dependentGrants("group"),
+}
+
+func organizationCreate(c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("organization create", flag.ContinueOnError)
+	name := fs.String("name", "", "name of the organization")
+	owner := fs.String("owner", "", "tenant that manages the organization")
+	pos, err := parse(fs, args, "pck")
+	if err != nil {
+		return err
+	}
+	if err := required(fs, "name", "owner"); err != nil {
+		return err
+	}
+	if err := c.RegisterOrganization(pos[0], *name, *owner); err != nil {
+		return err
+	}
+	return out.done("organization %s registered", pos[0])
+}
+
+func organizationShow(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("organization show", flag.ContinueOnError), args, "pck")
+	if err != nil {
+		return err
+	}
+	organization, err := c.IsOrganization(pos[0])
+	if err != nil {
+		return err
+	}
+	return out.record(organization, [][2]string{
+		{"Pck", organization.Pck},
+		{"Name", organization.Name},
+		{"Owner", organization.Owner},
+		{"Members", strings.Join(organization.Members, ", ")},
+		{"Groups", strings.Join(organization.Groups, ", ")},
+		{"Registered", formatBool(organization.Registered)},
+	})
+}
+
+func groupCreate(c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("group create", flag.ContinueOnError)
+	name := fs.String("name", "", "name of the group")
+	organization := fs.String("organization", "", "organization the group belongs to")
+	pos, err := parse(fs, args, "pck")
+	if err != nil {
+		return err
+	}
+	if err := required(fs, "name", "organization"); err != nil {
+		return err
+	}
+	if err := c.RegisterGroup(pos[0], *name, *organization); err != nil {
+		return err
+	}
+	return out.done("group %s registered", pos[0])
+}
+
+func groupShow(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("group show", flag.ContinueOnError), args, "pck")
+	if err != nil {
+		return err
+	}
+	group, err := c.IsGroup(pos[0])
+	if err != nil {
+		return err
+	}
+	return out.record(group, [][2]string{
+		{"Pck", group.Pck},
+		{"Name", group.Name},
+		{"Organization", group.Organization},
+		{"Members", strings.Join(group.Members, ", ")},
+		{"Registered", formatBool(group.Registered)},
+	})
+}
+
+func groupDestroy(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("group destroy", flag.ContinueOnError), args, "pck")
+	if err != nil {
+		return err
+	}
+	if err := c.UnRegisterGroup(pos[0]); err != nil {
+		return err
+	}
+	return out.done("group %s unregistered", pos[0])
+}
+
+//memberAdd returns the command that adds a tenant to an organization or group
+func memberAdd(kind string) command {
+	return func(c *client.Client, out *printer, args []string) error {
+		pos, err := parse(flag.NewFlagSet(kind+" add", flag.ContinueOnError), args, "pck", "tenant")
+		if err != nil {
+			return err
+		}
+		if err := c.AddMember(pos[0], pos[1]); err != nil {
+			return err
+		}
+		return out.done("%s added to %s %s, or invited when it has to accept", pos[1], kind, pos[0])
+	}
+}
+
+//memberAccept returns the command that accepts the invitation of a tenant to an organization or group
+func memberAccept(kind string) command {
+	return func(c *client.Client, out *printer, args []string) error {
+		pos, err := parse(flag.NewFlagSet(kind+" accept", flag.ContinueOnError), args, "pck", "tenant")
+		if err != nil {
+			return err
+		}
+		if err := c.AcceptMembership(pos[0], pos[1]); err != nil {
+			return err
+		}
+		return out.done("%s joined %s %s", pos[1], kind, pos[0])
+	}
+}
+
+//memberRemove returns the command that removes a tenant from an organization or group
+func memberRemove(kind string) command {
+	return func(c *client.Client, out *printer, args []string) error {
+		pos, err := parse(flag.NewFlagSet(kind+" remove", flag.ContinueOnError), args, "pck", "tenant")
+		if err != nil {
+			return err
+		}
+		if err := c.RemoveMember(pos[0], pos[1]); err != nil {
+			return err
+		}
+		return out.done("%s removed from %s %s", pos[1], kind, pos[0])
+	}
+}
+
+//memberAccess returns the command that checks if a Delegation or SubDelegation gives a tenant access,
+//directly or as a member of the group that received it
+func memberAccess(kind string) command {
+	return func(c *client.Client, out *printer, args []string) error {
+		pos, err := parse(flag.NewFlagSet(kind+" access", flag.ContinueOnError), args, "pck", "tenant")
+		if err != nil {
+			return err
+		}
+		allowed, err := c.CheckMemberAccess(pos[0], pos[1])
+		if err != nil {
+			return err
+		}
+		return out.record(map[string]interface{}{"pck": pos[0], "tenant": pos[1], "allowed": allowed}, [][2]string{
+			{"Pck", pos[0]},
+			{"Tenant", pos[1]},
+			{"Allowed", formatBool(allowed)},
+		})
+	}
+}
+
+func tenantMemberships(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("tenant memberships", flag.ContinueOnError), args, "pck")
+	if err != nil {
+		return err
+	}
+	memberships, err := c.GetMemberships(pos[0])
+	if err != nil {
+		return err
+	}
+	var rows [][]string
+	for _, membership := range memberships {
+		kind := "organization"
+		if membership.Kind == "G" {
+			kind = "group"
+		}
+		rows = append(rows, []string{membership.Container, kind})
+	}
+	return out.table(memberships, []string{"PCK", "KIND"}, rows)
+}
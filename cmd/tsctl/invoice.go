@@ -0,0 +1,144 @@
+package main
+
+import (
+	"flag"
+	"strings"
+	"time"
+
+	"github.com/Kthanasis/Tenant-Service-Cloud-Management-using-Blockchain/client"
+)
+
+var invoiceCommands = map[string]command{
+	"issue":   invoiceIssue,
+	"show":    invoiceShow,
+	"list":    invoiceList,
+	"pay":     invoicePay,
+	"dun":     invoiceDun,
+	"dunning": invoiceDunning,
+}
+
+//printInvoice prints an invoice and whether it has been paid
+func printInvoice(out *printer, invoice *client.Invoice) error {
+	paid := "no"
+	if invoice.Paid {
+		paid = formatUnix(uint64(invoice.PaidAt)) + " (" + invoice.Payment + ")"
+	}
+	return out.record(invoice, [][2]string{
+		{"Tenant", invoice.Tenant},
+		{"Invoice", invoice.InvoiceID},
+		{"Amount", invoice.Amount.String()},
+		{"Issued", formatUnix(uint64(invoice.Issued))},
+		{"Due", formatUnix(uint64(invoice.Due))},
+		{"Paid", paid},
+	})
+}
+
+//printDunning prints the dunning record of a tenant
+func printDunning(out *printer, dunning *client.Dunning) error {
+	reinstated := ""
+	if dunning.ReinstatedAt != 0 {
+		reinstated = formatUnix(uint64(dunning.ReinstatedAt))
+	}
+	return out.record(dunning, [][2]string{
+		{"Tenant", dunning.Tenant},
+		{"Active", formatBool(dunning.Active)},
+		{"Grace", (time.Duration(dunning.Grace) * time.Second).String()},
+		{"Overdue", strings.Join(dunning.Invoices, ", ")},
+		{"Suspended", strings.Join(dunning.Suspended, ", ")},
+		{"Reinstated", reinstated},
+	})
+}
+
+func invoiceIssue(c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("invoice issue", flag.ContinueOnError)
+	currency := fs.String("currency", "", "currency of the amount, the currency of the wallet of the tenant when empty")
+	due := fs.String("due", "30d", "when the invoice must be paid, a time or a duration from now")
+	pos, err := parse(fs, args, "tenant", "invoiceid", "amount")
+	if err != nil {
+		return err
+	}
+	duetime, err := parseTime(*due, time.Now())
+	if err != nil {
+		return err
+	}
+	if err := c.IssueInvoice(pos[0], pos[1], amountText(pos[2], *currency), duetime); err != nil {
+		return err
+	}
+	invoice, err := c.GetInvoice(pos[0], pos[1])
+	if err != nil {
+		return err
+	}
+	return printInvoice(out, invoice)
+}
+
+func invoiceShow(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("invoice show", flag.ContinueOnError), args, "tenant", "invoiceid")
+	if err != nil {
+		return err
+	}
+	invoice, err := c.GetInvoice(pos[0], pos[1])
+	if err != nil {
+		return err
+	}
+	return printInvoice(out, invoice)
+}
+
+func invoiceList(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("invoice list", flag.ContinueOnError), args, "tenant")
+	if err != nil {
+		return err
+	}
+	invoices, err := c.GetInvoices(pos[0])
+	if err != nil {
+		return err
+	}
+	var rows [][]string
+	for _, invoice := range invoices {
+		rows = append(rows, []string{invoice.InvoiceID, invoice.Amount.String(), formatUnix(uint64(invoice.Due)), formatBool(invoice.Paid), invoice.Payment})
+	}
+	return out.table(invoices, []string{"INVOICE", "AMOUNT", "DUE", "PAID", "PAYMENT"}, rows)
+}
+
+func invoicePay(c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("invoice pay", flag.ContinueOnError)
+	reference := fs.String("reference", "", "payment made outside the ledger, for tenants without a wallet")
+	pos, err := parse(fs, args, "tenant", "invoiceid")
+	if err != nil {
+		return err
+	}
+	invoice, err := c.PayInvoice(pos[0], pos[1], *reference)
+	if err != nil {
+		return err
+	}
+	return printInvoice(out, invoice)
+}
+
+func invoiceDun(c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("invoice dun", flag.ContinueOnError)
+	grace := fs.String("grace", "7d", "how long after the due date an unpaid invoice suspends the grants")
+	pos, err := parse(fs, args, "tenant")
+	if err != nil {
+		return err
+	}
+	graceperiod, err := parseDuration(*grace)
+	if err != nil {
+		return err
+	}
+	dunning, err := c.RunDunning(pos[0], graceperiod)
+	if err != nil {
+		return err
+	}
+	return printDunning(out, dunning)
+}
+
+func invoiceDunning(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("invoice dunning", flag.ContinueOnError), args, "tenant")
+	if err != nil {
+		return err
+	}
+	dunning, err := c.GetDunning(pos[0])
+	if err != nil {
+		return err
+	}
+	return printDunning(out, dunning)
+}
@@ -0,0 +1,124 @@
+//tsctl is a command line tool for the tenant service chaincode. It loads a connection profile
+//once and replaces the peer chaincode invoke commands of commands.txt
+//
+//	tsctl [-profile connection.json] [-output table|json] <resource> <action> [arguments]
+//
+//	tsctl tenant enroll T10 -name "Tenant Ten" -email 10@mail.com -phone 1010101010
+//	tsctl delegation create D1 -from S1 -to S2 -subdel 2 -expires 72h
+//	tsctl subdelegation create SD1 -parent D1 -to T4 -subdel 1 -issue 2020-06-01 -expires 2020-07-01
+//	tsctl delegation tree D1
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Kthanasis/Tenant-Service-Cloud-Management-using-Blockchain/client"
+)
+
+//command runs one action of a resource with the arguments left after the action name
+type command func(c *client.Client, out *printer, args []string) error
+
+//resources maps every resource and action to the command that runs it
+var resources = map[string]map[string]command{
+	"ledger":         ledgerCommands,
+	"tenant":         tenantCommands,
+	"service":        serviceCommands,
+	"delegation":     delegationCommands,
+	"subdelegation":  subdelegationCommands,
+	"currency":       currencyCommands,
+	"wallet":         walletCommands,
+	"invoice":        invoiceCommands,
+	"quote":          quoteCommands,
+	"proposal":       proposalCommands,
+	"role":           roleCommands,
+	"access":         accessCommands,
+	"grant-policy":   grantPolicyCommands,
+	"cascade-policy": cascadePolicyCommands,
+	"organization":   organizationCommands,
+	"group":          groupCommands,
+}
+
+func main() {
+	global := flag.NewFlagSet("tsctl", flag.ExitOnError)
+	profilePath := global.String("profile", defaultProfile(), "connection profile, also read from $TSCTL_PROFILE")
+	output := global.String("output", "table", "output format, table or json")
+	global.Usage = func() { usage(global) }
+	global.Parse(os.Args[1:])
+
+	args := global.Args()
+	if len(args) < 2 {
+		usage(global)
+		os.Exit(2)
+	}
+
+	actions, ok := resources[args[0]]
+	if !ok {
+		fail(fmt.Errorf("unknown resource %q", args[0]))
+	}
+	run, ok := actions[args[1]]
+	if !ok {
+		fail(fmt.Errorf("unknown action %q for %s, expected one of %s", args[1], args[0], strings.Join(names(actions), ", ")))
+	}
+
+	out, err := newPrinter(*output)
+	if err != nil {
+		fail(err)
+	}
+
+	profile, err := client.LoadProfile(*profilePath)
+	if err != nil {
+		fail(err)
+	}
+	conn, err := client.Dial(profile)
+	if err != nil {
+		fail(err)
+	}
+	defer conn.Close()
+
+	if err := run(conn.Client(), out, args[2:]); err != nil {
+		conn.Close()
+		fail(err)
+	}
+}
+
+func defaultProfile() string {
+	if path := os.Getenv("TSCTL_PROFILE"); path != "" {
+		return path
+	}
+	return "connection.json"
+}
+
+func usage(global *flag.FlagSet) {
+	fmt.Fprintf(os.Stderr, "usage: tsctl [flags] <resource> <action> [arguments]\n\nflags:\n")
+	global.PrintDefaults()
+	fmt.Fprintf(os.Stderr, "\nresources:\n")
+	for _, resource := range names(resources) {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", resource, strings.Join(names(resources[resource]), ", "))
+	}
+	fmt.Fprintf(os.Stderr, "\ntimes accept now, Unix seconds, 2006-01-02, 2006-01-02T15:04, RFC 3339 or a duration like 72h, 30m, 7d or 2w\n")
+}
+
+func names(m interface{}) []string {
+	var keys []string
+	switch m := m.(type) {
+	case map[string]map[string]command:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]command:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "tsctl: %s\n", err.Error())
+	os.Exit(1)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+//printer writes the results of the commands either as aligned tables or as JSON
+type printer struct {
+	json bool
+}
+
+func newPrinter(format string) (*printer, error) {
+	switch format {
+	case "table":
+		return &printer{}, nil
+	case "json":
+		return &printer{json: true}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q, expected table or json", format)
+	}
+}
+
+//table prints the value as JSON, or as a table with the given header and rows
+func (p *printer) table(value interface{}, header []string, rows [][]string) error {
+	if p.json {
+		return p.value(value)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+//record prints a single record as JSON, or as a two column table of its fields
+func (p *printer) record(value interface{}, fields [][2]string) error {
+	if p.json {
+		return p.value(value)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, field := range fields {
+		fmt.Fprintf(w, "%s:\t%s\n", field[0], field[1])
+	}
+	return w.Flush()
+}
+
+//value prints any value as indented JSON, or with its default format
+func (p *printer) value(value interface{}) error {
+	if !p.json {
+		fmt.Println(value)
+		return nil
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+//done prints the confirmation of a submitted transaction
+func (p *printer) done(format string, args ...interface{}) error {
+	if p.json {
+		return p.value(map[string]string{"result": fmt.Sprintf(format, args...)})
+	}
+	fmt.Printf(format+"\n", args...)
+	return nil
+}
+
+func formatUnix(seconds uint64) string {
+	return time.Unix(int64(seconds), 0).Format("2006-01-02 15:04:05")
+}
+
+func formatBool(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
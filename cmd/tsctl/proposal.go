@@ -0,0 +1,115 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/Kthanasis/Tenant-Service-Cloud-Management-using-Blockchain/client"
+)
+
+var proposalCommands = map[string]command{
+	"create":      proposalCreate,
+	"show":        proposalShow,
+	"list":        proposalList,
+	"accept":      proposalStep("accept", (*client.Client).AcceptProposal),
+	"countersign": proposalStep("countersign", (*client.Client).CountersignProposal),
+	"decline":     proposalStep("decline", (*client.Client).DeclineProposal),
+	"withdraw":    proposalStep("withdraw", (*client.Client).WithdrawProposal),
+	"expire":      proposalStep("expire", (*client.Client).ExpireProposal),
+}
+
+//printProposal prints a proposal and who has agreed to it so far
+func printProposal(out *printer, proposal *client.Proposal) error {
+	return out.record(proposal, [][2]string{
+		{"Pck", proposal.Pck},
+		{"Grandor", proposal.Grandor},
+		{"Recipient", proposal.Recipient},
+		{"Scope", proposal.Scope},
+		{"Proposer", proposal.Proposer},
+		{"AcceptedBy", proposal.AcceptedBy},
+		{"Countersigner", proposal.Countersigner},
+		{"CountersignedBy", proposal.CountersignedBy},
+		{"Expires", formatUnix(uint64(proposal.ExpiresAt))},
+		{"Status", proposal.Status},
+	})
+}
+
+func proposalCreate(c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("proposal create", flag.ContinueOnError)
+	from := fs.String("from", "", "grandor service")
+	to := fs.String("to", "", "recipient service")
+	subdel := fs.Uint("subdel", 0, "how many levels the delegation can be subdelegated")
+	issue := fs.String("issue", "now", "start of the validity window")
+	expires := fs.String("expires", "", "end of the validity window, a date or a duration after -issue")
+	validity := fs.String("validity", "", "how long the proposal stays open, the contract default when empty")
+	scope := scopeFlags(fs)
+	pos, err := parse(fs, args, "pck")
+	if err != nil {
+		return err
+	}
+	if err := required(fs, "from", "to", "expires"); err != nil {
+		return err
+	}
+	if *subdel > 255 {
+		return fmt.Errorf("-subdel must be between 0 and 255")
+	}
+	issued, expiry, err := window(*issue, *expires)
+	if err != nil {
+		return err
+	}
+	var openfor time.Duration
+	if *validity != "" {
+		if openfor, err = parseDuration(*validity); err != nil {
+			return err
+		}
+	}
+
+	proposal, err := c.ProposeDelegation(pos[0], *from, *to, uint8(*subdel), issued, expiry, scope(), openfor)
+	if err != nil {
+		return err
+	}
+	return printProposal(out, proposal)
+}
+
+//proposalStep returns the command that moves a proposal on with one of the steps of the client
+func proposalStep(name string, step func(*client.Client, string) (*client.Proposal, error)) command {
+	return func(c *client.Client, out *printer, args []string) error {
+		pos, err := parse(flag.NewFlagSet("proposal "+name, flag.ContinueOnError), args, "pck")
+		if err != nil {
+			return err
+		}
+		proposal, err := step(c, pos[0])
+		if err != nil {
+			return err
+		}
+		return printProposal(out, proposal)
+	}
+}
+
+func proposalShow(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("proposal show", flag.ContinueOnError), args, "pck")
+	if err != nil {
+		return err
+	}
+	proposal, err := c.GetProposal(pos[0])
+	if err != nil {
+		return err
+	}
+	return printProposal(out, proposal)
+}
+
+func proposalList(c *client.Client, out *printer, args []string) error {
+	if _, err := parse(flag.NewFlagSet("proposal list", flag.ContinueOnError), args); err != nil {
+		return err
+	}
+	proposals, err := c.GetProposals()
+	if err != nil {
+		return err
+	}
+	var rows [][]string
+	for _, proposal := range proposals {
+		rows = append(rows, []string{proposal.Pck, proposal.Grandor, proposal.Recipient, proposal.Status, formatUnix(uint64(proposal.ExpiresAt))})
+	}
+	return out.table(proposals, []string{"PCK", "GRANDOR", "RECIPIENT", "STATUS", "EXPIRES"}, rows)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Kthanasis/Tenant-Service-Cloud-Management-using-Blockchain/client"
+)
+
+var quoteCommands = map[string]command{
+	"estimate": quoteEstimate,
+	"issue":    quoteIssue,
+	"show":     quoteShow,
+}
+
+//quoteFlags registers the flags that describe the prospective grant of a quote, the returned function
+//gives the cores and the window after parsing
+func quoteFlags(fs *flag.FlagSet) func() (uint64, time.Time, time.Time, error) {
+	cores := fs.Uint64("cores", 0, "cores the grant allows")
+	issue := fs.String("issue", "now", "start of the validity window")
+	expires := fs.String("expires", "", "end of the validity window, a date or a duration after -issue")
+
+	return func() (uint64, time.Time, time.Time, error) {
+		if err := required(fs, "expires"); err != nil {
+			return 0, time.Time{}, time.Time{}, err
+		}
+		if *cores == 0 {
+			return 0, time.Time{}, time.Time{}, fmt.Errorf("%s requires -cores", fs.Name())
+		}
+		issued, expiry, err := window(*issue, *expires)
+		return *cores, issued, expiry, err
+	}
+}
+
+//printQuote prints the items and the total of a quote, an issued quote is followed by the flags the
+//grant must be created with to lock it in
+func printQuote(out *printer, quote *client.Quote) error {
+	var rows [][]string
+	for _, item := range quote.Items {
+		rows = append(rows, []string{formatUnix(item.From), formatUnix(item.To), strconv.FormatUint(item.Cores, 10),
+			(time.Duration(item.Seconds) * time.Second).String(), item.Amount.String()})
+	}
+	rows = append(rows, []string{"", "", "", "total", quote.Total.String()})
+	if err := out.table(quote, []string{"FROM", "TO", "CORES", "DURATION", "AMOUNT"}, rows); err != nil {
+		return err
+	}
+	if out.json {
+		return nil
+	}
+
+	fmt.Printf("\nservice %s at %s per core and hour\n", quote.Service, quote.Price)
+	if quote.Pck != "" {
+		fmt.Printf("quote of %s, locked: %s, valid until %s, create it with -issue %d -expires %d -cores %d\n",
+			quote.Pck, formatBool(quote.Locked), formatUnix(uint64(quote.ValidUntil)), quote.Issue, quote.Expiry, quote.Cores)
+	}
+	return nil
+}
+
+func quoteEstimate(c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("quote estimate", flag.ContinueOnError)
+	grant := quoteFlags(fs)
+	pos, err := parse(fs, args, "grandor")
+	if err != nil {
+		return err
+	}
+	cores, issued, expiry, err := grant()
+	if err != nil {
+		return err
+	}
+	quote, err := c.QuoteGrant(pos[0], cores, issued, expiry)
+	if err != nil {
+		return err
+	}
+	return printQuote(out, quote)
+}
+
+func quoteIssue(c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("quote issue", flag.ContinueOnError)
+	grant := quoteFlags(fs)
+	pos, err := parse(fs, args, "pck", "grandor")
+	if err != nil {
+		return err
+	}
+	cores, issued, expiry, err := grant()
+	if err != nil {
+		return err
+	}
+	quote, err := c.IssueQuote(pos[0], pos[1], cores, issued, expiry)
+	if err != nil {
+		return err
+	}
+	return printQuote(out, quote)
+}
+
+func quoteShow(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("quote show", flag.ContinueOnError), args, "pck")
+	if err != nil {
+		return err
+	}
+	quote, err := c.GetQuote(pos[0])
+	if err != nil {
+		return err
+	}
+	return printQuote(out, quote)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Kthanasis/Tenant-Service-Cloud-Management-using-Blockchain/client"
+)
+
+//the revocation policy actions are shared by delegations and subdelegations, kind names the resource
+
+//printRevocationPolicy prints a revocation policy and the revokers that have signed it so far
+func printRevocationPolicy(out *printer, policy *client.RevocationPolicy) error {
+	notbefore := "-"
+	if policy.NotBefore != 0 {
+		notbefore = formatUnix(policy.NotBefore)
+	}
+	k := "-"
+	if policy.Rule == "k-of-n" {
+		k = strconv.Itoa(int(policy.K))
+	}
+	return out.record(policy, [][2]string{
+		{"Grant", policy.Grant},
+		{"Rule", policy.Rule},
+		{"Parties", strings.Join(policy.Parties, ", ")},
+		{"K", k},
+		{"NotBefore", notbefore},
+		{"Signed", strings.Join(policy.Signed, ", ")},
+	})
+}
+
+//revocationPolicyShow returns the command that prints the revocation policy of a grant
+func revocationPolicyShow(kind string) command {
+	return func(c *client.Client, out *printer, args []string) error {
+		pos, err := parse(flag.NewFlagSet(kind+" revocation-policy", flag.ContinueOnError), args, "pck")
+		if err != nil {
+			return err
+		}
+		policy, err := c.GetRevocationPolicy(pos[0])
+		if err != nil {
+			return err
+		}
+		return printRevocationPolicy(out, policy)
+	}
+}
+
+//revocationPolicySet returns the command that sets the revocation policy of a grant
+func revocationPolicySet(kind string) command {
+	return func(c *client.Client, out *printer, args []string) error {
+		fs := flag.NewFlagSet(kind+" set-revocation-policy", flag.ContinueOnError)
+		rule := fs.String("rule", "any-of", "any-of, all-of, k-of-n or org-admin")
+		parties := fs.String("parties", "", "comma separated revokers the rule counts, all of the Revokers when empty")
+		k := fs.Uint("k", 0, "signatures a k-of-n rule needs")
+		notbefore := fs.String("not-before", "", "the "+kind+" cannot be revoked before, a date or a duration from now")
+		pos, err := parse(fs, args, "pck")
+		if err != nil {
+			return err
+		}
+		if *k > 255 {
+			return fmt.Errorf("-k must be between 0 and 255")
+		}
+
+		policy := client.RevocationPolicy{Rule: *rule, Parties: splitList(*parties), K: uint8(*k)}
+		if *notbefore != "" {
+			t, err := parseTime(*notbefore, time.Now())
+			if err != nil {
+				return err
+			}
+			policy.NotBefore = uint64(t.Unix())
+		}
+
+		if err := c.SetRevocationPolicy(pos[0], policy); err != nil {
+			return err
+		}
+		updated, err := c.GetRevocationPolicy(pos[0])
+		if err != nil {
+			return err
+		}
+		return printRevocationPolicy(out, updated)
+	}
+}
+
+//revokedMessage reports if a revocation revoked the grant or only signed its revocation policy
+func revokedMessage(out *printer, kind string, pck string, by string, revoked bool) error {
+	if revoked {
+		return out.done("%s %s revoked by %s", kind, pck, by)
+	}
+	return out.done("revocation of %s %s signed by %s, its revocation policy needs more revokers", kind, pck, by)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"strings"
+
+	"github.com/Kthanasis/Tenant-Service-Cloud-Management-using-Blockchain/client"
+)
+
+var roleCommands = map[string]command{
+	"whoami":  roleWhoAmI,
+	"assign":  roleAssign,
+	"revoke":  roleRevoke,
+	"list":    roleList,
+	"changes": roleChanges,
+}
+
+func roleWhoAmI(c *client.Client, out *printer, args []string) error {
+	if _, err := parse(flag.NewFlagSet("role whoami", flag.ContinueOnError), args); err != nil {
+		return err
+	}
+	caller, err := c.WhoAmI()
+	if err != nil {
+		return err
+	}
+	var held []string
+	for _, assignment := range caller.Roles {
+		if assignment.Scope != "" {
+			held = append(held, assignment.Role+" of "+assignment.Scope)
+		} else {
+			held = append(held, assignment.Role)
+		}
+	}
+	return out.record(caller, [][2]string{
+		{"Identity", caller.Identity},
+		{"MSP", caller.MSP},
+		{"Roles", strings.Join(held, ", ")},
+	})
+}
+
+func roleAssign(c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("role assign", flag.ContinueOnError)
+	msp := fs.String("msp", "", "organization of the identity")
+	scope := fs.String("scope", "", "service of a service-operator, tenant of a tenant role")
+	pos, err := parse(fs, args, "identity", "role")
+	if err != nil {
+		return err
+	}
+	if err := required(fs, "msp"); err != nil {
+		return err
+	}
+	if err := c.AssignRole(pos[0], *msp, pos[1], *scope); err != nil {
+		return err
+	}
+	return out.done("%s assigned to %s", pos[1], pos[0])
+}
+
+func roleRevoke(c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("role revoke", flag.ContinueOnError)
+	scope := fs.String("scope", "", "service of a service-operator, tenant of a tenant role")
+	pos, err := parse(fs, args, "identity", "role")
+	if err != nil {
+		return err
+	}
+	if err := c.RevokeRole(pos[0], pos[1], *scope); err != nil {
+		return err
+	}
+	return out.done("%s revoked from %s", pos[1], pos[0])
+}
+
+func roleList(c *client.Client, out *printer, args []string) error {
+	if _, err := parse(flag.NewFlagSet("role list", flag.ContinueOnError), args); err != nil {
+		return err
+	}
+	assignments, err := c.GetRoleAssignments()
+	if err != nil {
+		return err
+	}
+	var rows [][]string
+	for _, assignment := range assignments {
+		rows = append(rows, []string{assignment.Role, assignment.Scope, assignment.MSP, assignment.Identity})
+	}
+	return out.table(assignments, []string{"ROLE", "SCOPE", "MSP", "IDENTITY"}, rows)
+}
+
+func roleChanges(c *client.Client, out *printer, args []string) error {
+	if _, err := parse(flag.NewFlagSet("role changes", flag.ContinueOnError), args); err != nil {
+		return err
+	}
+	changes, err := c.GetRoleChanges()
+	if err != nil {
+		return err
+	}
+	var rows [][]string
+	for _, change := range changes {
+		rows = append(rows, []string{formatUnix(uint64(change.Timestamp)), change.Action, change.Role, change.Scope, change.Identity, change.ActorMSP})
+	}
+	return out.table(changes, []string{"TIME", "ACTION", "ROLE", "SCOPE", "IDENTITY", "BY"}, rows)
+}
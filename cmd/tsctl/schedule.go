@@ -0,0 +1,161 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Kthanasis/Tenant-Service-Cloud-Management-using-Blockchain/client"
+)
+
+//the schedule actions are shared by delegations and subdelegations, kind names the resource
+
+//formatWindow prints a schedule window as the flag that sets it
+func formatWindow(window client.ScheduleWindow) string {
+	switch window.Kind {
+	case "weekly":
+		return fmt.Sprintf("weekly %s@%s-%s", strings.Join(window.Days, ","), window.Start, window.End)
+	case "monthly":
+		return fmt.Sprintf("monthly %d@%s-%s", window.Day, window.Start, window.End)
+	}
+	return fmt.Sprintf("once %s to %s", formatUnix(window.From), formatUnix(window.Until))
+}
+
+//printSchedule prints a schedule and its windows
+func printSchedule(out *printer, schedule *client.Schedule) error {
+	windows := "always active"
+	if len(schedule.Windows) > 0 {
+		var parts []string
+		for _, window := range schedule.Windows {
+			parts = append(parts, formatWindow(window))
+		}
+		windows = strings.Join(parts, "; ")
+	}
+	return out.record(schedule, [][2]string{
+		{"Grant", schedule.Grant},
+		{"UTCOffset", strconv.Itoa(schedule.UTCOffset) + " minutes"},
+		{"Windows", windows},
+	})
+}
+
+//parseClockWindow splits a days@HH:MM-HH:MM window into its days and its clock times
+func parseClockWindow(value string) (string, string, string, error) {
+	parts := strings.SplitN(value, "@", 2)
+	if len(parts) != 2 {
+		return "", "", "", fmt.Errorf("cannot understand the window %q, expected days@HH:MM-HH:MM", value)
+	}
+	clock := strings.SplitN(parts[1], "-", 2)
+	if len(clock) != 2 {
+		return "", "", "", fmt.Errorf("cannot understand the window %q, expected days@HH:MM-HH:MM", value)
+	}
+	return parts[0], clock[0], clock[1], nil
+}
+
+//splitWindows splits a flag holding several windows separated by semicolons
+func splitWindows(value string) []string {
+	var windows []string
+	for _, window := range strings.Split(value, ";") {
+		if window = strings.TrimSpace(window); window != "" {
+			windows = append(windows, window)
+		}
+	}
+	return windows
+}
+
+//scheduleShow returns the command that prints the schedule of a grant
+func scheduleShow(kind string) command {
+	return func(c *client.Client, out *printer, args []string) error {
+		pos, err := parse(flag.NewFlagSet(kind+" schedule", flag.ContinueOnError), args, "pck")
+		if err != nil {
+			return err
+		}
+		schedule, err := c.GetSchedule(pos[0])
+		if err != nil {
+			return err
+		}
+		return printSchedule(out, schedule)
+	}
+}
+
+//scheduleSet returns the command that sets the schedule of a grant
+func scheduleSet(kind string) command {
+	return func(c *client.Client, out *printer, args []string) error {
+		fs := flag.NewFlagSet(kind+" set-schedule", flag.ContinueOnError)
+		offset := fs.Int("utc-offset", 0, "minutes the time of the windows is ahead of UTC")
+		weekly := fs.String("weekly", "", "weekly windows such as mon,tue,wed,thu,fri@09:00-18:00, separated by ;")
+		monthly := fs.String("monthly", "", "monthly windows such as 1@22:00-06:00, separated by ;")
+		once := fs.String("once", "", "single windows such as 2026-12-24/2026-12-27, dates or durations from now, separated by ;")
+		pos, err := parse(fs, args, "pck")
+		if err != nil {
+			return err
+		}
+
+		schedule := client.Schedule{UTCOffset: *offset, Windows: []client.ScheduleWindow{}}
+		for _, value := range splitWindows(*weekly) {
+			days, start, end, err := parseClockWindow(value)
+			if err != nil {
+				return err
+			}
+			schedule.Windows = append(schedule.Windows, client.ScheduleWindow{Kind: "weekly", Days: splitList(days), Start: start, End: end})
+		}
+		for _, value := range splitWindows(*monthly) {
+			day, start, end, err := parseClockWindow(value)
+			if err != nil {
+				return err
+			}
+			n, err := strconv.ParseUint(day, 10, 8)
+			if err != nil {
+				return fmt.Errorf("cannot understand the day of the month %q", day)
+			}
+			schedule.Windows = append(schedule.Windows, client.ScheduleWindow{Kind: "monthly", Day: uint8(n), Start: start, End: end})
+		}
+		for _, value := range splitWindows(*once) {
+			bounds := strings.SplitN(value, "/", 2)
+			if len(bounds) != 2 {
+				return fmt.Errorf("cannot understand the window %q, expected from/until", value)
+			}
+			from, err := parseTime(bounds[0], time.Now())
+			if err != nil {
+				return err
+			}
+			until, err := parseTime(bounds[1], from)
+			if err != nil {
+				return err
+			}
+			schedule.Windows = append(schedule.Windows, client.ScheduleWindow{Kind: "once", From: uint64(from.Unix()), Until: uint64(until.Unix())})
+		}
+
+		if err := c.SetSchedule(pos[0], schedule); err != nil {
+			return err
+		}
+		updated, err := c.GetSchedule(pos[0])
+		if err != nil {
+			return err
+		}
+		return printSchedule(out, updated)
+	}
+}
+
+//nextActivation returns the command that prints when a grant is next active
+func nextActivation(kind string) command {
+	return func(c *client.Client, out *printer, args []string) error {
+		pos, err := parse(flag.NewFlagSet(kind+" next-activation", flag.ContinueOnError), args, "pck")
+		if err != nil {
+			return err
+		}
+		next, err := c.NextActivation(pos[0])
+		if err != nil {
+			return err
+		}
+		at := "never"
+		if !next.IsZero() {
+			at = formatUnix(uint64(next.Unix()))
+		}
+		return out.record(map[string]interface{}{"pck": pos[0], "next": next}, [][2]string{
+			{"Pck", pos[0]},
+			{"Next", at},
+		})
+	}
+}
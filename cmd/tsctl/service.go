@@ -0,0 +1,247 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+
+	"github.com/Kthanasis/Tenant-Service-Cloud-Management-using-Blockchain/client"
+)
+
+var serviceCommands = map[string]command{
+	"register":         serviceRegister,
+	"unregister":       serviceUnregister,
+	"show":             serviceShow,
+	"transfer":         serviceTransfer,
+	"accept":           serviceAccept,
+	"price":            servicePrice,
+	"storage-price":    serviceStoragePrice,
+	"rounding":         serviceRounding,
+	"quota":            serviceQuota,
+	"set-quota":        serviceSetQuota,
+	"approval":         serviceApproval,
+	"countersigner":    serviceCountersigner,
+	"dependent-grants": dependentGrants("service"),
+}
+
+func serviceRegister(c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("service register", flag.ContinueOnError)
+	name := fs.String("name", "", "name of the service")
+	owner := fs.String("owner", "", "tenant that owns the service")
+	pos, err := parse(fs, args, "pck")
+	if err != nil {
+		return err
+	}
+	if err := required(fs, "name", "owner"); err != nil {
+		return err
+	}
+
+	if err := c.RegisterService(pos[0], *name, *owner); err != nil {
+		return err
+	}
+	return out.done("service %s registered", pos[0])
+}
+
+func serviceUnregister(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("service unregister", flag.ContinueOnError), args, "pck")
+	if err != nil {
+		return err
+	}
+	if err := c.UnRegisterService(pos[0]); err != nil {
+		return err
+	}
+	return out.done("service %s unregistered", pos[0])
+}
+
+func serviceShow(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("service show", flag.ContinueOnError), args, "pck")
+	if err != nil {
+		return err
+	}
+	service, err := c.IsService(pos[0])
+	if err != nil {
+		return err
+	}
+	return out.record(service, [][2]string{
+		{"Pck", service.Pck},
+		{"Name", service.Name},
+		{"Registered", formatBool(service.Registered)},
+		{"Owner", service.Owner},
+		{"OwnerMSP", service.OwnerMSP},
+		{"PendingOwner", service.PendingOwner},
+		{"Price", servicePriceText(service)},
+		{"StoragePrice", serviceStoragePriceText(service)},
+		{"Rounding", service.Rounding},
+		{"Quota", formatResources(service.Quota)},
+		{"ApprovalCores", strconv.FormatUint(service.ApprovalCores, 10)},
+	})
+}
+
+//servicePriceText prints the price of a service, services priced before currencies existed only
+//have a whole unit price
+func servicePriceText(service *client.Service) string {
+	if service.Price.Currency == "" {
+		return strconv.FormatUint(service.CostPerHour, 10)
+	}
+	return service.Price.String()
+}
+
+//serviceStoragePriceText prints the storage price of a service, storage without a price is free
+func serviceStoragePriceText(service *client.Service) string {
+	if service.StoragePrice.Amount == 0 {
+		return "free"
+	}
+	return service.StoragePrice.String()
+}
+
+//formatResources prints an amount of resources, a zero total reads as unlimited
+func formatResources(r client.Resources) string {
+	amount := func(value uint64, unit string) string {
+		if value == 0 {
+			return "unlimited"
+		}
+		return strconv.FormatUint(value, 10) + unit
+	}
+	return fmt.Sprintf("cores=%s memory=%s storage=%s", amount(r.Cores, ""), amount(r.Memory, "MB"), amount(r.Storage, "GB"))
+}
+
+//printQuota prints the quota of a Service or of a grant
+func printQuota(c *client.Client, out *printer, pck string) error {
+	quota, err := c.GetQuota(pck)
+	if err != nil {
+		return err
+	}
+	return out.record(quota, [][2]string{
+		{"Pck", quota.Pck},
+		{"Total", formatResources(quota.Total)},
+		{"Allocated", fmt.Sprintf("cores=%d memory=%dMB storage=%dGB", quota.Allocated.Cores, quota.Allocated.Memory, quota.Allocated.Storage)},
+		{"Free", fmt.Sprintf("cores=%d memory=%dMB storage=%dGB", quota.Free.Cores, quota.Free.Memory, quota.Free.Storage)},
+	})
+}
+
+func serviceQuota(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("service quota", flag.ContinueOnError), args, "pck")
+	if err != nil {
+		return err
+	}
+	return printQuota(c, out, pos[0])
+}
+
+func serviceSetQuota(c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("service set-quota", flag.ContinueOnError)
+	cores := fs.Uint64("cores", 0, "total cores of the service, 0 is unlimited")
+	memory := fs.Uint64("memory", 0, "total memory in MB of the service, 0 is unlimited")
+	storage := fs.Uint64("storage", 0, "total storage in GB of the service, 0 is unlimited")
+	pos, err := parse(fs, args, "pck")
+	if err != nil {
+		return err
+	}
+	if err := c.SetServiceQuota(pos[0], client.Resources{Cores: *cores, Memory: *memory, Storage: *storage}); err != nil {
+		return err
+	}
+	return printQuota(c, out, pos[0])
+}
+
+func serviceTransfer(c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("service transfer", flag.ContinueOnError)
+	to := fs.String("to", "", "tenant that becomes the new owner")
+	pos, err := parse(fs, args, "pck")
+	if err != nil {
+		return err
+	}
+	if err := required(fs, "to"); err != nil {
+		return err
+	}
+	if err := c.TransferServiceOwnership(pos[0], *to); err != nil {
+		return err
+	}
+	return out.done("ownership of service %s offered to %s", pos[0], *to)
+}
+
+func serviceAccept(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("service accept", flag.ContinueOnError), args, "pck")
+	if err != nil {
+		return err
+	}
+	if err := c.AcceptServiceOwnership(pos[0]); err != nil {
+		return err
+	}
+	return out.done("ownership of service %s accepted", pos[0])
+}
+
+func servicePrice(c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("service price", flag.ContinueOnError)
+	currency := fs.String("currency", "", "currency of the price, the service keeps its currency when empty")
+	pos, err := parse(fs, args, "pck", "costperhour")
+	if err != nil {
+		return err
+	}
+	if err := c.SetServicePrice(pos[0], amountText(pos[1], *currency)); err != nil {
+		return err
+	}
+	service, err := c.IsService(pos[0])
+	if err != nil {
+		return err
+	}
+	return out.done("price of service %s set to %s per core and hour", pos[0], service.Price)
+}
+
+func serviceStoragePrice(c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("service storage-price", flag.ContinueOnError)
+	currency := fs.String("currency", "", "currency of the price, the one of the service when empty")
+	pos, err := parse(fs, args, "pck", "pergbhour")
+	if err != nil {
+		return err
+	}
+	if err := c.SetServiceStoragePrice(pos[0], amountText(pos[1], *currency)); err != nil {
+		return err
+	}
+	service, err := c.IsService(pos[0])
+	if err != nil {
+		return err
+	}
+	return out.done("storage of service %s priced at %s per GB and hour", pos[0], serviceStoragePriceText(service))
+}
+
+func serviceRounding(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("service rounding", flag.ContinueOnError), args, "pck", "rule")
+	if err != nil {
+		return err
+	}
+	if err := c.SetServiceRounding(pos[0], pos[1]); err != nil {
+		return err
+	}
+	return out.done("service %s charges are rounded %s", pos[0], pos[1])
+}
+
+func serviceApproval(c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("service approval", flag.ContinueOnError)
+	cores := fs.Uint64("cores", 0, "delegations of more cores must be proposed and accepted, 0 never")
+	pos, err := parse(fs, args, "pck")
+	if err != nil {
+		return err
+	}
+	if err := c.SetApprovalThreshold(pos[0], *cores); err != nil {
+		return err
+	}
+	if *cores == 0 {
+		return out.done("delegations from %s no longer need approval", pos[0])
+	}
+	return out.done("delegations of more than %d cores from %s must be proposed", *cores, pos[0])
+}
+
+func serviceCountersigner(c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("service countersigner", flag.ContinueOnError)
+	msp := fs.String("msp", "", "organization that must countersign the proposals from the service, none when empty")
+	pos, err := parse(fs, args, "pck")
+	if err != nil {
+		return err
+	}
+	if err := c.SetCountersigner(pos[0], *msp); err != nil {
+		return err
+	}
+	if *msp == "" {
+		return out.done("proposals from %s no longer need to be countersigned", pos[0])
+	}
+	return out.done("proposals from %s must be countersigned by %s", pos[0], *msp)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/Kthanasis/Tenant-Service-Cloud-Management-using-Blockchain/client"
+)
+
+var subdelegationCommands = map[string]command{
+	"create":                subdelegationCreate,
+	"suspend":               subdelegationSuspend,
+	"resume":                subdelegationResume,
+	"revoke":                subdelegationRevoke,
+	"accept":                subdelegationAccept,
+	"decline":               subdelegationDecline,
+	"show":                  subdelegationShow,
+	"status":                subdelegationStatus,
+	"access":                memberAccess("subdelegation"),
+	"revocation-policy":     revocationPolicyShow("subdelegation"),
+	"set-revocation-policy": revocationPolicySet("subdelegation"),
+	"schedule":              scheduleShow("subdelegation"),
+	"set-schedule":          scheduleSet("subdelegation"),
+	"next-activation":       nextActivation("subdelegation"),
+	"transfer":              transferRequest("subdelegation"),
+	"approve-transfer":      transferApprove("subdelegation"),
+	"reject-transfer":       transferReject("subdelegation"),
+	"transfers":             transferHistory("subdelegation"),
+}
+
+func subdelegationCreate(c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("subdelegation create", flag.ContinueOnError)
+	parent := fs.String("parent", "", "Delegation or SubDelegation that is subdelegated")
+	to := fs.String("to", "", "recipient tenant, group or service when the grant policy allows it")
+	subdel := fs.Uint("subdel", 0, "how many levels the subdelegation can be subdelegated further")
+	issue := fs.String("issue", "now", "start of the validity window")
+	expires := fs.String("expires", "", "end of the validity window, a date or a duration after -issue")
+	scope := scopeFlags(fs)
+	pos, err := parse(fs, args, "pck")
+	if err != nil {
+		return err
+	}
+	if err := required(fs, "parent", "to", "expires"); err != nil {
+		return err
+	}
+	if *subdel > 255 {
+		return fmt.Errorf("-subdel must be between 0 and 255")
+	}
+	issued, expiry, err := window(*issue, *expires)
+	if err != nil {
+		return err
+	}
+
+	if err := c.RegisterSubDelegation(pos[0], *parent, *to, uint8(*subdel), issued, expiry, scope()); err != nil {
+		return err
+	}
+	return out.done("subdelegation %s created, valid from %s until %s once %s accepts it", pos[0], issued.Format("2006-01-02 15:04:05"), expiry.Format("2006-01-02 15:04:05"), *to)
+}
+
+func subdelegationSuspend(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("subdelegation suspend", flag.ContinueOnError), args, "pck")
+	if err != nil {
+		return err
+	}
+	if err := c.SuspendSubDelegation(pos[0]); err != nil {
+		return err
+	}
+	return out.done("subdelegation %s suspended", pos[0])
+}
+
+func subdelegationResume(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("subdelegation resume", flag.ContinueOnError), args, "pck")
+	if err != nil {
+		return err
+	}
+	if err := c.ResumeSubDelegation(pos[0]); err != nil {
+		return err
+	}
+	return out.done("subdelegation %s resumed", pos[0])
+}
+
+func subdelegationRevoke(c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("subdelegation revoke", flag.ContinueOnError)
+	by := fs.String("by", "", "revoker, must be in the Revokers of the subdelegation")
+	pos, err := parse(fs, args, "pck")
+	if err != nil {
+		return err
+	}
+	if err := required(fs, "by"); err != nil {
+		return err
+	}
+	if err := c.RevokeSubDelegation(pos[0], *by); err != nil {
+		return err
+	}
+	subdelegation, err := c.IsSubDelegation(pos[0])
+	if err != nil {
+		return err
+	}
+	return revokedMessage(out, "subdelegation", pos[0], *by, subdelegation.Revoked)
+}
+
+func subdelegationAccept(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("subdelegation accept", flag.ContinueOnError), args, "pck")
+	if err != nil {
+		return err
+	}
+	if err := c.AcceptGrant(pos[0]); err != nil {
+		return err
+	}
+	return out.done("subdelegation %s accepted", pos[0])
+}
+
+func subdelegationDecline(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("subdelegation decline", flag.ContinueOnError), args, "pck")
+	if err != nil {
+		return err
+	}
+	if err := c.DeclineGrant(pos[0]); err != nil {
+		return err
+	}
+	return out.done("subdelegation %s declined", pos[0])
+}
+
+func subdelegationShow(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("subdelegation show", flag.ContinueOnError), args, "pck")
+	if err != nil {
+		return err
+	}
+	subdelegation, err := c.IsSubDelegation(pos[0])
+	if err != nil {
+		return err
+	}
+	return out.record(subdelegation, grantFields((*client.Delegation)(subdelegation)))
+}
+
+func subdelegationStatus(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("subdelegation status", flag.ContinueOnError), args, "pck")
+	if err != nil {
+		return err
+	}
+
+	st := status{Pck: pos[0]}
+	if st.Valid, err = c.IsSubValid(pos[0]); err != nil {
+		return err
+	}
+	if st.Expired, err = c.IsSubExpired(pos[0]); err != nil {
+		return err
+	}
+	if st.Suspended, err = c.IsSubSuspended(pos[0]); err != nil {
+		return err
+	}
+	if st.Revoked, err = c.IsSubRevoked(pos[0]); err != nil {
+		return err
+	}
+	return out.record(st, statusFields(st))
+}
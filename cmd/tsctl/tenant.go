@@ -0,0 +1,222 @@
+package main
+
+import (
+	"flag"
+	"strings"
+
+	"github.com/Kthanasis/Tenant-Service-Cloud-Management-using-Blockchain/client"
+)
+
+var ledgerCommands = map[string]command{
+	"init": func(c *client.Client, out *printer, args []string) error {
+		fs := flag.NewFlagSet("ledger init", flag.ContinueOnError)
+		adminmsp := fs.String("admin-msp", "", "deploying organization whose admin bootstraps the role registry, the caller's when empty")
+		if _, err := parse(fs, args); err != nil {
+			return err
+		}
+		if err := c.InitLedger(*adminmsp); err != nil {
+			return err
+		}
+		return out.done("ledger initialised")
+	},
+	"index": func(c *client.Client, out *printer, args []string) error {
+		if _, err := parse(flag.NewFlagSet("ledger index", flag.ContinueOnError), args); err != nil {
+			return err
+		}
+		if err := c.IndexGrants(); err != nil {
+			return err
+		}
+		return out.done("grants indexed")
+	},
+}
+
+var tenantCommands = map[string]command{
+	"enroll":           tenantEnroll,
+	"update":           tenantUpdate,
+	"destroy":          tenantDestroy,
+	"show":             tenantShow,
+	"contact":          tenantContact,
+	"migrate":          tenantMigrate,
+	"erase":            tenantErase,
+	"erasure":          tenantErasure,
+	"statement":        tenantStatement,
+	"memberships":      tenantMemberships,
+	"dependent-grants": dependentGrants("tenant"),
+}
+
+func tenantEnroll(c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("tenant enroll", flag.ContinueOnError)
+	name := fs.String("name", "", "name of the tenant")
+	email := fs.String("email", "", "email of the tenant")
+	phone := fs.String("phone", "", "phone of the tenant")
+	pos, err := parse(fs, args, "pck")
+	if err != nil {
+		return err
+	}
+	if err := required(fs, "name"); err != nil {
+		return err
+	}
+
+	//the contact details are only sent when given, they travel in the transient map
+	var contact *client.Contact
+	if *email != "" || *phone != "" {
+		contact = &client.Contact{Email: *email, Phone: *phone}
+	}
+
+	if err := c.Enroll(pos[0], *name, contact); err != nil {
+		return err
+	}
+	return out.done("tenant %s enrolled", pos[0])
+}
+
+func tenantUpdate(c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("tenant update", flag.ContinueOnError)
+	name := fs.String("name", "", "new name of the tenant, unchanged if empty")
+	email := fs.String("email", "", "new email of the tenant, unchanged if empty")
+	phone := fs.String("phone", "", "new phone of the tenant, unchanged if empty")
+	pos, err := parse(fs, args, "pck")
+	if err != nil {
+		return err
+	}
+
+	//the contract replaces the name, and the contact when it is given, so the fields not given are carried over
+	tenant, err := c.IsTenant(pos[0])
+	if err != nil {
+		return err
+	}
+	if *name == "" {
+		*name = tenant.Name
+	}
+
+	var contact *client.Contact
+	if *email != "" || *phone != "" {
+		contact = &client.Contact{Email: *email, Phone: *phone}
+		if *email == "" || *phone == "" {
+			current, err := c.GetTenantContact(pos[0])
+			if err != nil {
+				return err
+			}
+			if *email == "" {
+				contact.Email = current.Email
+			}
+			if *phone == "" {
+				contact.Phone = current.Phone
+			}
+		}
+	}
+
+	if err := c.Update(pos[0], *name, contact); err != nil {
+		return err
+	}
+	return out.done("tenant %s updated", pos[0])
+}
+
+func tenantDestroy(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("tenant destroy", flag.ContinueOnError), args, "pck")
+	if err != nil {
+		return err
+	}
+	if err := c.DestroyTenant(pos[0]); err != nil {
+		return err
+	}
+	return out.done("tenant %s destroyed", pos[0])
+}
+
+func tenantShow(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("tenant show", flag.ContinueOnError), args, "pck")
+	if err != nil {
+		return err
+	}
+	tenant, err := c.IsTenant(pos[0])
+	if err != nil {
+		return err
+	}
+	return out.record(tenant, [][2]string{
+		{"Pck", tenant.Pck},
+		{"Name", tenant.Name},
+		{"ContactHash", tenant.ContactHash},
+		{"Identity", tenant.Identity},
+		{"IdentityMSP", tenant.IdentityMSP},
+		{"Registered", formatBool(tenant.Registered)},
+	})
+}
+
+func tenantContact(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("tenant contact", flag.ContinueOnError), args, "pck")
+	if err != nil {
+		return err
+	}
+	contact, err := c.GetTenantContact(pos[0])
+	if err != nil {
+		return err
+	}
+	return out.record(contact, [][2]string{
+		{"Pck", contact.Pck},
+		{"Email", contact.Email},
+		{"Phone", contact.Phone},
+	})
+}
+
+func tenantMigrate(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("tenant migrate", flag.ContinueOnError), args, "pck")
+	if err != nil {
+		return err
+	}
+	if err := c.MigrateTenantContact(pos[0]); err != nil {
+		return err
+	}
+	return out.done("contact details of tenant %s moved to the private data collection", pos[0])
+}
+
+func tenantStatement(c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("tenant statement", flag.ContinueOnError)
+	currency := fs.String("currency", "EUR", "currency the charges are consolidated in")
+	pos, err := parse(fs, args, "pck")
+	if err != nil {
+		return err
+	}
+	statement, err := c.GetTenantStatement(pos[0], *currency)
+	if err != nil {
+		return err
+	}
+	var rows [][]string
+	for _, line := range statement.Lines {
+		rows = append(rows, []string{line.Grant, line.Service, line.Charge.String(), client.ExchangeRate{Rate: line.Rate}.String(), line.Converted.String()})
+	}
+	rows = append(rows, []string{"TOTAL", "", "", "", statement.Total.String()})
+	return out.table(statement, []string{"GRANT", "SERVICE", "CHARGE", "RATE", "CONVERTED"}, rows)
+}
+
+func tenantErase(c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("tenant erase", flag.ContinueOnError)
+	successor := fs.String("transfer-to", "", "tenant that receives the grants, they are revoked if empty")
+	pos, err := parse(fs, args, "pck")
+	if err != nil {
+		return err
+	}
+	if err := c.EraseTenant(pos[0], *successor); err != nil {
+		return err
+	}
+	return out.done("tenant %s erased", pos[0])
+}
+
+func tenantErasure(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("tenant erasure", flag.ContinueOnError), args, "pck")
+	if err != nil {
+		return err
+	}
+	receipt, err := c.GetErasureReceipt(pos[0])
+	if err != nil {
+		return err
+	}
+	return out.record(receipt, [][2]string{
+		{"Tenant", receipt.Tenant},
+		{"TxID", receipt.TxID},
+		{"Timestamp", formatUnix(uint64(receipt.Timestamp))},
+		{"RequestedBy", receipt.RequestedBy},
+		{"Successor", receipt.Successor},
+		{"Revoked", strings.Join(receipt.Revoked, ", ")},
+		{"Suspended", strings.Join(receipt.Suspended, ", ")},
+		{"Transferred", strings.Join(receipt.Transferred, ", ")},
+	})
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/Kthanasis/Tenant-Service-Cloud-Management-using-Blockchain/client"
+)
+
+//the transfer actions are shared by delegations and subdelegations, kind names the resource
+
+//transferRequest returns the command that asks to move a grant to a new recipient
+func transferRequest(kind string) command {
+	return func(c *client.Client, out *printer, args []string) error {
+		fs := flag.NewFlagSet(kind+" transfer", flag.ContinueOnError)
+		to := fs.String("to", "", "new recipient")
+		pos, err := parse(fs, args, "pck")
+		if err != nil {
+			return err
+		}
+		if err := required(fs, "to"); err != nil {
+			return err
+		}
+		if err := c.TransferDelegation(pos[0], *to); err != nil {
+			return err
+		}
+		return out.done("asked to transfer %s %s to %s, its grandor has to approve it", kind, pos[0], *to)
+	}
+}
+
+//transferApprove returns the command that completes the open transfer of a grant
+func transferApprove(kind string) command {
+	return func(c *client.Client, out *printer, args []string) error {
+		pos, err := parse(flag.NewFlagSet(kind+" approve-transfer", flag.ContinueOnError), args, "pck")
+		if err != nil {
+			return err
+		}
+		if err := c.ApproveTransfer(pos[0]); err != nil {
+			return err
+		}
+		return out.done("transferred %s %s, pending until its new recipient accepts it", kind, pos[0])
+	}
+}
+
+//transferReject returns the command that closes the open transfer of a grant without moving it
+func transferReject(kind string) command {
+	return func(c *client.Client, out *printer, args []string) error {
+		pos, err := parse(flag.NewFlagSet(kind+" reject-transfer", flag.ContinueOnError), args, "pck")
+		if err != nil {
+			return err
+		}
+		if err := c.RejectTransfer(pos[0]); err != nil {
+			return err
+		}
+		return out.done("rejected the transfer of %s %s", kind, pos[0])
+	}
+}
+
+//transferHistory returns the command that lists the transfers of a grant
+func transferHistory(kind string) command {
+	return func(c *client.Client, out *printer, args []string) error {
+		pos, err := parse(flag.NewFlagSet(kind+" transfers", flag.ContinueOnError), args, "pck")
+		if err != nil {
+			return err
+		}
+		transfers, err := c.GetTransfers(pos[0])
+		if err != nil {
+			return err
+		}
+		var rows [][]string
+		for _, transfer := range transfers {
+			closed := ""
+			if transfer.Closed != 0 {
+				closed = formatUnix(uint64(transfer.Closed))
+			}
+			rows = append(rows, []string{transfer.From, transfer.To, transfer.Status, formatUnix(uint64(transfer.Requested)), closed, transfer.ClosedBy})
+		}
+		return out.table(transfers, []string{"FROM", "TO", "STATUS", "REQUESTED", "CLOSED", "CLOSED BY"}, rows)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/Kthanasis/Tenant-Service-Cloud-Management-using-Blockchain/client"
+)
+
+var walletCommands = map[string]command{
+	"open":         walletOpen,
+	"show":         walletShow,
+	"deposit":      walletDeposit,
+	"debit":        walletDebit,
+	"credit-limit": walletCreditLimit,
+	"hold":         walletHold,
+	"release":      walletRelease,
+	"holds":        walletHolds,
+	"entries":      walletEntries,
+}
+
+//printWallet prints the balance and the amounts of a wallet
+func printWallet(out *printer, wallet *client.Wallet) error {
+	return out.record(wallet, [][2]string{
+		{"Tenant", wallet.Tenant},
+		{"Balance", wallet.Balance.String()},
+		{"CreditLimit", wallet.CreditLimit.String()},
+		{"Held", wallet.Held.String()},
+		{"Available", wallet.Available.String()},
+	})
+}
+
+func walletOpen(c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("wallet open", flag.ContinueOnError)
+	currency := fs.String("currency", "EUR", "currency the wallet is kept in")
+	pos, err := parse(fs, args, "tenant")
+	if err != nil {
+		return err
+	}
+	if err := c.OpenWallet(pos[0], *currency); err != nil {
+		return err
+	}
+	return out.done("wallet of %s opened in %s", pos[0], *currency)
+}
+
+func walletShow(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("wallet show", flag.ContinueOnError), args, "tenant")
+	if err != nil {
+		return err
+	}
+	wallet, err := c.GetWallet(pos[0])
+	if err != nil {
+		return err
+	}
+	return printWallet(out, wallet)
+}
+
+func walletDeposit(c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("wallet deposit", flag.ContinueOnError)
+	currency := fs.String("currency", "", "currency of the amount, the currency of the wallet when empty")
+	reference := fs.String("reference", "", "payment the deposit comes from")
+	pos, err := parse(fs, args, "tenant", "amount")
+	if err != nil {
+		return err
+	}
+	wallet, err := c.Deposit(pos[0], amountText(pos[1], *currency), *reference)
+	if err != nil {
+		return err
+	}
+	return printWallet(out, wallet)
+}
+
+func walletDebit(c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("wallet debit", flag.ContinueOnError)
+	currency := fs.String("currency", "", "currency of the amount, the currency of the wallet when empty")
+	pos, err := parse(fs, args, "tenant", "amount", "reference")
+	if err != nil {
+		return err
+	}
+	wallet, err := c.Debit(pos[0], amountText(pos[1], *currency), pos[2])
+	if err != nil {
+		return err
+	}
+	return printWallet(out, wallet)
+}
+
+func walletCreditLimit(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("wallet credit-limit", flag.ContinueOnError), args, "tenant", "limit")
+	if err != nil {
+		return err
+	}
+	wallet, err := c.SetCreditLimit(pos[0], pos[1])
+	if err != nil {
+		return err
+	}
+	return printWallet(out, wallet)
+}
+
+func walletHold(c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("wallet hold", flag.ContinueOnError)
+	currency := fs.String("currency", "", "currency of the amount, the currency of the wallet when empty")
+	reason := fs.String("reason", "", "why the amount is held")
+	pos, err := parse(fs, args, "tenant", "holdid", "amount")
+	if err != nil {
+		return err
+	}
+	wallet, err := c.PlaceHold(pos[0], pos[1], amountText(pos[2], *currency), *reason)
+	if err != nil {
+		return err
+	}
+	return printWallet(out, wallet)
+}
+
+func walletRelease(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("wallet release", flag.ContinueOnError), args, "tenant", "holdid")
+	if err != nil {
+		return err
+	}
+	wallet, err := c.ReleaseHold(pos[0], pos[1])
+	if err != nil {
+		return err
+	}
+	return printWallet(out, wallet)
+}
+
+func walletHolds(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("wallet holds", flag.ContinueOnError), args, "tenant")
+	if err != nil {
+		return err
+	}
+	holds, err := c.GetHolds(pos[0])
+	if err != nil {
+		return err
+	}
+	var rows [][]string
+	for _, hold := range holds {
+		rows = append(rows, []string{hold.HoldID, hold.Amount.String(), hold.Reason, formatUnix(uint64(hold.Timestamp))})
+	}
+	return out.table(holds, []string{"HOLD", "AMOUNT", "REASON", "PLACED"}, rows)
+}
+
+func walletEntries(c *client.Client, out *printer, args []string) error {
+	pos, err := parse(flag.NewFlagSet("wallet entries", flag.ContinueOnError), args, "tenant")
+	if err != nil {
+		return err
+	}
+	entries, err := c.GetWalletEntries(pos[0])
+	if err != nil {
+		return err
+	}
+	var rows [][]string
+	for _, entry := range entries {
+		rows = append(rows, []string{formatUnix(uint64(entry.Timestamp)), entry.Kind, entry.Amount.String(), entry.Balance.String(), entry.Reference})
+	}
+	return out.table(entries, []string{"TIMESTAMP", "KIND", "AMOUNT", "BALANCE", "REFERENCE"}, rows)
+}
@@ -0,0 +1,35 @@
+//tsgateway serves the tenant service chaincode as REST resources over HTTP and JSON. It reaches
+//the chaincode through the Fabric Gateway peer of a tsctl connection profile
+//
+//	tsgateway -profile connection.json -listen :8080
+//
+//the OpenAPI document of the resources is served at /openapi.json
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/Kthanasis/Tenant-Service-Cloud-Management-using-Blockchain/client"
+	"github.com/Kthanasis/Tenant-Service-Cloud-Management-using-Blockchain/rest"
+)
+
+func main() {
+	profilePath := flag.String("profile", "connection.json", "connection profile of the Fabric Gateway peer")
+	listen := flag.String("listen", ":8080", "address to serve the REST resources on")
+	flag.Parse()
+
+	profile, err := client.LoadProfile(*profilePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	conn, err := client.Dial(profile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+
+	log.Printf("serving %s/%s on %s", profile.Channel, profile.Chaincode, *listen)
+	log.Fatal(http.ListenAndServe(*listen, rest.NewServer(conn.Client())))
+}
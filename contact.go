@@ -0,0 +1,217 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+//***************************************************************************************************
+//**																							   **
+//**							The following section Manages the Tenant Contact Details           **
+//**                                                                                               **
+//***************************************************************************************************
+
+//-------------------------------------Tenant Contact Management-------------------------------------
+//the email and phone of a tenant are personal data, they are kept in the private data collection
+//of collections_config.json and are passed to Enroll and Update through the transient map, so they
+//never appear in the transaction arguments. The public Tenant record only holds a salted hash of them,
+//the salt is a random value the client passes in the transient map and is only kept next to the details
+//in the collection, so the hash of an email or phone number cannot be brute forced from the ledger
+
+//tenantContactCollection is the private data collection holding the TenantContact records
+const tenantContactCollection = "tenantContactCollection"
+
+//contactTransientKey is the key of the transient map holding the contact details as JSON
+const contactTransientKey = "contact"
+
+//saltTransientKey is the key of the transient map holding the random salt of the contact hash
+const saltTransientKey = "salt"
+
+//minSaltLength is the least number of random bytes accepted as salt
+const minSaltLength = 16
+
+//contactReaderMSPs are the organizations allowed to read the contact details, it must match the
+//members of the collection in collections_config.json
+var contactReaderMSPs = []string{"Org1MSP"}
+
+//TenantContact describes the personal details of a tenant kept in the private data collection
+type TenantContact struct {
+	Pck   string `json:"pck"`
+	Email string `json:"email"`
+	Phone string `json:"phone"`
+	Salt  string `json:"salt"` //salt of the ContactHash stored on the public Tenant record
+	Type  string `json:"Type"` //TC for Tenant Contact
+}
+
+//contactInput is the JSON expected under the contact key of the transient map
+type contactInput struct {
+	Email string `json:"email"`
+	Phone string `json:"phone"`
+}
+
+//hash returns the salted hash of the contact details that is stored on the public record
+func (c *TenantContact) hash() string {
+	sum := sha256.Sum256([]byte(c.Salt + "\x00" + c.Email + "\x00" + c.Phone))
+	return hex.EncodeToString(sum[:])
+}
+
+//readContactInput reads the contact details from the transient map, it returns nil if they were not given
+func readContactInput(ctx contractapi.TransactionContextInterface) (*contactInput, error) {
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read the transient map. %s", err.Error())
+	}
+
+	contactAsBytes, ok := transient[contactTransientKey]
+	if !ok {
+		return nil, nil
+	}
+
+	input := new(contactInput)
+	if err := json.Unmarshal(contactAsBytes, input); err != nil {
+		return nil, fmt.Errorf("The %s transient field must be a JSON object with email and phone", contactTransientKey)
+	}
+
+	return input, nil
+}
+
+//readContactSalt reads the random salt the client passed in the transient map, it is never derived
+//from the transaction because everything in the transaction is public on the ledger
+func readContactSalt(ctx contractapi.TransactionContextInterface, pck string) (string, error) {
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return "", fmt.Errorf("Failed to read the transient map. %s", err.Error())
+	}
+
+	random := transient[saltTransientKey]
+	if len(random) < minSaltLength {
+		return "", fmt.Errorf("The %s transient field must hold at least %d random bytes", saltTransientKey, minSaltLength)
+	}
+
+	salt := sha256.Sum256(append(append(random, 0), []byte(pck)...))
+
+	return hex.EncodeToString(salt[:]), nil
+}
+
+//sampleContactSalt is the salt of the sample tenants of InitLedger, their contact details are public in
+//the source and InitLedger runs through -cci without a transient map, so it is derived from the transaction
+func sampleContactSalt(ctx contractapi.TransactionContextInterface, pck string) string {
+	salt := sha256.Sum256([]byte(ctx.GetStub().GetTxID() + "\x00" + pck))
+	return hex.EncodeToString(salt[:])
+}
+
+//putTenantContact stores the contact details of a tenant in the private data collection with their
+//salt and returns the hash to store on the public record
+func putTenantContact(ctx contractapi.TransactionContextInterface, pck string, email string, phone string, salt string) (string, error) {
+	contact := TenantContact{
+		Pck:   pck,
+		Email: email,
+		Phone: phone,
+		Salt:  salt,
+		Type:  "TC",
+	}
+
+	contactAsBytes, _ := json.Marshal(contact)
+	if err := ctx.GetStub().PutPrivateData(tenantContactCollection, pck, contactAsBytes); err != nil {
+		return "", fmt.Errorf("Failed to put to the private data collection. %s", err.Error())
+	}
+
+	return contact.hash(), nil
+}
+
+//clientMSPID returns the MSP of the identity that submitted the transaction
+func clientMSPID(ctx contractapi.TransactionContextInterface) (string, error) {
+	//the identity is read from the stub, GetClientIdentity holds a nil identity when the creator cannot be parsed
+	mspid, err := cid.GetMSPID(ctx.GetStub())
+	if err != nil {
+		return "", fmt.Errorf("Failed to read the MSP of the client. %s", err.Error())
+	}
+
+	return mspid, nil
+}
+
+//GetTenantContact returns the contact details of the tenant with given Pck (Key), only to the
+//organizations of contactReaderMSPs
+func (s *SmartContract) GetTenantContact(ctx contractapi.TransactionContextInterface, pck string) (*TenantContact, error) {
+	mspid, err := clientMSPID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !stringInSlice(mspid, contactReaderMSPs) {
+		return nil, fmt.Errorf("%s is not authorized to read the contact details of tenants", mspid)
+	}
+
+	tenant, err := s.IsTenant(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+
+	contactAsBytes, err := ctx.GetStub().GetPrivateData(tenantContactCollection, pck)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from the private data collection. %s", err.Error())
+	}
+	if contactAsBytes == nil {
+		return nil, fmt.Errorf("%s has no contact details", pck)
+	}
+
+	contact := new(TenantContact)
+	_ = json.Unmarshal(contactAsBytes, contact)
+
+	//the private record must be the one the public hash was computed from
+	if contact.hash() != tenant.ContactHash {
+		return nil, fmt.Errorf("The contact details of %s do not match the hash of the public record", pck)
+	}
+
+	return contact, nil
+}
+
+//MigrateTenantContact moves the email and phone that older versions of Enroll and Update wrote to the
+//public record of the tenant with given Pck (Key) into the private data collection, on behalf of the
+//tenant or of a platform admin. The salt is passed in the transient map as for Enroll
+func (s *SmartContract) MigrateTenantContact(ctx contractapi.TransactionContextInterface, pck string) error {
+	if requirePlatformAdmin(ctx) != nil {
+		if err := s.authorizeTenant(ctx, pck); err != nil {
+			return err
+		}
+	}
+
+	tenantAsBytes, err := ctx.GetStub().GetState(pck)
+	if err != nil {
+		return fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if tenantAsBytes == nil {
+		return fmt.Errorf("%s does not exist", pck)
+	}
+
+	legacy := new(contactInput)
+	_ = json.Unmarshal(tenantAsBytes, legacy)
+	if legacy.Email == "" && legacy.Phone == "" {
+		return fmt.Errorf("%s has no public contact details to migrate", pck)
+	}
+
+	tenant, err := s.IsTenant(ctx, pck)
+	if err != nil {
+		return err
+	}
+
+	salt, err := readContactSalt(ctx, pck)
+	if err != nil {
+		return err
+	}
+	tenant.ContactHash, err = putTenantContact(ctx, pck, legacy.Email, legacy.Phone, salt)
+	if err != nil {
+		return err
+	}
+
+	//the public record is rewritten without the legacy fields
+	tenantAsBytes, _ = json.Marshal(tenant)
+
+	return ctx.GetStub().PutState(pck, tenantAsBytes)
+}
+
+//--------------------------------------End Of Tenant Contact Management-----------------------------
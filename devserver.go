@@ -0,0 +1,30 @@
+//go:build devserver
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Kthanasis/Tenant-Service-Cloud-Management-using-Blockchain/client"
+	"github.com/Kthanasis/Tenant-Service-Cloud-Management-using-Blockchain/client/local"
+	"github.com/Kthanasis/Tenant-Service-Cloud-Management-using-Blockchain/rest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+//---------------------------------------REST Development Server-------------------------------------
+//when TENANT_REST_ADDR is set the chaincode is not started as a peer chaincode, instead the REST
+//resources of the rest package are served against this contract with an in-memory world state, so
+//the web portal can be developed without a Fabric network. The server is only built with the devserver
+//tag, go build -tags devserver, so the deployed chaincode does not ship the mock stub or an HTTP server
+
+//serveREST runs the REST resources on addr over the in-process chaincode, it only returns on error
+func serveREST(addr string, chaincode *contractapi.ContractChaincode) error {
+	backend := client.New(local.NewChaincodeTransport(chaincode))
+
+	fmt.Printf("Serving the REST resources over an in-memory world state on %s\n", addr)
+
+	return http.ListenAndServe(addr, rest.NewServer(backend))
+}
+
+//---------------------------------------End Of REST Development Server------------------------------
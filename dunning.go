@@ -0,0 +1,316 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+//***************************************************************************************************
+//**																							   **
+//**							The following section Manages the Dunning of Tenants               **
+//**                                                                                               **
+//***************************************************************************************************
+
+//-------------------------------------Dunning-------------------------------------------------------
+//a tenant with invoices still unpaid a grace period after they were due loses access. Dunning suspends
+//every grant the tenant receives, the SubDelegations it is the recipient of and the Delegations of the
+//services it owns, the same way SuspendDelegation and SuspendSubDelegation do, and remembers which
+//grants it suspended, on the record and with a mark on every grant. Once the payments are recorded and
+//no invoice is overdue any more those grants, and only those, are reinstated. Grants that were already
+//suspended or revoked are left alone, and so are grants suspended again by hand in the meantime, as that
+//suspension removes the mark. Billing identities run the dunning
+
+//object types of the composite keys of the dunning records and of the marks of the grants they suspended
+const (
+	dunningObjectType = "dunning"
+	dunnedObjectType  = "dunned"
+)
+
+//Dunning records the grants suspended because a tenant did not pay
+type Dunning struct {
+	Tenant       string   `json:"tenant"`
+	Active       bool     `json:"active"`       //true while the grants are suspended
+	Grace        int64    `json:"grace"`        //seconds after the due date an invoice becomes overdue
+	Invoices     []string `json:"invoices"`     //overdue invoices found by the last dunning
+	Suspended    []string `json:"suspended"`    //grants suspended by the dunning
+	DunnedAt     int64    `json:"dunnedat"`     //Unix seconds of the transaction that started the dunning
+	ReinstatedAt int64    `json:"reinstatedat"` //Unix seconds of the transaction that settled it, 0 while active
+	Type         string   `json:"Type"`         //DN for Dunning
+}
+
+//dunningKey returns the key of the dunning record of a tenant
+func dunningKey(ctx contractapi.TransactionContextInterface, tenant string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(dunningObjectType, []string{tenant})
+	if err != nil {
+		return "", fmt.Errorf("Failed to create the dunning key. %s", err.Error())
+	}
+	return key, nil
+}
+
+//GetDunning returns the dunning record of the tenant with given Pck (Key), a tenant that was never
+//dunned has an inactive one
+func (s *SmartContract) GetDunning(ctx contractapi.TransactionContextInterface, pck string) (*Dunning, error) {
+	key, err := dunningKey(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+	dunningAsBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+
+	dunning := &Dunning{Tenant: pck, Invoices: []string{}, Suspended: []string{}, Type: "DN"}
+	if dunningAsBytes != nil {
+		_ = json.Unmarshal(dunningAsBytes, dunning)
+	}
+
+	return dunning, nil
+}
+
+//putDunning stores the dunning record of a tenant and emits it as event
+func putDunning(ctx contractapi.TransactionContextInterface, dunning *Dunning, event string) error {
+	key, err := dunningKey(ctx, dunning.Tenant)
+	if err != nil {
+		return err
+	}
+
+	dunningAsBytes, _ := json.Marshal(dunning)
+	if err := ctx.GetStub().PutState(key, dunningAsBytes); err != nil {
+		return err
+	}
+
+	return ctx.GetStub().SetEvent(event, dunningAsBytes)
+}
+
+//dunnedKey returns the key of the mark of a grant suspended by a dunning
+func dunnedKey(ctx contractapi.TransactionContextInterface, grant string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(dunnedObjectType, []string{grant})
+	if err != nil {
+		return "", fmt.Errorf("Failed to create the dunned key. %s", err.Error())
+	}
+	return key, nil
+}
+
+//dunnedBy returns the tenant whose dunning suspended a grant, empty when no dunning did
+func dunnedBy(ctx contractapi.TransactionContextInterface, grant string) (string, error) {
+	key, err := dunnedKey(ctx, grant)
+	if err != nil {
+		return "", err
+	}
+	tenantAsBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return "", fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	return string(tenantAsBytes), nil
+}
+
+//clearDunned removes the mark a dunning left on a grant, a grant suspended by hand stays suspended when
+//the dunning is settled
+func clearDunned(ctx contractapi.TransactionContextInterface, grant string) error {
+	key, err := dunnedKey(ctx, grant)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().DelState(key)
+}
+
+//overdueInvoices returns the ids of the unpaid invoices of a tenant due more than grace seconds before now
+func (s *SmartContract) overdueInvoices(ctx contractapi.TransactionContextInterface, tenant string, grace int64, now int64) ([]string, error) {
+	invoices, err := s.GetInvoices(ctx, tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	overdue := []string{}
+	for _, invoice := range invoices {
+		if !invoice.Paid && invoice.Due+grace < now {
+			overdue = append(overdue, invoice.InvoiceID)
+		}
+	}
+
+	return overdue, nil
+}
+
+//paidGrants returns every Delegation and SubDelegation a tenant pays for, from the grant index
+func (s *SmartContract) paidGrants(ctx contractapi.TransactionContextInterface, tenant string) ([]*Delegation, error) {
+	return s.indexedGrants(ctx, payerIndex, tenant)
+}
+
+//RunDunning suspends every grant of the tenant with given Pck (Key) when one of its invoices is still
+//unpaid grace seconds after it was due. It can be run again, grants received since are suspended too
+func (s *SmartContract) RunDunning(ctx contractapi.TransactionContextInterface, pck string, grace string) (*Dunning, error) {
+	if err := requireBilling(ctx); err != nil {
+		return nil, err
+	}
+	tempgrace, err := strconv.ParseInt(grace, 10, 64)
+	if err != nil || tempgrace < 0 {
+		return nil, fmt.Errorf("grace must be a number of seconds")
+	}
+
+	tenant, err := s.IsTenant(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+	if tenant.Type != "T" {
+		return nil, fmt.Errorf("%s is not a Tenant", pck)
+	}
+
+	now, err := txSeconds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	overdue, err := s.overdueInvoices(ctx, pck, tempgrace, now)
+	if err != nil {
+		return nil, err
+	}
+
+	dunning, err := s.GetDunning(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+	if len(overdue) == 0 {
+		return dunning, nil
+	}
+
+	if !dunning.Active {
+		dunning.Active = true
+		dunning.Suspended = []string{}
+		dunning.DunnedAt = now
+		dunning.ReinstatedAt = 0
+	}
+	dunning.Grace = tempgrace
+	dunning.Invoices = overdue
+
+	grants, err := s.paidGrants(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+	for _, grant := range grants {
+		if grant.Suspended || grant.Revoked || grant.Declined || grant.Expiry <= uint64(now) {
+			continue
+		}
+
+		if grant.Type == "D" {
+			err = s.SuspendDelegation(ctx, grant.Pck)
+		} else {
+			err = s.SuspendSubDelegation(ctx, grant.Pck)
+		}
+		if err != nil {
+			return nil, err
+		}
+		key, err := dunnedKey(ctx, grant.Pck)
+		if err != nil {
+			return nil, err
+		}
+		if err := ctx.GetStub().PutState(key, []byte(pck)); err != nil {
+			return nil, err
+		}
+		dunning.Suspended = append(dunning.Suspended, grant.Pck)
+	}
+
+	if err := putDunning(ctx, dunning, "TenantDunned"); err != nil {
+		return nil, err
+	}
+
+	return dunning, nil
+}
+
+//resumable checks that a grant suspended by hand can give access again, a dunning that still holds it
+//or the unpaid balance of its payer keep it suspended
+func (s *SmartContract) resumable(ctx contractapi.TransactionContextInterface, grant *Delegation) error {
+	if !grant.Suspended {
+		return fmt.Errorf("%s is not suspended", grant.Pck)
+	}
+	if grant.Revoked {
+		return fmt.Errorf("%s has already been revoked", grant.Pck)
+	}
+
+	dunnedby, err := dunnedBy(ctx, grant.Pck)
+	if err != nil {
+		return err
+	}
+	if dunnedby != "" {
+		return fmt.Errorf("%s is suspended by the dunning of %s, it is reinstated once %s pays", grant.Pck, dunnedby, dunnedby)
+	}
+
+	payer, err := s.payingTenant(ctx, grant.Recipient)
+	if err != nil || payer == "" {
+		return err
+	}
+	dunning, err := s.GetDunning(ctx, payer)
+	if err != nil {
+		return err
+	}
+	if dunning.Active {
+		return fmt.Errorf("%s pays for %s and is being dunned", payer, grant.Pck)
+	}
+	wallet, err := s.findWallet(ctx, payer)
+	if err != nil {
+		return err
+	}
+	if wallet != nil && wallet.Balance.Amount+wallet.CreditLimit.Amount < 0 {
+		return fmt.Errorf("The wallet of %s, which pays for %s, is overdrawn", payer, grant.Pck)
+	}
+
+	return nil
+}
+
+//settleDunning reinstates the grants the dunning of a tenant suspended once none of its invoices is
+//overdue any more. The invoice paid in the same transaction still reads as unpaid, it is left out
+func (s *SmartContract) settleDunning(ctx contractapi.TransactionContextInterface, tenant string, paid string, now int64) error {
+	dunning, err := s.GetDunning(ctx, tenant)
+	if err != nil || !dunning.Active {
+		return err
+	}
+
+	overdue, err := s.overdueInvoices(ctx, tenant, dunning.Grace, now)
+	if err != nil {
+		return err
+	}
+	overdue = removeFromSlice(overdue, paid)
+	dunning.Invoices = overdue
+	if len(overdue) > 0 {
+		key, err := dunningKey(ctx, tenant)
+		if err != nil {
+			return err
+		}
+		dunningAsBytes, _ := json.Marshal(dunning)
+		return ctx.GetStub().PutState(key, dunningAsBytes)
+	}
+
+	for _, pck := range dunning.Suspended {
+		//a grant suspended again by hand since is left suspended
+		dunnedby, err := dunnedBy(ctx, pck)
+		if err != nil {
+			return err
+		}
+		if dunnedby != tenant {
+			continue
+		}
+		if err := clearDunned(ctx, pck); err != nil {
+			return err
+		}
+
+		grant, err := s.IsDelegation(ctx, pck)
+		if err != nil {
+			return err
+		}
+		//a grant revoked in the meantime stays revoked, reinstating it only lifts the suspension
+		grant.Suspended = false
+
+		grantAsBytes, _ := json.Marshal(grant)
+		if err := ctx.GetStub().PutState(pck, grantAsBytes); err != nil {
+			return err
+		}
+	}
+
+	dunning.Active = false
+	dunning.ReinstatedAt = now
+
+	return putDunning(ctx, dunning, "TenantReinstated")
+}
+
+//--------------------------------------End Of Dunning-----------------------------------------------
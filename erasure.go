@@ -0,0 +1,303 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+//***************************************************************************************************
+//**																							   **
+//**							The following section Manages the Tenant Erasure                   **
+//**                                                                                               **
+//***************************************************************************************************
+
+//-------------------------------------Tenant Erasure------------------------------------------------
+//EraseTenant removes the personal data of a tenant while keeping its record at the same key, so the
+//Grandor, Recipient and Revokers of the grants it took part in still point to an existing tenant.
+//The cascade policy of tenants applies first as to any deregistration, then every SubDelegation the
+//tenant still receives is either revoked or transferred to a successor and the whole operation is
+//recorded in an ErasureReceipt
+
+//erasureObjectType is the object type of the composite key of the ErasureReceipts
+const erasureObjectType = "erasure"
+
+//erasedName is the tombstone that replaces the name of an erased tenant
+const erasedName = "[erased]"
+
+//ErasureReceipt records the erasure of a tenant
+type ErasureReceipt struct {
+	Tenant      string        `json:"tenant"`
+	TxID        string        `json:"txid"`        //transaction that erased the tenant
+	Timestamp   int64         `json:"timestamp"`   //Unix seconds of the transaction
+	RequestedBy string        `json:"requestedby"` //MSP of the identity that submitted the erasure
+	Successor   string        `json:"successor"`   //tenant the grants were transferred to, empty if they were revoked
+	Revoked     []string      `json:"revoked"`     //grants revoked by the erasure
+	Suspended   []string      `json:"suspended"`   //grants suspended by the cascade policy of tenants
+	Transferred []string      `json:"transferred"` //grants transferred to the successor
+	Cascade     CascadeReport `json:"cascade"`     //what the cascade policy of tenants did to the grants
+	Type        string        `json:"Type"`        //ER for Erasure Receipt
+}
+
+//receivedGrants returns every SubDelegation the given tenant is the recipient of, from the grant index
+func (s *SmartContract) receivedGrants(ctx contractapi.TransactionContextInterface, recipient string) ([]*SubDelegation, error) {
+	indexed, err := s.indexedGrants(ctx, recipientIndex, recipient)
+	if err != nil {
+		return nil, err
+	}
+
+	var grants []*SubDelegation
+	for _, grant := range indexed {
+		//Delegations and SubDelegations share the same fields
+		if grant.Type == "SD" {
+			grants = append(grants, (*SubDelegation)(grant))
+		}
+	}
+
+	return grants, nil
+}
+
+//replaceInSlice returns a copy of list with every old replaced by new, without duplicating new
+func replaceInSlice(list []string, old string, new string) []string {
+	var result []string
+	for _, x := range list {
+		if x == old {
+			x = new
+		}
+		if !stringInSlice(x, result) {
+			result = append(result, x)
+		}
+	}
+	return result
+}
+
+//transferGrant moves a SubDelegation from its current recipient to a new one, the new recipient takes
+//the place of the old one in the Revokers of the grant and of every grant below it, and becomes the
+//Grandor of the direct children of the grant. The grants written are kept in written
+func (s *SmartContract) transferGrant(ctx contractapi.TransactionContextInterface, subdelegation *SubDelegation, recipient string, written map[string]*SubDelegation) error {
+	previous := subdelegation.Recipient
+
+	subdelegation.Recipient = recipient
+	subdelegation.Revokers = replaceInSlice(subdelegation.Revokers, previous, recipient)
+
+	subdelegationAsBytes, _ := json.Marshal(subdelegation)
+	if err := ctx.GetStub().PutState(subdelegation.Pck, subdelegationAsBytes); err != nil {
+		return err
+	}
+	written[subdelegation.Pck] = subdelegation
+	if err := s.reindexRecipient(ctx, subdelegation.Pck, previous, recipient); err != nil {
+		return err
+	}
+	if err := rewriteRevocationParties(ctx, subdelegation.Pck, previous, recipient); err != nil {
+		return err
+	}
+
+	return s.rewriteDescendants(ctx, subdelegation.Pck, previous, recipient, true, written)
+}
+
+//rewriteDescendants replaces previous with recipient in the grants below grant, direct is true for the
+//children of the transferred grant whose Grandor changes as well. written holds the grants already
+//written in the transaction, they are not read back from the world state
+func (s *SmartContract) rewriteDescendants(ctx contractapi.TransactionContextInterface, grant string, previous string, recipient string, direct bool, written map[string]*SubDelegation) error {
+	allocations, err := s.GetAllocations(ctx, grant)
+	if err != nil {
+		return err
+	}
+
+	for _, allocation := range allocations {
+		child, ok := written[allocation.Child]
+		if !ok {
+			if child, err = s.IsSubDelegation(ctx, allocation.Child); err != nil {
+				return err
+			}
+		}
+
+		if direct && child.Grandor == previous {
+			child.Grandor = recipient
+			if err := moveGrantIndex(ctx, grandorIndex, previous, recipient, child.Pck); err != nil {
+				return err
+			}
+		}
+		child.Revokers = replaceInSlice(child.Revokers, previous, recipient)
+
+		childAsBytes, _ := json.Marshal(child)
+		if err := ctx.GetStub().PutState(child.Pck, childAsBytes); err != nil {
+			return err
+		}
+		written[child.Pck] = child
+		if err := rewriteRevocationParties(ctx, child.Pck, previous, recipient); err != nil {
+			return err
+		}
+
+		if err := s.rewriteDescendants(ctx, child.Pck, previous, recipient, false, written); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//EraseTenant erases the personal data of the tenant with given Pck (Key). The cascade policy of tenants
+//applies to its grants first, then the grants the tenant still receives are transferred to successor, or
+//revoked when successor is empty
+func (s *SmartContract) EraseTenant(ctx contractapi.TransactionContextInterface, pck string, successor string) error {
+	//only a platform admin can erase a tenant
+	if err := requirePlatformAdmin(ctx); err != nil {
+		return err
+	}
+	if err := checkAccessPolicy(ctx, "EraseTenant", pck); err != nil {
+		return err
+	}
+
+	tenant, err := s.IsTenant(ctx, pck)
+	if err != nil {
+		return err
+	}
+	if tenant.Type != "T" {
+		return fmt.Errorf("%s is not a Tenant", pck)
+	}
+	if tenant.Name == erasedName {
+		return fmt.Errorf("%s has already been erased", pck)
+	}
+
+	//the successor must be able to receive the grants
+	if successor != "" {
+		if successor == pck {
+			return fmt.Errorf("A tenant cannot be its own successor")
+		}
+		next, err := s.IsTenant(ctx, successor)
+		if err != nil {
+			return err
+		}
+		if next.Type != "T" || next.Registered == false {
+			return fmt.Errorf("The successor %s must be a registered Tenant", successor)
+		}
+	}
+
+	mspid, err := clientMSPID(ctx)
+	if err != nil {
+		return err
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("Failed to read the transaction timestamp. %s", err.Error())
+	}
+
+	//the receipt holds nothing derived from the erased data, a hash of a name or phone number
+	//would let it be recovered by guessing
+	receipt := ErasureReceipt{
+		Tenant:      pck,
+		TxID:        ctx.GetStub().GetTxID(),
+		Timestamp:   timestamp.GetSeconds(),
+		RequestedBy: mspid,
+		Successor:   successor,
+		Revoked:     []string{},
+		Suspended:   []string{},
+		Transferred: []string{},
+		Type:        "ER",
+	}
+
+	//the erasure deregisters the tenant, its cascade policy decides first what happens to its grants
+	report, cascaded, err := s.cascadeDeregistration(ctx, pck, tenant.Type)
+	if err != nil {
+		return err
+	}
+	receipt.Cascade = *report
+	if report.Mode == cascadeRevoke {
+		receipt.Revoked = append(receipt.Revoked, report.Grants...)
+	} else {
+		receipt.Suspended = append(receipt.Suspended, report.Grants...)
+	}
+
+	//the writes of the transaction are not read back from the world state, the grants written so far
+	//are carried on from memory. Delegations and SubDelegations share the same fields
+	written := map[string]*SubDelegation{}
+	for _, grant := range cascaded {
+		written[grant.Pck] = (*SubDelegation)(grant)
+	}
+
+	//revoking or transferring every grant the tenant still receives
+	grants, err := s.receivedGrants(ctx, pck)
+	if err != nil {
+		return err
+	}
+	for _, grant := range grants {
+		if cached, ok := written[grant.Pck]; ok {
+			grant = cached
+		}
+		if grant.Revoked {
+			continue
+		}
+
+		if successor == "" || grant.Grandor == successor {
+			//a grant cannot be transferred to its own grandor, it is revoked instead
+			grant.Revoked = true
+			grantAsBytes, _ := json.Marshal(grant)
+			if err := ctx.GetStub().PutState(grant.Pck, grantAsBytes); err != nil {
+				return err
+			}
+			written[grant.Pck] = grant
+			receipt.Revoked = append(receipt.Revoked, grant.Pck)
+		} else {
+			if err := s.transferGrant(ctx, grant, successor, written); err != nil {
+				return err
+			}
+			receipt.Transferred = append(receipt.Transferred, grant.Pck)
+		}
+	}
+
+	//purging the private contact details
+	if err := ctx.GetStub().DelPrivateData(tenantContactCollection, pck); err != nil {
+		return fmt.Errorf("Failed to delete from the private data collection. %s", err.Error())
+	}
+
+	//the record stays at its key as a tombstone so the references to it keep working
+	tenant.Name = erasedName
+	tenant.ContactHash = ""
+	tenant.Registered = false
+
+	//the subject of the bound identity can name the person, the tenant stays bound to no identity
+	if tenant.Identity != "" {
+		tenant.Identity = erasedName
+	}
+
+	tenantAsBytes, _ := json.Marshal(tenant)
+	if err := ctx.GetStub().PutState(pck, tenantAsBytes); err != nil {
+		return err
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(erasureObjectType, []string{pck})
+	if err != nil {
+		return fmt.Errorf("Failed to create the erasure key. %s", err.Error())
+	}
+	receiptAsBytes, _ := json.Marshal(receipt)
+	if err := ctx.GetStub().PutState(key, receiptAsBytes); err != nil {
+		return err
+	}
+
+	return ctx.GetStub().SetEvent("TenantErased", receiptAsBytes)
+}
+
+//GetErasureReceipt returns the receipt of the erasure of the tenant with given Pck (Key)
+func (s *SmartContract) GetErasureReceipt(ctx contractapi.TransactionContextInterface, pck string) (*ErasureReceipt, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(erasureObjectType, []string{pck})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create the erasure key. %s", err.Error())
+	}
+
+	receiptAsBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if receiptAsBytes == nil {
+		return nil, fmt.Errorf("%s has not been erased", pck)
+	}
+
+	receipt := new(ErasureReceipt)
+	_ = json.Unmarshal(receiptAsBytes, receipt)
+
+	return receipt, nil
+}
+
+//--------------------------------------End Of Tenant Erasure----------------------------------------
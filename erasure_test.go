@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestErasureKeepsWhatTheCascadeDidToTheTransferredGrants(t *testing.T) {
+	l := initLedger(t)
+	now := time.Now()
+	l.grant("D1", "S1", "S2", nil)
+	l.ok("RegisterSubDelegation", l.RegisterSubDelegation("SD1", "D1", "T4", 2, now.Add(-2*time.Hour), now.Add(time.Hour), nil))
+	l.ok("AcceptGrant", l.AcceptGrant("SD1"))
+	l.subgrant("SD2", "SD1", "T6", 1, nil)
+
+	l.ok("SetCascadePolicy", l.SetCascadePolicy("T", "block"))
+	l.refused("an erasure the cascade policy blocks", l.EraseTenant("T4", "T5"))
+	l.ok("SetCascadePolicy", l.SetCascadePolicy("T", "suspend"))
+	l.ok("EraseTenant", l.EraseTenant("T4", "T5"))
+
+	receipt, err := l.GetErasureReceipt("T4")
+	l.ok("GetErasureReceipt", err)
+	if receipt.Cascade.Mode != "suspend" || len(receipt.Cascade.Grants) != 2 || len(receipt.Transferred) != 1 {
+		t.Fatalf("the receipt is %+v", receipt)
+	}
+
+	//SD1 moved to the successor and SD2 below it, whose grandor changed, kept their suspension
+	sd1, err := l.IsSubDelegation("SD1")
+	l.ok("IsSubDelegation", err)
+	sd2, err := l.IsSubDelegation("SD2")
+	l.ok("IsSubDelegation", err)
+	if !sd1.Suspended || sd1.Recipient != "T5" || !sd2.Suspended || sd2.Grandor != "T5" {
+		t.Fatalf("SD1 %+v, SD2 %+v", sd1, sd2)
+	}
+	tenant, err := l.IsTenant("T4")
+	l.ok("IsTenant", err)
+	if tenant.Name != "[erased]" || tenant.Registered {
+		t.Fatalf("T4 is %+v", tenant)
+	}
+}
@@ -5,8 +5,8 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"os"
 	"strconv"
-	"time"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 )
 
@@ -41,8 +41,9 @@ func stringInSlice(a string, list []string) bool {
 type Tenant struct {
 	Pck			string   `json:"pck"` 		
 	Name        string 	 `json:"name"`
-	Email       string 	 `json:"email"`
-	Phone       string   `json:"phone"`
+	ContactHash string   `json:"contacthash"`	//salted hash of the email and phone kept in the private data collection
+	Identity    string   `json:"identity"`	//client identity the tenant is bound to, empty if not bound
+	IdentityMSP string   `json:"identitymsp"`	//organization of that identity
 	Registered 	bool     `json:"registered"`	//false if not, true if Registered
 	Type        string   `json:"type"`		    //T for tenants
 }
@@ -53,6 +54,15 @@ type Service struct {
 	Pck					string  `json:"pck"`
 	Name				string  `json:"name"`
 	Registered			bool    `json:"registered"` //true if registered false if not 
+	Owner				string  `json:"owner"`        //pck of the tenant that owns the service
+	OwnerMSP			string  `json:"ownermsp"`     //organization of the owner, the only one allowed to manage the service
+	PendingOwner		string  `json:"pendingowner"` //tenant the ownership is being transferred to
+	CostPerHour			uint64  `json:"costperhour"`  //whole unit price of services priced before Price existed
+	Price				Money   `json:"price"`        //price per core and hour, see money.go
+	StoragePrice		Money   `json:"storageprice"` //price per GB and hour of the storage used, free when not set
+	Rounding			string  `json:"rounding"`     //rounding rule of the charges, the one of the currency when empty
+	Quota				Resources `json:"quota"`      //total resources the service can delegate, 0 is unlimited
+	ApprovalCores		uint64  `json:"approvalcores"` //delegations of more cores must be proposed and accepted, 0 never
 	Type 				string 	`json:"Type"`       //S for Services
 }
 
@@ -63,13 +73,16 @@ type Delegation struct {
 	Pck					string	 `json:"pck"`	               //	
 	Grandor				string   `json:"grandor"`              //			 
 	Recipient  			string 	 `json:"recipient"`			   //
-	Subdel       		uint8 	 `json:"subdel"`			   //
+	Subdel       		uint8 	 `json:"subdel"`			   //max depth of subdelegation, the budget is kept in the capacity ledger
 	Issue 				uint64   `json:"issue"`				   //
 	Expiry 				uint64   `json:"expiry"`			   //
 	Suspended			bool	 `json:"suspended"`			   //false if not, true if suspended
 	Revoked 			bool	 `json:"revoked"`			   //false if not, true if revoked
+	Pending				bool	 `json:"pending"`			   //true until the recipient accepts the delegation, see acceptance.go
+	Declined			bool	 `json:"declined"`			   //true if the recipient declined the delegation
 	Revokers 			[]string `json:"revokers"`      	   //list of tenants & services who can revoke the delegation
 	DelegationChain		[]string `json:"delegationchain"`	   //
+	Scope				Scope	 `json:"scope"`				   //what the delegation grants, see scope.go
 	Type 				string 	 `json:"Type"`				   //D is for Delegation	
 }
 
@@ -78,13 +91,16 @@ type SubDelegation struct {
 	Pck					string	 	`json:"pck"`	               //
 	Grandor				string   	`json:"grandor"`               //		 
 	Recipient  			string 	 	`json:"recipient"`			   //
-	Subdel       		uint8 	 	`json:"subdel"`			  	   //
+	Subdel       		uint8 	 	`json:"subdel"`			  	   //max depth of subdelegation, the budget is kept in the capacity ledger
 	Issue 				uint64   	`json:"issue"`				   //
 	Expiry 				uint64   	`json:"expiry"`				   //
 	Suspended			bool	 	`json:"suspended"`			   //false if not, true if suspended
 	Revoked 			bool	 	`json:"revoked"`			   //false if not, true if revoked
+	Pending				bool	 	`json:"pending"`			   //true until the recipient accepts the subdelegation, see acceptance.go
+	Declined			bool	 	`json:"declined"`			   //true if the recipient declined the subdelegation
 	Revokers 			[]string 	`json:"revokers"`      		   //list of tenants & services who can revoke the subdelegation
 	DelegationChain		[]string 	`json:"delegationchain"`       //
+	Scope				Scope		`json:"scope"`				   //subset of the scope of the previous delegation
 	Type 				string 		`json:"Type"`				   //SD is for SubDelegation 
 }
 
@@ -92,24 +108,60 @@ type SubDelegation struct {
 
 
 
-// InitLedger adds a base set of tenants and services to the ledger
-func (s *SmartContract) InitLedger(ctx contractapi.TransactionContextInterface) error {
+// InitLedger adds a base set of tenants and services to the ledger, the first call bootstraps the role
+// registry for the admin of the deploying organization adminmsp, see roles.go
+// the first call, made when the chaincode is instantiated, makes the caller the platform admin,
+// later calls need a platform admin and leave the tenants and services already stored untouched
+func (s *SmartContract) InitLedger(ctx contractapi.TransactionContextInterface, adminmsp string) error {
+	bootstrapped, err := bootstrapRoles(ctx, adminmsp)
+	if err != nil {
+		return err
+	}
+	if !bootstrapped {
+		if err := requirePlatformAdmin(ctx); err != nil {
+			return err
+		}
+	}
+
 	tenants := []Tenant{
-		Tenant{Pck: "T1", Name: "Tenant One",   Email: "t1@mail.com", Phone: "1111111111", Registered: true, Type: "T"},
-		Tenant{Pck: "T2", Name: "Tenant Two",   Email: "t2@mail.com", Phone: "2222222222", Registered: true, Type: "T"},
-		Tenant{Pck: "T3", Name: "Tenant Three", Email: "t3@mail.com", Phone: "3333333333", Registered: true, Type: "T"},
-		Tenant{Pck: "T4", Name: "Tenant Four",  Email: "t4@mail.com", Phone: "4444444444", Registered: true, Type: "T"},
-		Tenant{Pck: "T5", Name: "Tenant Five",  Email: "t5@mail.com", Phone: "5555555555", Registered: true, Type: "T"},
-		Tenant{Pck: "T6", Name: "Tenant Six",   Email: "t6@mail.com", Phone: "6666666666", Registered: true, Type: "T"},
-		Tenant{Pck: "T7", Name: "Tenant Seven", Email: "t7@mail.com", Phone: "7777777777", Registered: true, Type: "T"},
-		Tenant{Pck: "T8", Name: "Tenant Eight", Email: "t8@mail.com", Phone: "8888888888", Registered: true, Type: "T"},
+		Tenant{Pck: "T1", Name: "Tenant One",   Registered: true, Type: "T"},
+		Tenant{Pck: "T2", Name: "Tenant Two",   Registered: true, Type: "T"},
+		Tenant{Pck: "T3", Name: "Tenant Three", Registered: true, Type: "T"},
+		Tenant{Pck: "T4", Name: "Tenant Four",  Registered: true, Type: "T"},
+		Tenant{Pck: "T5", Name: "Tenant Five",  Registered: true, Type: "T"},
+		Tenant{Pck: "T6", Name: "Tenant Six",   Registered: true, Type: "T"},
+		Tenant{Pck: "T7", Name: "Tenant Seven", Registered: true, Type: "T"},
+		Tenant{Pck: "T8", Name: "Tenant Eight", Registered: true, Type: "T"},
+	}
+
+	//the contact details go to the private data collection, email first and phone second
+	contacts := map[string][2]string{
+		"T1": {"t1@mail.com", "1111111111"},
+		"T2": {"t2@mail.com", "2222222222"},
+		"T3": {"t3@mail.com", "3333333333"},
+		"T4": {"t4@mail.com", "4444444444"},
+		"T5": {"t5@mail.com", "5555555555"},
+		"T6": {"t6@mail.com", "6666666666"},
+		"T7": {"t7@mail.com", "7777777777"},
+		"T8": {"t8@mail.com", "8888888888"},
 	}
 
 
 	//We save the data to the world State based on their Pck
 	for _, tenant := range tenants {
+		if existing, _ := ctx.GetStub().GetState(tenant.Pck); existing != nil {
+			continue
+		}
+
+		contact := contacts[tenant.Pck]
+		contacthash, err := putTenantContact(ctx, tenant.Pck, contact[0], contact[1], sampleContactSalt(ctx, tenant.Pck))
+		if err != nil {
+			return err
+		}
+		tenant.ContactHash = contacthash
+
 		tenantAsBytes, _ := json.Marshal(tenant)
-		err := ctx.GetStub().PutState(tenant.Pck, tenantAsBytes)
+		err = ctx.GetStub().PutState(tenant.Pck, tenantAsBytes)
 
 		if err != nil {
 			return fmt.Errorf("Failed to put to world state. %s", err.Error())
@@ -117,14 +169,24 @@ func (s *SmartContract) InitLedger(ctx contractapi.TransactionContextInterface)
 	}
 	
 	services := []Service{
-		Service{Pck: "S1", Name: "Service One",   Registered: true, Type: "S"},
-		Service{Pck: "S2", Name: "Service Two",   Registered: true, Type: "S"},
-		Service{Pck: "S3", Name: "Service Three", Registered: true, Type: "S"},
+		Service{Pck: "S1", Name: "Service One",   Registered: true, Owner: "T1", Price: Money{Amount: defaultCostPerHour * moneyScale, Currency: defaultCurrency}, Type: "S"},
+		Service{Pck: "S2", Name: "Service Two",   Registered: true, Owner: "T2", Price: Money{Amount: defaultCostPerHour * moneyScale, Currency: defaultCurrency}, Type: "S"},
+		Service{Pck: "S3", Name: "Service Three", Registered: true, Owner: "T3", Price: Money{Amount: defaultCostPerHour * moneyScale, Currency: defaultCurrency}, Type: "S"},
 	}
 
+	//the organization that initializes the ledger owns the base services
+	mspid, err := clientMSPID(ctx)
+	if err != nil {
+		return err
+	}
 
 	//We save the data to the world State based on their Pck
 	for _, service := range services {
+		if existing, _ := ctx.GetStub().GetState(service.Pck); existing != nil {
+			continue
+		}
+
+		service.OwnerMSP = mspid
 		serviceAsBytes, _ := json.Marshal(service)
 		err := ctx.GetStub().PutState(service.Pck, serviceAsBytes)
 
@@ -153,12 +215,45 @@ func (s *SmartContract) InitLedger(ctx contractapi.TransactionContextInterface)
 
 
 //RegisterSubDelegation adds a new SubDelegation to the world state with given details
-func (s *SmartContract) RegisterSubDelegation(ctx contractapi.TransactionContextInterface, pck string, exdelegation string, recipient string, subdel string, issue string, expiry string) error {
+func (s *SmartContract) RegisterSubDelegation(ctx contractapi.TransactionContextInterface, pck string, exdelegation string, recipient string, subdel string, issue string, expiry string, scope string) error {
+	//a subdelegation never replaces another record
+	if err := unusedPck(ctx, pck); err != nil {
+		return err
+	}
+
 	//getting the previous in chain delegation info 
 	delegation, err := s.IsDelegation(ctx, exdelegation)
 	if err != nil {
 		return err
 	}
+
+	//the recipient of the previous delegation is the grandor, only the owning organization of a service
+	//or the identity of a tenant bound to one subdelegates through it
+	if err := s.authorizeParty(ctx, delegation.Recipient); err != nil {
+		return err
+	}
+	if err := checkAccessPolicy(ctx, "RegisterSubDelegation", delegation.Recipient); err != nil {
+		return err
+	}
+
+	//the scope of the subdelegation must be a subset of the scope of the previous delegation
+	requested, err := parseScope(scope)
+	if err != nil {
+		return err
+	}
+	subscope, err := narrowScope(delegation.Scope, requested)
+	if err != nil {
+		return err
+	}
+
+	//and its resources must fit in what the previous delegation has not subdelegated yet
+	quota, err := s.GetQuota(ctx, exdelegation)
+	if err != nil {
+		return err
+	}
+	if err := quota.fits(scopeResources(subscope)); err != nil {
+		return err
+	}
 	
 	//turning string to uint8
 	tempsubdel, err := strconv.ParseUint(subdel, 10, 8) 
@@ -179,13 +274,17 @@ func (s *SmartContract) RegisterSubDelegation(ctx contractapi.TransactionContext
 		}
 	
 	
-	//checking if the recipient is a Tenant, Services are NOT allowed to be sudelegated
+	//checking the recipient against the grant policy, the grandor is the recipient of the previous delegation
 	tenant, err := s.IsTenant(ctx, recipient)
 	if err != nil {
 		return err
 	}
-	if tenant.Type != "T"{
-		return fmt.Errorf("Recipient of a SubDelegation must be a Tenant")
+	grandor, err := s.IsTenant(ctx, delegation.Recipient)
+	if err != nil {
+		return err
+	}
+	if err := checkGrantRule(ctx, "SD", grandor.Type, tenant.Type); err != nil {
+		return err
 	}
 	
 	if tenant.Registered == false {
@@ -197,10 +296,20 @@ func (s *SmartContract) RegisterSubDelegation(ctx contractapi.TransactionContext
 		return fmt.Errorf("Cannot Self-SubDelegate")
 	}
 
-	//checking the subdel 
-	if delegation.Subdel - 1 < subdel1 {
+	//checking the subdel against the capacity of the previous delegation
+	capacity, err := s.GetCapacity(ctx, exdelegation)
+	if err != nil {
+		return err
+	}
+	if capacity.MaxDepth == 0 {
+		return fmt.Errorf("%s cannot be subdelegated any further", exdelegation)
+	}
+	if capacity.MaxDepth - 1 < subdel1 {
 		return fmt.Errorf("Subdel must be smaller")
 	}
+	if capacity.Available == 0 {
+		return fmt.Errorf("%s has no available capacity for another SubDelegation", exdelegation)
+	}
 	
 	//checking issue parameter, we accept equals
 	if delegation.Issue > issue1 {
@@ -225,11 +334,20 @@ func (s *SmartContract) RegisterSubDelegation(ctx contractapi.TransactionContext
 	if delegation.Revoked == true  {
 		return fmt.Errorf("Cannot create SubDelegation because Delegation has been Revoked")
 	}
+
+	//checking if the recipient of the previous delegation has accepted it
+	if delegation.Pending == true || delegation.Declined == true {
+		return fmt.Errorf("Cannot create SubDelegation because Delegation has not been accepted")
+	}
 	
 	
 	//checking if it is already expired upon creation
 	expiryint64, _ := strconv.ParseUint(expiry, 10, 64)
-	timenow := uint64(time.Now().Unix())
+	timestamp, err := txSeconds(ctx)
+	if err != nil {
+		return err
+	}
+	timenow := uint64(timestamp)
 	if timenow > expiryint64 {
 		return fmt.Errorf("Cannot create a SubDelegation which is already Expired")
 	}
@@ -248,19 +366,32 @@ func (s *SmartContract) RegisterSubDelegation(ctx contractapi.TransactionContext
 	}
 	
 	
-	//updating the subdel field on the previous delegation 
-	if delegation.Type == "D" {
-		delegation.Subdel = delegation.Subdel - 1 - subdel1
-		delegationAsBytes, _ := json.Marshal(delegation)
-		ctx.GetStub().PutState(delegation.Pck, delegationAsBytes)
-	} else {
-		tempdelegation, err := s.IsSubDelegation(ctx, exdelegation)
-		if err != nil {
-			return err
-		}
-		tempdelegation.Subdel = tempdelegation.Subdel - 1 - subdel1
-		tempdelegationAsBytes, _ := json.Marshal(tempdelegation)
-		ctx.GetStub().PutState(tempdelegation.Pck, tempdelegationAsBytes)
+	//the recipient pays for the subdelegation at the price of the service at the root of the chain
+	service, err := s.rootService(ctx, delegation)
+	if err != nil {
+		return err
+	}
+	price, err := s.lockQuote(ctx, pck, exdelegation, subscope, issue1, expiry1, service)
+	if err != nil {
+		return err
+	}
+	payer, err := s.payingTenant(ctx, recipient)
+	if err != nil {
+		return err
+	}
+	if err := s.admitGrant(ctx, pck, payer, price, subscope, issue1, expiry1); err != nil {
+		return err
+	}
+
+	//recording the allocation of the new subdelegation on the previous delegation
+	err = recordAllocation(ctx, exdelegation, pck, subdel1)
+	if err != nil {
+		return err
+	}
+
+	err = putCapacity(ctx, newCapacity(pck, subdel1))
+	if err != nil {
+		return err
 	}
 	
 	//creating the Revokers list 
@@ -282,14 +413,22 @@ func (s *SmartContract) RegisterSubDelegation(ctx contractapi.TransactionContext
 		Expiry: 			expiry1,   
 		Suspended:			false,
 		Revoked:			false,
+		Pending:			true,
 		Revokers: 			finalrevokers, 
 		DelegationChain:	tempdelegationchain,
+		Scope:				subscope,
 		Type: 				"SD",
 	}
 
 	subdelegationAsBytes, _ := json.Marshal(subdelegation)
 
-	return ctx.GetStub().PutState(pck, subdelegationAsBytes)
+	err = ctx.GetStub().PutState(pck, subdelegationAsBytes)
+	if err != nil {
+		return err
+	}
+
+	//indexing the subdelegation under its grandor, recipient and payer, see grantindex.go
+	return s.indexGrant(ctx, pck, subdelegation.Grandor, recipient)
 }
 
 
@@ -302,34 +441,54 @@ func (s *SmartContract) SuspendSubDelegation(ctx contractapi.TransactionContextI
 		return err
 	}
 
+	//only the grandor, billing or a platform admin can suspend it
+	if err := s.authorizeSuspension(ctx, subdelegation.Grandor); err != nil {
+		return err
+	}
+	if err := checkAccessPolicy(ctx, "SuspendSubDelegation", subdelegation.Grandor); err != nil {
+		return err
+	}
+
+	//a suspension by hand outlasts the dunning of the tenant, see dunning.go
+	if err := clearDunned(ctx, pck); err != nil {
+		return err
+	}
+
 	//we update the Suspended field 
 	subdelegation.Suspended = true
 	
-	//we update the subdel field to the privious delegation 
-	previousdelegation := subdelegation.DelegationChain[len(subdelegation.DelegationChain)-2]
-	temppreviousdelegation, err := s.IsDelegation(ctx, previousdelegation)
-		if err != nil {
-			return err
-		}
-	
-	
-	//updating the subdel field on the previous delegation 
-	if temppreviousdelegation.Type == "D" {
-		newsubdel:=temppreviousdelegation.Subdel + 1 + subdelegation.Subdel
-		temppreviousdelegation.Subdel = newsubdel 
-		temppreviousdelegationAsBytes, _ := json.Marshal(temppreviousdelegation)
-		ctx.GetStub().PutState(temppreviousdelegation.Pck, temppreviousdelegationAsBytes)
-	} else {
-		tempdelegation, err := s.IsSubDelegation(ctx, previousdelegation)
-		if err != nil {
-			return err
-		}
-		tempdelegation.Subdel = tempdelegation.Subdel + 1 + subdelegation.Subdel
-		tempdelegationAsBytes, _ := json.Marshal(tempdelegation)
-		ctx.GetStub().PutState(tempdelegation.Pck, tempdelegationAsBytes)
+	//we store back to the world state
+	subdelegationAsBytes, _ := json.Marshal(subdelegation)
+
+	return ctx.GetStub().PutState(pck, subdelegationAsBytes)
+}
+
+
+//function to update the world state with the Suspended status back to false, when a suspended subdelegation gives access again
+func (s *SmartContract) ResumeSubDelegation(ctx contractapi.TransactionContextInterface, pck string) error {
+	//we pull from the world state the data for the subdelegation
+	subdelegation, err := s.IsDelegation(ctx, pck)
+
+	if err != nil {
+		return err
 	}
-	
-	
+	if subdelegation.Type != "SD" {
+		return fmt.Errorf("%s is not a SubDelegation", pck)
+	}
+
+	if err := s.authorizeSuspension(ctx, subdelegation.Grandor); err != nil {
+		return err
+	}
+	if err := checkAccessPolicy(ctx, "ResumeSubDelegation", subdelegation.Grandor); err != nil {
+		return err
+	}
+	if err := s.resumable(ctx, subdelegation); err != nil {
+		return err
+	}
+
+	//we update the Suspended field 
+	subdelegation.Suspended = false
+
 	//we store back to the world state
 	subdelegationAsBytes, _ := json.Marshal(subdelegation)
 
@@ -346,38 +505,22 @@ func (s *SmartContract) RevokeSubDelegation(ctx contractapi.TransactionContextIn
 		return err
 	}
 
-	if stringInSlice(revoker, subdelegation.Revokers){
-			//we update the Revoked field 
-			subdelegation.Revoked = true
-			} else {
-				return fmt.Errorf("%s is not an authorized Revoker", revoker)
-		}
-		
-	//we update the subdel field to the privious delegation 
-	previousdelegation := subdelegation.DelegationChain[len(subdelegation.DelegationChain)-2]
-	temppreviousdelegation, err := s.IsDelegation(ctx, previousdelegation)
-		if err != nil {
-			return err
-		}
-	
-	
-	//updating the subdel field on the previous delegation 
-	if temppreviousdelegation.Type == "D" {
-		temppreviousdelegation.Subdel = temppreviousdelegation.Subdel + 1 + subdelegation.Subdel
-		temppreviousdelegationAsBytes, _ := json.Marshal(temppreviousdelegation)
-		ctx.GetStub().PutState(temppreviousdelegation.Pck, temppreviousdelegationAsBytes)
-	} else {
-		tempdelegation, err := s.IsSubDelegation(ctx, previousdelegation)
-		if err != nil {
-			return err
-		}
-		tempdelegation.Subdel = tempdelegation.Subdel + 1 + subdelegation.Subdel
-		tempdelegationAsBytes, _ := json.Marshal(tempdelegation)
-		ctx.GetStub().PutState(tempdelegation.Pck, tempdelegationAsBytes)
+	if err := checkAccessPolicy(ctx, "RevokeSubDelegation", revoker); err != nil {
+		return err
+	}
+
+	//the revocation policy of the subdelegation decides if this revoker is enough
+	revoked, err := s.signRevocation(ctx, pck, revoker)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return nil
 	}
-		
-		
 
+	//we update the Revoked field 
+	subdelegation.Revoked = true
+		
 	//we store back to the world state
 	subdelegationAsBytes, _ := json.Marshal(subdelegation)
 
@@ -424,8 +567,12 @@ func (s *SmartContract) IsSubValid(ctx contractapi.TransactionContextInterface,
 	//we pull from the world state the data for the delegation
 	subdelegation, _ := s.IsSubDelegation(ctx, pck)
 
-	//we pull the current system time to check if it surpasses the Expired field of the delegation
-	timenow := uint64(time.Now().Unix())
+	//we take the time of the transaction, the same on every endorser, to check if it surpasses the Expired field of the delegation
+	timestamp, err := txSeconds(ctx)
+	if err != nil {
+		return false
+	}
+	timenow := uint64(timestamp)
 
 	if subdelegation.Expiry > timenow && subdelegation.Issue < timenow {
 		//checking if a previous delegation has been revoked or suspended
@@ -435,9 +582,17 @@ func (s *SmartContract) IsSubValid(ctx contractapi.TransactionContextInterface,
 				return false
 			} else if temp.Revoked == true {
 				return false
+			} else if temp.Pending == true || temp.Declined == true {
+				return false
 			}
 		}
-		return true 
+		//every principal of the chain must still be registered
+		if registered, _ := s.principalsRegistered(ctx, subdelegation.DelegationChain); !registered {
+			return false
+		}
+		//a scheduled subdelegation is only valid inside the windows of every schedule of its chain
+		active, _ := scheduleActive(ctx, pck, subdelegation.DelegationChain, timenow)
+		return active
 	}	else {
 		return false 
 	}
@@ -448,8 +603,12 @@ func (s *SmartContract) IsSubExpired(ctx contractapi.TransactionContextInterface
 	//we pull from the world state the data for the delegation
 	subdelegation, _ := s.IsSubDelegation(ctx, pck)
 
-	//we pull the current system time to check if it surpasses the Expired field of the delegation
-	timenow := uint64(time.Now().Unix())
+	//we take the time of the transaction, the same on every endorser, to check if it surpasses the Expired field of the delegation
+	timestamp, err := txSeconds(ctx)
+	if err != nil {
+		return false, err
+	}
+	timenow := uint64(timestamp)
 
 	//we check if this delegation is Expired and we return true or false 
 	if subdelegation.Expiry > timenow {
@@ -459,26 +618,6 @@ func (s *SmartContract) IsSubExpired(ctx contractapi.TransactionContextInterface
 			//we update the Suspended field 
 			subdelegation.Suspended = true
 	
-			//we update the subdel field to the privious delegation 
-			previousdelegation := subdelegation.DelegationChain[len(subdelegation.DelegationChain)-2]
-			temppreviousdelegation, _ := s.IsDelegation(ctx, previousdelegation)
-		
-	
-	
-			//updating the subdel field on the previous delegation 
-			if temppreviousdelegation.Type == "D" {
-				newsubdel:=temppreviousdelegation.Subdel + 1 + subdelegation.Subdel
-				temppreviousdelegation.Subdel = newsubdel 
-				temppreviousdelegationAsBytes, _ := json.Marshal(temppreviousdelegation)
-				ctx.GetStub().PutState(temppreviousdelegation.Pck, temppreviousdelegationAsBytes)
-			} else {
-				tempdelegation, _ := s.IsSubDelegation(ctx, previousdelegation)
-				tempdelegation.Subdel = tempdelegation.Subdel + 1 + subdelegation.Subdel
-				tempdelegationAsBytes, _ := json.Marshal(tempdelegation)
-				ctx.GetStub().PutState(tempdelegation.Pck, tempdelegationAsBytes)
-			}
-	
-	
 			//we store back to the world state
 			subdelegationAsBytes, _ := json.Marshal(subdelegation)
 			ctx.GetStub().PutState(pck, subdelegationAsBytes)
@@ -528,8 +667,39 @@ func (s *SmartContract)IsSubDelegation(ctx contractapi.TransactionContextInterfa
 //in this section there are the basic functions to manage Delegations  
 
 //RegisterDelegation adds a new Delegation to the world state with given details
-func (s *SmartContract) RegisterDelegation(ctx contractapi.TransactionContextInterface, pck string, grandor string, recipient string, subdel string, issue string, expiry string) error {
-	
+func (s *SmartContract) RegisterDelegation(ctx contractapi.TransactionContextInterface, pck string, grandor string, recipient string, subdel string, issue string, expiry string, scope string) error {
+
+	service, err := s.IsService(ctx, grandor)
+	if err != nil {
+		return err
+	}
+
+	//only the owner of the service can grant delegations from it
+	if err := authorizeServiceOwner(ctx, service); err != nil {
+		return err
+	}
+	if err := checkAccessPolicy(ctx, "RegisterDelegation", grandor); err != nil {
+		return err
+	}
+
+	//large delegations must be accepted by the recipient, see proposal.go
+	if err := requireApproval(service, scope); err != nil {
+		return err
+	}
+
+	//the recipient still has to accept it
+	return s.createDelegation(ctx, pck, grandor, recipient, subdel, issue, expiry, scope, true)
+}
+
+
+//createDelegation checks and stores a Delegation once the organization of the grandor has authorized it,
+//pending is false when the recipient has already agreed to it
+func (s *SmartContract) createDelegation(ctx contractapi.TransactionContextInterface, pck string, grandor string, recipient string, subdel string, issue string, expiry string, scope string, pending bool) error {
+	//a delegation never replaces another record
+	if err := unusedPck(ctx, pck); err != nil {
+		return err
+	}
+
 	//getting the service data from the world state 
 	service, err := s.IsService(ctx, grandor)
 	
@@ -546,13 +716,27 @@ func (s *SmartContract) RegisterDelegation(ctx contractapi.TransactionContextInt
 	if grandor == recipient {
 		return fmt.Errorf("Cannot Self-Delegate")
 	}
+
+	//the grant policy decides which entity types a service can delegate to
+	if err := checkGrantRule(ctx, "D", service.Type, recipientcheck.Type); err != nil {
+		return err
+	}
+
+	delegationscope, err := parseScope(scope)
+	if err != nil {
+		return err
+	}
 	
 	//if issue > expiry {
 	//	return fmt.Errorf("Delegation Issue and Expiry times should be checked again")
 	//}
 	
 	expiryint64, _ := strconv.ParseUint(expiry, 10, 64)
-	timenow := uint64(time.Now().Unix())
+	timestamp, err := txSeconds(ctx)
+	if err != nil {
+		return err
+	}
+	timenow := uint64(timestamp)
 	if timenow > expiryint64 {
 		return fmt.Errorf("Cannot create a Delegation which is already Expired")
 	}
@@ -586,6 +770,30 @@ func (s *SmartContract) RegisterDelegation(ctx contractapi.TransactionContextInt
 		return fmt.Errorf("Delegation Issue and Expiry times should be checked again, Issue cannot be after the expiry")
 	}
 
+	//the delegation must fit in what the service has not delegated yet
+	quota, err := s.GetQuota(ctx, grandor)
+	if err != nil {
+		return err
+	}
+	if err := quota.fits(scopeResources(delegationscope)); err != nil {
+		return err
+	}
+
+	//a quote issued for the delegation fixes its price
+	price, err := s.lockQuote(ctx, pck, grandor, delegationscope, issue1, expiry1, service)
+	if err != nil {
+		return err
+	}
+
+	//the owner of the recipient service pays for the delegation and must be able to afford it
+	payer, err := s.payingTenant(ctx, recipient)
+	if err != nil {
+		return err
+	}
+	if err := s.admitGrant(ctx, pck, payer, price, delegationscope, issue1, expiry1); err != nil {
+		return err
+	}
+
 	var finalrevokers []string
 	finalrevokers = append(finalrevokers,grandor)
 	finalrevokers = append(finalrevokers,recipient)
@@ -603,14 +811,33 @@ func (s *SmartContract) RegisterDelegation(ctx contractapi.TransactionContextInt
 		Expiry: 			expiry1,   
 		Suspended:			false,
 		Revoked:			false,
+		Pending:			pending,
 		Revokers: 			finalrevokers,
 		DelegationChain:	tempdelegationchain,
+		Scope:				delegationscope,
 		Type:				"D",
 	}
 
 	delegationAsBytes, _ := json.Marshal(delegation)
 
-	return ctx.GetStub().PutState(pck, delegationAsBytes)
+	err = ctx.GetStub().PutState(pck, delegationAsBytes)
+	if err != nil {
+		return err
+	}
+
+	err = recordServiceGrant(ctx, grandor, pck)
+	if err != nil {
+		return err
+	}
+
+	//indexing the delegation under its grandor, recipient and payer, see grantindex.go
+	err = s.indexGrant(ctx, pck, grandor, recipient)
+	if err != nil {
+		return err
+	}
+
+	//every delegation starts with the capacity given by its subdel
+	return putCapacity(ctx, newCapacity(pck, subdel1))
 	
 	} else {
 		return fmt.Errorf("Grandor Or Reciepient Error")
@@ -628,6 +855,19 @@ func (s *SmartContract) SuspendDelegation(ctx contractapi.TransactionContextInte
 		return err
 	}
 
+	//only the grandor, billing or a platform admin can suspend it
+	if err := s.authorizeSuspension(ctx, delegation.Grandor); err != nil {
+		return err
+	}
+	if err := checkAccessPolicy(ctx, "SuspendDelegation", delegation.Grandor); err != nil {
+		return err
+	}
+
+	//a suspension by hand outlasts the dunning of the tenant, see dunning.go
+	if err := clearDunned(ctx, pck); err != nil {
+		return err
+	}
+
 	//we update the Suspended field 
 	delegation.Suspended = true
 
@@ -638,6 +878,38 @@ func (s *SmartContract) SuspendDelegation(ctx contractapi.TransactionContextInte
 }
 
 
+//function to update the world state with the Suspended status back to false, when a suspended delegation gives access again
+func (s *SmartContract) ResumeDelegation(ctx contractapi.TransactionContextInterface, pck string) error {
+	//we pull from the world state the data for the delegation
+	delegation, err := s.IsDelegation(ctx, pck)
+
+	if err != nil {
+		return err
+	}
+	if delegation.Type != "D" {
+		return fmt.Errorf("%s is not a Delegation", pck)
+	}
+
+	if err := s.authorizeSuspension(ctx, delegation.Grandor); err != nil {
+		return err
+	}
+	if err := checkAccessPolicy(ctx, "ResumeDelegation", delegation.Grandor); err != nil {
+		return err
+	}
+	if err := s.resumable(ctx, delegation); err != nil {
+		return err
+	}
+
+	//we update the Suspended field 
+	delegation.Suspended = false
+
+	//we store back to the world state
+	delegationAsBytes, _ := json.Marshal(delegation)
+
+	return ctx.GetStub().PutState(pck, delegationAsBytes)
+}
+
+
 //function to update the world state with the new Revoke status, true when the delegation has been revoked
 func (s *SmartContract) RevokeDelegation(ctx contractapi.TransactionContextInterface, pck string, revoker string ) error {
 	//we pull from the world state the data for the delegation
@@ -647,12 +919,21 @@ func (s *SmartContract) RevokeDelegation(ctx contractapi.TransactionContextInter
 		return err
 	}
 
-	if stringInSlice(revoker, delegation.Revokers){
-			//we update the Revoked field 
-			delegation.Revoked = true
-			} else {
-				return fmt.Errorf("%s is not an authorized Revoker", revoker)
-		}
+	if err := checkAccessPolicy(ctx, "RevokeDelegation", revoker); err != nil {
+		return err
+	}
+
+	//the revocation policy of the delegation decides if this revoker is enough
+	revoked, err := s.signRevocation(ctx, pck, revoker)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return nil
+	}
+
+	//we update the Revoked field 
+	delegation.Revoked = true
 
 	//we store back to the world state
 	delegationAsBytes, _ := json.Marshal(delegation)
@@ -694,12 +975,21 @@ func (s *SmartContract) IsValid(ctx contractapi.TransactionContextInterface, pck
 	//we pull from the world state the data for the delegation
 	delegation, _ := s.IsDelegation(ctx, pck)
 
-	//we pull the current system time to check if it surpasses the Expired field of the delegation
-	timenow := uint64(time.Now().Unix())
+	//we take the time of the transaction, the same on every endorser, to check if it surpasses the Expired field of the delegation
+	timestamp, err := txSeconds(ctx)
+	if err != nil {
+		return false, err
+	}
+	timenow := uint64(timestamp)
 
 	//we check if this delegation is Valid and we return true or false 
-	if delegation.Expiry > timenow && delegation.Issue < timenow && delegation.Suspended == false && delegation.Revoked == false{
-		return true, nil 
+	if delegation.Expiry > timenow && delegation.Issue < timenow && delegation.Suspended == false && delegation.Revoked == false && delegation.Pending == false && delegation.Declined == false{
+		//its grandor and recipient must still be registered
+		if registered, err := s.principalsRegistered(ctx, delegation.DelegationChain); !registered {
+			return false, err
+		}
+		//a scheduled delegation is only valid inside one of its windows
+		return scheduleActive(ctx, pck, delegation.DelegationChain, timenow)
 	} else {
 		return false, nil 
 	}
@@ -711,8 +1001,12 @@ func (s *SmartContract) IsExpired(ctx contractapi.TransactionContextInterface, p
 	//we pull from the world state the data for the delegation
 	delegation, _ := s.IsDelegation(ctx, pck)
 
-	//we pull the current system time to check if it surpasses the Expired field of the delegation
-	timenow := uint64(time.Now().Unix())
+	//we take the time of the transaction, the same on every endorser, to check if it surpasses the Expired field of the delegation
+	timestamp, err := txSeconds(ctx)
+	if err != nil {
+		return false, err
+	}
+	timenow := uint64(timestamp)
 
 	//we check if this delegation is Expired and we return true or false 
 	if delegation.Expiry > timenow {
@@ -723,43 +1017,76 @@ func (s *SmartContract) IsExpired(ctx contractapi.TransactionContextInterface, p
 }
 
 
-//function to charge the Delegations
-func (s *SmartContract) ChargingDel(ctx contractapi.TransactionContextInterface, pck string, ncores string ) (uint64, error) {
+//function to charge the Delegations, the cost is computed from the usage the service has recorded and
+//is returned in the currency of the service, rounded with its rounding rule
+func (s *SmartContract) ChargingDel(ctx contractapi.TransactionContextInterface, pck string) (*Money, error) {
 	//we pull from the world state the data for the subdelegation
 	delegation, err:= s.IsDelegation(ctx, pck)
 	if err != nil {
-		return 0, fmt.Errorf("There is no such Delegation")
+		return nil, fmt.Errorf("There is no such Delegation")
 	}
 
-	var chargetime  uint64
-	var hours	    uint64
-	var costperhour uint64
-	var singlecost  uint64
-	var totalcost   uint64
+	//a grant the recipient never agreed to is not charged
+	if delegation.Pending == true || delegation.Declined == true {
+		return nil, fmt.Errorf("%s has not been accepted by its recipient", pck)
+	}
 
-	//turning string to uint64 for issue 
-	ncores1, _ := strconv.ParseUint(ncores, 10, 64)
+	//the price is set by the service at the root of the delegation chain
+	service, err := s.rootService(ctx, delegation)
+	if err != nil {
+		return nil, err
+	}
 
-	//we pull the current system time to check if it surpasses the Expired field of the delegation and if yes the delegation has started to charge 
-	timenow := uint64(time.Now().Unix())
+	samples, err := s.GetUsage(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
 
-	if delegation.Issue > timenow {
-		return 0, nil
-	} else if delegation.Issue < timenow && delegation.Expiry > timenow {
-		chargetime = timenow - delegation.Issue
-		hours = chargetime/3600
-		costperhour = 2
-		singlecost = hours * costperhour
-		totalcost = singlecost * ncores1
-		return totalcost, nil
-	} else {
-		chargetime = delegation.Expiry - delegation.Issue
-		hours = chargetime/3600
-		costperhour = 2
-		singlecost = hours * costperhour
-		totalcost = singlecost * ncores1
-		return totalcost, nil
+	//a grant created with a quote keeps the quoted price
+	costperhour, err := s.grantPrice(ctx, pck, service)
+	if err != nil {
+		return nil, err
+	}
+	totalcost := Money{Currency: costperhour.Currency}
+
+	//a scheduled grant is only charged for the hours that fall in its windows
+	schedules, err := chainSchedules(ctx, pck, delegation.DelegationChain)
+	if err != nil {
+		return nil, err
 	}
+
+	//the storage is charged at the storage price of the service in the currency of the charge
+	storageprice, err := s.storagePrice(ctx, service, totalcost.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	//every sample is charged for its cores and storage over its hours, the total is only rounded at the end
+	for _, sample := range samples {
+		hours := activeHours(schedules, sample.Timestamp, sample.Hours)
+		samplecost, err := costperhour.times(sample.Cores, hours)
+		if err != nil {
+			return nil, err
+		}
+		storagecost, err := storageprice.times(sample.Storage, hours)
+		if err != nil {
+			return nil, err
+		}
+		total, err := totalcost.add(samplecost)
+		if err != nil {
+			return nil, err
+		}
+		if totalcost, err = total.add(storagecost); err != nil {
+			return nil, err
+		}
+	}
+
+	totalcost, err = s.roundCharge(ctx, totalcost, service.Rounding)
+	if err != nil {
+		return nil, err
+	}
+
+	return &totalcost, nil
 }
 
 
@@ -800,12 +1127,33 @@ func (s *SmartContract)IsDelegation(ctx contractapi.TransactionContextInterface,
 //in this section there are the basic functions to manage Services 
 
 //Register_Service creates a service and adds its info and the tenant owner of it in the world state
-func (s *SmartContract) Register_Service(ctx contractapi.TransactionContextInterface, pck string, name string ) error {
+func (s *SmartContract) Register_Service(ctx contractapi.TransactionContextInterface, pck string, name string, owner string ) error {
+	//an existing service, tenant or grant is never overwritten
+	if err := unusedPck(ctx, pck); err != nil {
+		return err
+	}
+
+	//the owner must be a registered tenant the caller acts for and the submitting organization becomes the owning one
+	if err := s.registeredTenant(ctx, owner); err != nil {
+		return err
+	}
+	if err := s.authorizeTenant(ctx, owner); err != nil {
+		return err
+	}
+
+	mspid, err := clientMSPID(ctx)
+	if err != nil {
+		return err
+	}
+
 	//matching the data given with the service fields 
 	service := Service{
 		Pck: 	    		  pck,
 		Name: 	   			  name,
 		Registered:			  true,
+		Owner:				  owner,
+		OwnerMSP:			  mspid,
+		Price:				  Money{Amount: defaultCostPerHour * moneyScale, Currency: defaultCurrency},
 		Type:				  "S",
 	}
 
@@ -825,13 +1173,30 @@ func (s *SmartContract) UnRegister_Service(ctx contractapi.TransactionContextInt
 		return err
 	}
 
+	//only the owner can unregister the service, through one of its admins
+	if err := authorizeServiceOwner(ctx, service); err != nil {
+		return err
+	}
+	if err := requireAdmin(ctx, service.OwnerMSP); err != nil {
+		return err
+	}
+
+	//the cascade policy decides what happens to the grants of the service
+	report, _, err := s.cascadeDeregistration(ctx, pck, service.Type)
+	if err != nil {
+		return err
+	}
+
 	//updating the Registered field of the service 
 	service.Registered = false
 
 	//storing the updated data back to the world state 
 	serviceAsBytes, _ := json.Marshal(service)
+	if err := ctx.GetStub().PutState(pck, serviceAsBytes); err != nil {
+		return err
+	}
 
-	return ctx.GetStub().PutState(pck, serviceAsBytes)
+	return deregistered(ctx, report)
 }
 
 
@@ -873,37 +1238,114 @@ func (s *SmartContract)IsService(ctx contractapi.TransactionContextInterface, pc
 //in this section there are the basic function to manage (Enroll, Update, Destroy, and Search) a Tenant 
 
 
-//Enroll adds a new tenant to the world state with given details
-func (s *SmartContract) Enroll(ctx contractapi.TransactionContextInterface, pck string, name string, email string, phone string) error {
+//Enroll adds a new tenant to the world state with given details, the email and phone are passed
+//in the transient map under the contact key and are stored in the private data collection. A client
+//whose certificate has the tenant.id attribute can only enroll that tenant and binds a new tenant to
+//itself. Enrolling a registered tenant again updates it and keeps its binding, a deregistered tenant
+//cannot be enrolled again
+func (s *SmartContract) Enroll(ctx contractapi.TransactionContextInterface, pck string, name string) error {
+	existingAsBytes, err := ctx.GetStub().GetState(pck)
+	if err != nil {
+		return fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	var existing *Tenant
+	if existingAsBytes != nil {
+		existing = new(Tenant)
+		_ = json.Unmarshal(existingAsBytes, existing)
+		if existing.Type != "T" {
+			return fmt.Errorf("%s already exists and is not a Tenant", pck)
+		}
+		if !existing.Registered {
+			return fmt.Errorf("%s has been deregistered and cannot be enrolled again", pck)
+		}
+		//a tenant that is already bound can only be enrolled again by its own identity
+		if err := s.authorizeTenant(ctx, pck); err != nil {
+			return err
+		}
+	}
+	if err := checkAccessPolicy(ctx, "Enroll", pck); err != nil {
+		return err
+	}
+
+	identity, identitymsp, err := bindingIdentity(ctx, pck)
+	if err != nil {
+		return err
+	}
+	//only a new tenant is bound, an existing one keeps its binding or stays unbound as any certificate
+	//with the attribute could claim it
+	if existing != nil {
+		identity, identitymsp = existing.Identity, existing.IdentityMSP
+	}
+
 	//matching the given data to the tenant fields 
 	tenant := Tenant{
 		Pck: 	   pck,
 		Name: 	   name,
-		Email:     email,
-		Phone: 	   phone,
+		Identity:  identity,
+		IdentityMSP: identitymsp,
 		Registered: true,
 		Type:      "T",
 	}
 
+	//storing the contact details privately, only their hash is public
+	contact, err := readContactInput(ctx)
+	if err != nil {
+		return err
+	}
+	if contact != nil {
+		salt, err := readContactSalt(ctx, pck)
+		if err != nil {
+			return err
+		}
+		tenant.ContactHash, err = putTenantContact(ctx, pck, contact.Email, contact.Phone, salt)
+		if err != nil {
+			return err
+		}
+	} else if existing != nil {
+		tenant.ContactHash = existing.ContactHash
+	}
+
 	//storing to the world state based on the pck 
 	tenantAsBytes, _ := json.Marshal(tenant)
 	return ctx.GetStub().PutState(pck, tenantAsBytes)
 }
 
 
-//Update function updates the info of a tenant with new info in world state 
-func (s *SmartContract) Update(ctx contractapi.TransactionContextInterface, tenantNumber string, newName string, newEmail string, newPhone string) error {
+//Update function updates the info of a tenant with new info in world state, new contact details
+//are passed in the transient map like in Enroll and are left unchanged if not given
+func (s *SmartContract) Update(ctx contractapi.TransactionContextInterface, tenantNumber string, newName string) error {
 	//getting the data from the world state 
 	tenant, err := s.IsTenant(ctx, tenantNumber)
 	
 	if err != nil {
 		return err
 	}
+
+	//a tenant bound to an identity is only updated by it
+	if err := s.authorizeTenant(ctx, tenantNumber); err != nil {
+		return err
+	}
+	if err := checkAccessPolicy(ctx, "Update", tenantNumber); err != nil {
+		return err
+	}
 		
 	//updating tenant info, all fields 
 	tenant.Name = newName
-	tenant.Email = newEmail
-	tenant.Phone = newPhone
+
+	contact, err := readContactInput(ctx)
+	if err != nil {
+		return err
+	}
+	if contact != nil {
+		salt, err := readContactSalt(ctx, tenantNumber)
+		if err != nil {
+			return err
+		}
+		tenant.ContactHash, err = putTenantContact(ctx, tenantNumber, contact.Email, contact.Phone, salt)
+		if err != nil {
+			return err
+		}
+	}
 
 	//storing back to the world state the updated info 
 	tenantAsBytes, _ := json.Marshal(tenant)
@@ -921,13 +1363,30 @@ func (s *SmartContract) DestroyTenant(ctx contractapi.TransactionContextInterfac
 		return err
 	}
 
+	//only a platform admin can destroy a tenant
+	if err := requirePlatformAdmin(ctx); err != nil {
+		return err
+	}
+	if err := checkAccessPolicy(ctx, "DestroyTenant", pck); err != nil {
+		return err
+	}
+
+	//the cascade policy decides what happens to the grants of the tenant
+	report, _, err := s.cascadeDeregistration(ctx, pck, tenant.Type)
+	if err != nil {
+		return err
+	}
+
 	//updating the Registered field for the specific tenant 
 	tenant.Registered = false 
 
 	//storing the updated data back to the world state 
 	tenantAsBytes, _ := json.Marshal(tenant)
+	if err := ctx.GetStub().PutState(pck, tenantAsBytes); err != nil {
+		return err
+	}
 
-	return ctx.GetStub().PutState(pck, tenantAsBytes)
+	return deregistered(ctx, report)
 }
 
 
@@ -978,6 +1437,14 @@ func main() {
 		return
 	}
 
+	//serving the REST resources locally instead of starting as a peer chaincode
+	if addr := os.Getenv("TENANT_REST_ADDR"); addr != "" {
+		if err := serveREST(addr, chaincode); err != nil {
+			fmt.Printf("Error serving Saranyu chaincode over REST: %s", err.Error())
+		}
+		return
+	}
+
 	if err := chaincode.Start(); err != nil {
 		fmt.Printf("Error starting Saranyu chaincode: %s", err.Error())
 	}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGrantsCannotTakeThePckOfAnotherEntity(t *testing.T) {
+	l := initLedger(t)
+	now := time.Now()
+
+	for _, pck := range []string{"S3", "T1"} {
+		l.refused("a Delegation at "+pck, l.RegisterDelegation(pck, "S1", "S2", 3, now.Add(-time.Hour), now.Add(time.Hour), nil))
+	}
+	l.grant("D1", "S1", "S2", nil)
+	for _, pck := range []string{"D1", "T2", "S1"} {
+		l.refused("a SubDelegation at "+pck, l.RegisterSubDelegation(pck, "D1", "T3", 1, now.Add(-time.Hour), now.Add(time.Hour), nil))
+	}
+
+	service, err := l.IsService("S3")
+	l.ok("IsService", err)
+	if service.Type != "S" {
+		t.Fatalf("S3 was overwritten, %+v", service)
+	}
+}
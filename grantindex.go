@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+//***************************************************************************************************
+//**																							   **
+//**							The following section Manages the Grant Index                      **
+//**                                                                                               **
+//***************************************************************************************************
+
+//-------------------------------------Grant Index---------------------------------------------------
+//the grants are stored at their pck, so finding the grants of a tenant, service or group would mean
+//scanning the whole world state. Every Delegation and SubDelegation is indexed instead under its
+//grandor, its recipient and the tenant that pays for it when it is stored, and the index follows the
+//grant when its recipient, its grandor or its payer change. IndexGrants indexes the grants stored
+//before the index existed
+
+//grantIndexObjectType is the object type of the composite keys that index the grants
+const grantIndexObjectType = "grantindex"
+
+//the parts a principal can take in a grant
+const (
+	grandorIndex   = "grandor"
+	recipientIndex = "recipient"
+	payerIndex     = "payer"
+)
+
+//grantIndexKey returns the key that indexes a grant under the principal taking part in it
+func grantIndexKey(ctx contractapi.TransactionContextInterface, part string, principal string, pck string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(grantIndexObjectType, []string{part, principal, pck})
+	if err != nil {
+		return "", fmt.Errorf("Failed to create the grant index key. %s", err.Error())
+	}
+	return key, nil
+}
+
+//putGrantIndex indexes a grant under the principal taking part in it
+func putGrantIndex(ctx contractapi.TransactionContextInterface, part string, principal string, pck string) error {
+	if principal == "" {
+		return nil
+	}
+	key, err := grantIndexKey(ctx, part, principal, pck)
+	if err != nil {
+		return err
+	}
+
+	//the key is all the index needs, the value only has to be non empty
+	return ctx.GetStub().PutState(key, []byte{0x00})
+}
+
+//moveGrantIndex moves a grant from previous to next in the index of part
+func moveGrantIndex(ctx contractapi.TransactionContextInterface, part string, previous string, next string, pck string) error {
+	if previous == next {
+		return nil
+	}
+	if previous != "" {
+		key, err := grantIndexKey(ctx, part, previous, pck)
+		if err != nil {
+			return err
+		}
+		if err := ctx.GetStub().DelState(key); err != nil {
+			return err
+		}
+	}
+
+	return putGrantIndex(ctx, part, next, pck)
+}
+
+//indexGrant indexes a grant under its grandor, its recipient and the tenant that pays for it
+func (s *SmartContract) indexGrant(ctx contractapi.TransactionContextInterface, pck string, grandor string, recipient string) error {
+	payer, err := s.payingTenant(ctx, recipient)
+	if err != nil {
+		return err
+	}
+	if err := putGrantIndex(ctx, grandorIndex, grandor, pck); err != nil {
+		return err
+	}
+	if err := putGrantIndex(ctx, recipientIndex, recipient, pck); err != nil {
+		return err
+	}
+
+	return putGrantIndex(ctx, payerIndex, payer, pck)
+}
+
+//reindexRecipient moves a grant from its previous recipient and payer to its new ones
+func (s *SmartContract) reindexRecipient(ctx contractapi.TransactionContextInterface, pck string, previous string, recipient string) error {
+	previouspayer, err := s.payingTenant(ctx, previous)
+	if err != nil {
+		return err
+	}
+	payer, err := s.payingTenant(ctx, recipient)
+	if err != nil {
+		return err
+	}
+	if err := moveGrantIndex(ctx, recipientIndex, previous, recipient, pck); err != nil {
+		return err
+	}
+
+	return moveGrantIndex(ctx, payerIndex, previouspayer, payer, pck)
+}
+
+//indexedGrants returns the Delegations and SubDelegations principal takes part in as part
+func (s *SmartContract) indexedGrants(ctx contractapi.TransactionContextInterface, part string, principal string) ([]*Delegation, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(grantIndexObjectType, []string{part, principal})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	defer iterator.Close()
+
+	grants := []*Delegation{}
+	for iterator.HasNext() {
+		response, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+		}
+
+		_, keys, err := ctx.GetStub().SplitCompositeKey(response.Key)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to split the grant index key. %s", err.Error())
+		}
+
+		grant, err := s.IsDelegation(ctx, keys[2])
+		if err != nil {
+			return nil, err
+		}
+		grants = append(grants, grant)
+	}
+
+	return grants, nil
+}
+
+//IndexGrants indexes the Delegations and SubDelegations stored before the grant index existed, and links
+//the Delegations of a Service stored before the quotas existed. It scans the whole world state once,
+//only a platform admin can run it and running it again changes nothing
+func (s *SmartContract) IndexGrants(ctx contractapi.TransactionContextInterface) error {
+	if err := requirePlatformAdmin(ctx); err != nil {
+		return err
+	}
+
+	//composite keys are not part of the range, only the entities stored at their pck are
+	iterator, err := ctx.GetStub().GetStateByRange("", "")
+	if err != nil {
+		return fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	defer iterator.Close()
+
+	for iterator.HasNext() {
+		response, err := iterator.Next()
+		if err != nil {
+			return fmt.Errorf("Failed to read from world state. %s", err.Error())
+		}
+
+		grant := new(Delegation)
+		if json.Unmarshal(response.Value, grant) != nil {
+			continue
+		}
+		if grant.Type != "D" && grant.Type != "SD" {
+			continue
+		}
+
+		if err := s.indexGrant(ctx, grant.Pck, grant.Grandor, grant.Recipient); err != nil {
+			return err
+		}
+		if grant.Type == "D" {
+			if err := recordServiceGrant(ctx, grant.Grandor, grant.Pck); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+//--------------------------------------End Of Grant Index-------------------------------------------
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTheGrantIndexFollowsATransfer(t *testing.T) {
+	l := initLedger(t)
+	l.grant("D1", "S1", "S2", nil)
+	l.subgrant("SD1", "D1", "T4", 1, nil)
+	l.ok("TransferDelegation", l.TransferDelegation("SD1", "T5"))
+	l.ok("ApproveTransfer", l.ApproveTransfer("SD1"))
+	l.ok("AcceptGrant", l.AcceptGrant("SD1"))
+
+	old, err := l.GetDependentGrants("T4")
+	l.ok("GetDependentGrants", err)
+	next, err := l.GetDependentGrants("T5")
+	l.ok("GetDependentGrants", err)
+	if len(old) != 0 || len(next) != 1 || next[0].Pck != "SD1" {
+		t.Fatalf("T4 takes part in %d grants and T5 in %d", len(old), len(next))
+	}
+
+	//the dunning of the old recipient no longer reaches SD1, the dunning of the new one does
+	l.ok("IssueInvoice", l.IssueInvoice("T4", "I4", "10", time.Now()))
+	l.ok("IssueInvoice", l.IssueInvoice("T5", "I5", "10", time.Now()))
+	overdue()
+	_, err = l.RunDunning("T4", 0)
+	l.ok("RunDunning", err)
+	if sd1, _ := l.IsSubDelegation("SD1"); sd1.Suspended {
+		t.Fatal("SD1 was suspended by the dunning of its old payer")
+	}
+	_, err = l.RunDunning("T5", 0)
+	l.ok("RunDunning", err)
+	if sd1, _ := l.IsSubDelegation("SD1"); !sd1.Suspended {
+		t.Fatal("SD1 was not suspended by the dunning of its new payer")
+	}
+}
+
+func TestTheGrantIndexFollowsTheOwnerOfAService(t *testing.T) {
+	l := initLedger(t)
+	l.grant("D1", "S1", "S2", nil)
+	l.ok("TransferServiceOwnership", l.TransferServiceOwnership("S2", "T6"))
+	l.ok("AcceptServiceOwnership", l.AcceptServiceOwnership("S2"))
+
+	//D1 is paid by the owner of S2 it was granted to
+	l.ok("IssueInvoice", l.IssueInvoice("T2", "I2", "10", time.Now()))
+	l.ok("IssueInvoice", l.IssueInvoice("T6", "I6", "10", time.Now()))
+	overdue()
+	_, err := l.RunDunning("T2", 0)
+	l.ok("RunDunning", err)
+	if valid, _ := l.IsValid("D1"); !valid {
+		t.Fatal("D1 was suspended by the dunning of the previous owner of S2")
+	}
+	_, err = l.RunDunning("T6", 0)
+	l.ok("RunDunning", err)
+	if valid, _ := l.IsValid("D1"); valid {
+		t.Fatal("D1 was not suspended by the dunning of the owner of S2")
+	}
+
+	l.ok("IndexGrants", l.IndexGrants())
+	l.as("Org2MSP", certificate(t, "nobody", false, ""))
+	l.refused("indexing by a member", l.IndexGrants())
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+//***************************************************************************************************
+//**																							   **
+//**							The following section Manages the Grant Policy                     **
+//**                                                                                               **
+//***************************************************************************************************
+
+//-------------------------------------Grant Policy--------------------------------------------------
+//the grant policy is the matrix of the entity types that may grant a Delegation or SubDelegation to
+//which, the registration transactions check it instead of fixed type checks. A Delegation takes its
+//resources from the quota of a service so it is always granted by a service, a SubDelegation is granted
+//by the recipient of the previous grant. Until a platform admin changes it the matrix allows what the
+//contract has always allowed, Delegations from a service to a service, tenant or group and
+//SubDelegations from any of them to a tenant or group. Subdelegating to a service, for example a tenant
+//handing its share to the service account of an application, has to be allowed explicitly
+
+//object type of the composite key of the grant rules
+const grantRuleObjectType = "grantrule"
+
+//grantTypes are the entity types that can grant or receive a grant
+var grantTypes = []string{"S", "T", "G"}
+
+//typeNames are the names of the entity types used in the messages
+var typeNames = map[string]string{"S": "Service", "T": "Tenant", "G": "Group", "D": "Delegation", "SD": "SubDelegation"}
+
+//defaultGrantRules are the cells of the matrix that are allowed when no rule was set for them
+var defaultGrantRules = map[string]bool{
+	"D/S/S": true, "D/S/T": true, "D/S/G": true,
+	"SD/S/T": true, "SD/S/G": true,
+	"SD/T/T": true, "SD/T/G": true,
+	"SD/G/T": true, "SD/G/G": true,
+}
+
+//GrantRule is a cell of the grant policy matrix
+type GrantRule struct {
+	Grant   string `json:"grant"`   //D or SD
+	From    string `json:"from"`    //entity type of the grandor, S, T or G
+	To      string `json:"to"`      //entity type of the recipient, S, T or G
+	Allowed bool   `json:"allowed"` //true if such grants can be registered
+	Default bool   `json:"default"` //true if no platform admin has set the rule
+	Type    string `json:"Type"`    //GR for GrantRule
+}
+
+//grantRuleKey returns the key of the rule of a cell of the matrix
+func grantRuleKey(ctx contractapi.TransactionContextInterface, grant string, from string, to string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(grantRuleObjectType, []string{grant, from, to})
+	if err != nil {
+		return "", fmt.Errorf("Failed to create the grant rule key. %s", err.Error())
+	}
+	return key, nil
+}
+
+//grantRule returns the rule of a cell of the matrix, its default when none was set
+func grantRule(ctx contractapi.TransactionContextInterface, grant string, from string, to string) (*GrantRule, error) {
+	key, err := grantRuleKey(ctx, grant, from, to)
+	if err != nil {
+		return nil, err
+	}
+	ruleAsBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+
+	if ruleAsBytes == nil {
+		return &GrantRule{Grant: grant, From: from, To: to, Allowed: defaultGrantRules[grant+"/"+from+"/"+to], Default: true, Type: "GR"}, nil
+	}
+
+	rule := new(GrantRule)
+	_ = json.Unmarshal(ruleAsBytes, rule)
+
+	return rule, nil
+}
+
+//checkGrantRule checks that the grant policy allows a grant of the given type from the grandor to the
+//recipient entity type
+func checkGrantRule(ctx contractapi.TransactionContextInterface, grant string, from string, to string) error {
+	if !stringInSlice(from, grantTypes) || !stringInSlice(to, grantTypes) {
+		return fmt.Errorf("A %s can only be granted between Services, Tenants and Groups", typeNames[grant])
+	}
+
+	rule, err := grantRule(ctx, grant, from, to)
+	if err != nil {
+		return err
+	}
+	if !rule.Allowed {
+		return fmt.Errorf("The grant policy does not allow a %s from a %s to a %s", typeNames[grant], typeNames[from], typeNames[to])
+	}
+
+	return nil
+}
+
+//SetGrantRule allows or forbids grants of type grant (D or SD) from the entity type from to the entity type
+//to (S, T or G), only a platform admin can change the grant policy. Grants already registered are kept
+func (s *SmartContract) SetGrantRule(ctx contractapi.TransactionContextInterface, grant string, from string, to string, allowed string) error {
+	if err := requirePlatformAdmin(ctx); err != nil {
+		return err
+	}
+	if grant != "D" && grant != "SD" {
+		return fmt.Errorf("grant must be D or SD")
+	}
+	if !stringInSlice(from, grantTypes) || !stringInSlice(to, grantTypes) {
+		return fmt.Errorf("from and to must be S, T or G")
+	}
+	if grant == "D" && from != "S" {
+		return fmt.Errorf("A Delegation takes its resources from a service and can only be granted by a Service")
+	}
+	tempallowed, err := strconv.ParseBool(allowed)
+	if err != nil {
+		return fmt.Errorf("allowed must be true or false")
+	}
+
+	key, err := grantRuleKey(ctx, grant, from, to)
+	if err != nil {
+		return err
+	}
+
+	rule := GrantRule{Grant: grant, From: from, To: to, Allowed: tempallowed, Type: "GR"}
+	ruleAsBytes, _ := json.Marshal(rule)
+	if err := ctx.GetStub().PutState(key, ruleAsBytes); err != nil {
+		return err
+	}
+
+	return ctx.GetStub().SetEvent("GrantRuleChanged", ruleAsBytes)
+}
+
+//GetGrantPolicy returns every cell of the grant policy matrix, the Delegation cells first
+func (s *SmartContract) GetGrantPolicy(ctx contractapi.TransactionContextInterface) ([]*GrantRule, error) {
+	rules := []*GrantRule{}
+	for _, grant := range []string{"D", "SD"} {
+		for _, from := range grantTypes {
+			if grant == "D" && from != "S" {
+				continue
+			}
+			for _, to := range grantTypes {
+				rule, err := grantRule(ctx, grant, from, to)
+				if err != nil {
+					return nil, err
+				}
+				rules = append(rules, rule)
+			}
+		}
+	}
+
+	return rules, nil
+}
+
+//--------------------------------------End Of Grant Policy------------------------------------------
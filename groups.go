@@ -0,0 +1,521 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+//***************************************************************************************************
+//**																							   **
+//**							The following section Manages the Organizations and Groups         **
+//**                                                                                               **
+//***************************************************************************************************
+
+//-------------------------------------Organizations and Groups--------------------------------------
+//an Organization gathers the tenants of one customer and is managed through its owner tenant. A Group
+//is a team of tenants of an organization and can receive Delegations and SubDelegations as a tenant
+//does. The members of a group are not copied into its grants, they are resolved when access is checked
+//so adding or removing a member takes effect at once. Grants to a group are paid by the owner of its
+//organization, and any member bound to an identity can accept, subdelegate or revoke for the group. A
+//tenant only joins an organization or group it agreed to, the owner invites it and it accepts
+
+//object types of the composite keys of the membership index and of the invitations, tenant first so its
+//memberships can be listed
+const (
+	membershipObjectType = "membership"
+	invitationObjectType = "invitation"
+)
+
+//Organization describes a customer and the tenants that belong to it
+type Organization struct {
+	Pck        string   `json:"pck"`
+	Name       string   `json:"name"`
+	Owner      string   `json:"owner"`      //tenant that manages the organization
+	Members    []string `json:"members"`    //tenants of the organization, the owner included
+	Groups     []string `json:"groups"`     //groups of the organization
+	Registered bool     `json:"registered"` //true if registered false if not
+	Type       string   `json:"Type"`       //O for Organization
+}
+
+//Group describes a team of tenants of an organization
+type Group struct {
+	Pck          string   `json:"pck"`
+	Name         string   `json:"name"`
+	Organization string   `json:"organization"` //organization the group belongs to
+	Members      []string `json:"members"`      //tenants of the group, all members of the organization
+	Registered   bool     `json:"registered"`   //true if registered false if not
+	Type         string   `json:"Type"`         //G for Group
+}
+
+//Membership is an entry of the membership index
+type Membership struct {
+	Tenant    string `json:"tenant"`
+	Container string `json:"container"` //pck of the Organization or Group
+	Kind      string `json:"kind"`      //O or G
+	Type      string `json:"Type"`      //M for Membership
+}
+
+//removeFromSlice returns a copy of list without x
+func removeFromSlice(list []string, x string) []string {
+	kept := []string{}
+	for _, y := range list {
+		if y != x {
+			kept = append(kept, y)
+		}
+	}
+	return kept
+}
+
+//putEntity stores an organization or group at its pck
+func putEntity(ctx contractapi.TransactionContextInterface, pck string, entity interface{}) error {
+	entityAsBytes, _ := json.Marshal(entity)
+	return ctx.GetStub().PutState(pck, entityAsBytes)
+}
+
+//putMembership adds or removes an entry of the membership index
+func putMembership(ctx contractapi.TransactionContextInterface, tenant string, container string, kind string, member bool) error {
+	key, err := ctx.GetStub().CreateCompositeKey(membershipObjectType, []string{tenant, container})
+	if err != nil {
+		return fmt.Errorf("Failed to create the membership key. %s", err.Error())
+	}
+	if !member {
+		return ctx.GetStub().DelState(key)
+	}
+
+	membershipAsBytes, _ := json.Marshal(Membership{Tenant: tenant, Container: container, Kind: kind, Type: "M"})
+
+	return ctx.GetStub().PutState(key, membershipAsBytes)
+}
+
+//unusedPck checks that no record is stored at pck yet
+func unusedPck(ctx contractapi.TransactionContextInterface, pck string) error {
+	if pck == "" {
+		return fmt.Errorf("pck must not be empty")
+	}
+	existing, err := ctx.GetStub().GetState(pck)
+	if err != nil {
+		return fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if existing != nil {
+		return fmt.Errorf("%s already exists", pck)
+	}
+	return nil
+}
+
+//IsOrganization returns the organization stored in the world state with given Pck (Key)
+func (s *SmartContract) IsOrganization(ctx contractapi.TransactionContextInterface, pck string) (*Organization, error) {
+	organizationAsBytes, err := ctx.GetStub().GetState(pck)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if organizationAsBytes == nil {
+		return nil, fmt.Errorf("%s does not exist", pck)
+	}
+
+	organization := new(Organization)
+	_ = json.Unmarshal(organizationAsBytes, organization)
+	if organization.Type != "O" {
+		return nil, fmt.Errorf("%s is not an Organization", pck)
+	}
+
+	return organization, nil
+}
+
+//IsGroup returns the group stored in the world state with given Pck (Key)
+func (s *SmartContract) IsGroup(ctx contractapi.TransactionContextInterface, pck string) (*Group, error) {
+	groupAsBytes, err := ctx.GetStub().GetState(pck)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if groupAsBytes == nil {
+		return nil, fmt.Errorf("%s does not exist", pck)
+	}
+
+	group := new(Group)
+	_ = json.Unmarshal(groupAsBytes, group)
+	if group.Type != "G" {
+		return nil, fmt.Errorf("%s is not a Group", pck)
+	}
+
+	return group, nil
+}
+
+//managedOrganization returns a registered organization after checking that the submitting identity can
+//act for its owner
+func (s *SmartContract) managedOrganization(ctx contractapi.TransactionContextInterface, pck string) (*Organization, error) {
+	organization, err := s.IsOrganization(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+	if !organization.Registered {
+		return nil, fmt.Errorf("%s is not registered", pck)
+	}
+	if err := s.authorizeTenant(ctx, organization.Owner); err != nil {
+		return nil, err
+	}
+	return organization, nil
+}
+
+//authorizeMember checks that the submitting identity can act for one of the members of a group, only
+//members bound to an identity count as an unbound member would let anyone act for the group
+func (s *SmartContract) authorizeMember(ctx contractapi.TransactionContextInterface, group *Group) error {
+	for _, member := range group.Members {
+		tenant, err := s.IsTenant(ctx, member)
+		if err != nil || tenant.Type != "T" || tenant.Identity == "" {
+			continue
+		}
+		if s.authorizeTenant(ctx, member) == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("The client cannot act for any member of %s", group.Pck)
+}
+
+//groupPayer returns the owner of the organization of pck when it is a group, the tenant that pays for
+//the grants given to the group
+func (s *SmartContract) groupPayer(ctx contractapi.TransactionContextInterface, pck string) (string, bool, error) {
+	group, err := s.IsGroup(ctx, pck)
+	if err != nil {
+		return "", false, nil
+	}
+	organization, err := s.IsOrganization(ctx, group.Organization)
+	if err != nil {
+		return "", true, err
+	}
+	return organization.Owner, true, nil
+}
+
+//RegisterOrganization creates an organization managed by the owner tenant, which becomes its first member
+func (s *SmartContract) RegisterOrganization(ctx contractapi.TransactionContextInterface, pck string, name string, owner string) error {
+	if err := unusedPck(ctx, pck); err != nil {
+		return err
+	}
+	if err := s.registeredTenant(ctx, owner); err != nil {
+		return err
+	}
+	if err := s.authorizeTenant(ctx, owner); err != nil {
+		return err
+	}
+
+	organization := Organization{
+		Pck:        pck,
+		Name:       name,
+		Owner:      owner,
+		Members:    []string{owner},
+		Groups:     []string{},
+		Registered: true,
+		Type:       "O",
+	}
+	if err := putEntity(ctx, pck, organization); err != nil {
+		return err
+	}
+
+	return putMembership(ctx, owner, pck, "O", true)
+}
+
+//RegisterGroup creates a group of the organization, on behalf of the owner of the organization
+func (s *SmartContract) RegisterGroup(ctx contractapi.TransactionContextInterface, pck string, name string, organization string) error {
+	if err := unusedPck(ctx, pck); err != nil {
+		return err
+	}
+	parent, err := s.managedOrganization(ctx, organization)
+	if err != nil {
+		return err
+	}
+
+	group := Group{
+		Pck:          pck,
+		Name:         name,
+		Organization: organization,
+		Members:      []string{},
+		Registered:   true,
+		Type:         "G",
+	}
+	if err := putEntity(ctx, pck, group); err != nil {
+		return err
+	}
+
+	parent.Groups = append(parent.Groups, pck)
+
+	return putEntity(ctx, organization, parent)
+}
+
+//UnRegisterGroup unregisters the group with given Pck (Key), its grants no longer give its members access,
+//the cascade policy of groups decides what happens to them
+func (s *SmartContract) UnRegisterGroup(ctx contractapi.TransactionContextInterface, pck string) error {
+	group, err := s.IsGroup(ctx, pck)
+	if err != nil {
+		return err
+	}
+	if _, err := s.managedOrganization(ctx, group.Organization); err != nil {
+		return err
+	}
+	report, _, err := s.cascadeDeregistration(ctx, pck, group.Type)
+	if err != nil {
+		return err
+	}
+
+	group.Registered = false
+	if err := putEntity(ctx, pck, group); err != nil {
+		return err
+	}
+
+	return deregistered(ctx, report)
+}
+
+//joinable returns the group, nil when pck is an organization, and the organization tenant is to join
+//after checking that it can join them, the members of a group must be members of its organization
+func (s *SmartContract) joinable(ctx contractapi.TransactionContextInterface, pck string, tenant string) (*Group, *Organization, error) {
+	if group, err := s.IsGroup(ctx, pck); err == nil {
+		organization, err := s.IsOrganization(ctx, group.Organization)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !organization.Registered {
+			return nil, nil, fmt.Errorf("%s is not registered", organization.Pck)
+		}
+		if !stringInSlice(tenant, organization.Members) {
+			return nil, nil, fmt.Errorf("%s must be a member of %s to join %s", tenant, organization.Pck, pck)
+		}
+		if stringInSlice(tenant, group.Members) {
+			return nil, nil, fmt.Errorf("%s is already a member of %s", tenant, pck)
+		}
+		return group, organization, nil
+	}
+
+	organization, err := s.IsOrganization(ctx, pck)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !organization.Registered {
+		return nil, nil, fmt.Errorf("%s is not registered", pck)
+	}
+	if stringInSlice(tenant, organization.Members) {
+		return nil, nil, fmt.Errorf("%s is already a member of %s", tenant, pck)
+	}
+	return nil, organization, nil
+}
+
+//addMember stores tenant as a member of the group or, when group is nil, of the organization
+func addMember(ctx contractapi.TransactionContextInterface, tenant string, group *Group, organization *Organization) error {
+	if group != nil {
+		group.Members = append(group.Members, tenant)
+		if err := putEntity(ctx, group.Pck, group); err != nil {
+			return err
+		}
+		return putMembership(ctx, tenant, group.Pck, "G", true)
+	}
+
+	organization.Members = append(organization.Members, tenant)
+	if err := putEntity(ctx, organization.Pck, organization); err != nil {
+		return err
+	}
+	return putMembership(ctx, tenant, organization.Pck, "O", true)
+}
+
+//invitationKey returns the key of the invitation of a tenant to an organization or group
+func invitationKey(ctx contractapi.TransactionContextInterface, tenant string, container string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(invitationObjectType, []string{tenant, container})
+	if err != nil {
+		return "", fmt.Errorf("Failed to create the invitation key. %s", err.Error())
+	}
+	return key, nil
+}
+
+//AddMember adds a registered tenant to the Organization or Group with given Pck (Key) on behalf of the
+//owner of the organization, the members of a group must be members of its organization. A tenant the
+//submitting identity cannot act for as well is only invited, it joins once it accepts with
+//AcceptMembership
+func (s *SmartContract) AddMember(ctx contractapi.TransactionContextInterface, pck string, tenant string) error {
+	if err := s.registeredTenant(ctx, tenant); err != nil {
+		return err
+	}
+
+	group, organization, err := s.joinable(ctx, pck, tenant)
+	if err != nil {
+		return err
+	}
+	if err := s.authorizeTenant(ctx, organization.Owner); err != nil {
+		return err
+	}
+
+	if s.authorizeTenant(ctx, tenant) == nil {
+		return addMember(ctx, tenant, group, organization)
+	}
+
+	kind := "O"
+	if group != nil {
+		kind = "G"
+	}
+	key, err := invitationKey(ctx, tenant, pck)
+	if err != nil {
+		return err
+	}
+	invitationAsBytes, _ := json.Marshal(Membership{Tenant: tenant, Container: pck, Kind: kind, Type: "MI"})
+	if err := ctx.GetStub().PutState(key, invitationAsBytes); err != nil {
+		return err
+	}
+
+	return ctx.GetStub().SetEvent("MemberInvited", invitationAsBytes)
+}
+
+//AcceptMembership accepts the invitation of tenant to the Organization or Group with given Pck (Key) on
+//behalf of the tenant, which then becomes a member
+func (s *SmartContract) AcceptMembership(ctx contractapi.TransactionContextInterface, pck string, tenant string) error {
+	key, err := invitationKey(ctx, tenant, pck)
+	if err != nil {
+		return err
+	}
+	invitationAsBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if invitationAsBytes == nil {
+		return fmt.Errorf("%s has not been invited to %s", tenant, pck)
+	}
+
+	if err := s.registeredTenant(ctx, tenant); err != nil {
+		return err
+	}
+	if err := s.authorizeTenant(ctx, tenant); err != nil {
+		return err
+	}
+
+	group, organization, err := s.joinable(ctx, pck, tenant)
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().DelState(key); err != nil {
+		return err
+	}
+
+	return addMember(ctx, tenant, group, organization)
+}
+
+//RemoveMember removes a tenant from the Organization or Group with given Pck (Key) on behalf of the owner
+//of the organization, a tenant leaving an organization leaves its groups too. The owner cannot leave
+func (s *SmartContract) RemoveMember(ctx contractapi.TransactionContextInterface, pck string, tenant string) error {
+	if group, err := s.IsGroup(ctx, pck); err == nil {
+		if _, err := s.managedOrganization(ctx, group.Organization); err != nil {
+			return err
+		}
+		if !stringInSlice(tenant, group.Members) {
+			return fmt.Errorf("%s is not a member of %s", tenant, pck)
+		}
+
+		group.Members = removeFromSlice(group.Members, tenant)
+		if err := putEntity(ctx, pck, group); err != nil {
+			return err
+		}
+		return putMembership(ctx, tenant, pck, "G", false)
+	}
+
+	organization, err := s.managedOrganization(ctx, pck)
+	if err != nil {
+		return err
+	}
+	if !stringInSlice(tenant, organization.Members) {
+		return fmt.Errorf("%s is not a member of %s", tenant, pck)
+	}
+	if tenant == organization.Owner {
+		return fmt.Errorf("The owner of %s cannot leave it", pck)
+	}
+
+	for _, groupid := range organization.Groups {
+		group, err := s.IsGroup(ctx, groupid)
+		if err != nil {
+			return err
+		}
+		if !stringInSlice(tenant, group.Members) {
+			continue
+		}
+		group.Members = removeFromSlice(group.Members, tenant)
+		if err := putEntity(ctx, groupid, group); err != nil {
+			return err
+		}
+		if err := putMembership(ctx, tenant, groupid, "G", false); err != nil {
+			return err
+		}
+	}
+
+	organization.Members = removeFromSlice(organization.Members, tenant)
+	if err := putEntity(ctx, pck, organization); err != nil {
+		return err
+	}
+	return putMembership(ctx, tenant, pck, "O", false)
+}
+
+//GetMemberships returns the organizations and groups the tenant with given Pck (Key) is a member of
+func (s *SmartContract) GetMemberships(ctx contractapi.TransactionContextInterface, tenant string) ([]*Membership, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(membershipObjectType, []string{tenant})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	defer iterator.Close()
+
+	memberships := []*Membership{}
+	for iterator.HasNext() {
+		response, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+		}
+
+		membership := new(Membership)
+		_ = json.Unmarshal(response.Value, membership)
+		memberships = append(memberships, membership)
+	}
+
+	return memberships, nil
+}
+
+//CheckMemberAccess checks if the Delegation or SubDelegation with given Pck (Key) gives tenant access
+//now. The grant must be valid and tenant must be its recipient or, when the recipient is a group, a
+//registered member of the group and of its organization at the time of the check
+func (s *SmartContract) CheckMemberAccess(ctx contractapi.TransactionContextInterface, pck string, tenant string) (bool, error) {
+	grant, err := s.IsDelegation(ctx, pck)
+	if err != nil {
+		return false, err
+	}
+
+	var valid bool
+	switch grant.Type {
+	case "D":
+		valid, err = s.IsValid(ctx, pck)
+	case "SD":
+		valid = s.IsSubValid(ctx, pck)
+	default:
+		return false, fmt.Errorf("%s is not a Delegation or SubDelegation", pck)
+	}
+	if err != nil || !valid {
+		return false, err
+	}
+
+	member, err := s.IsTenant(ctx, tenant)
+	if err != nil {
+		return false, err
+	}
+	if member.Type != "T" || !member.Registered {
+		return false, nil
+	}
+	if grant.Recipient == tenant {
+		return true, nil
+	}
+
+	group, err := s.IsGroup(ctx, grant.Recipient)
+	if err != nil {
+		return false, nil
+	}
+	if !group.Registered || !stringInSlice(tenant, group.Members) {
+		return false, nil
+	}
+	organization, err := s.IsOrganization(ctx, group.Organization)
+	if err != nil {
+		return false, err
+	}
+
+	return organization.Registered && stringInSlice(tenant, organization.Members), nil
+}
+
+//--------------------------------------End Of Organizations and Groups------------------------------
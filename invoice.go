@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+//***************************************************************************************************
+//**																							   **
+//**							The following section Manages the Tenant Invoices                  **
+//**                                                                                               **
+//***************************************************************************************************
+
+//-------------------------------------Tenant Invoices-----------------------------------------------
+//an invoice is an amount a tenant owes by a due date. It is paid from the wallet of the tenant when
+//it has one, a hold placed under the id of the invoice is captured first, otherwise the payment made
+//outside the ledger is recorded with its reference. Recording a payment settles the dunning of the
+//tenant once none of its invoices is overdue any more, see dunning.go. Billing identities issue the
+//invoices, the tenant or a billing identity records their payment
+
+//object type of the composite key of the invoices
+const invoiceObjectType = "invoice"
+
+//Invoice is an amount owed by a tenant
+type Invoice struct {
+	Tenant    string `json:"tenant"`
+	InvoiceID string `json:"invoiceid"`
+	Amount    Money  `json:"amount"`
+	Issued    int64  `json:"issued"`  //Unix seconds of the transaction that issued the invoice
+	Due       int64  `json:"due"`     //Unix seconds the invoice must be paid by
+	Paid      bool   `json:"paid"`    //false until the payment is recorded
+	PaidAt    int64  `json:"paidat"`  //Unix seconds of the transaction that recorded the payment
+	Payment   string `json:"payment"` //reference of the payment, wallet for invoices paid from the wallet
+	Type      string `json:"Type"`    //IN for Invoice
+}
+
+//invoiceKey returns the key of an invoice of a tenant
+func invoiceKey(ctx contractapi.TransactionContextInterface, tenant string, invoiceid string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(invoiceObjectType, []string{tenant, invoiceid})
+	if err != nil {
+		return "", fmt.Errorf("Failed to create the invoice key. %s", err.Error())
+	}
+	return key, nil
+}
+
+//putInvoice stores an invoice
+func putInvoice(ctx contractapi.TransactionContextInterface, invoice *Invoice) error {
+	key, err := invoiceKey(ctx, invoice.Tenant, invoice.InvoiceID)
+	if err != nil {
+		return err
+	}
+
+	invoiceAsBytes, _ := json.Marshal(invoice)
+
+	return ctx.GetStub().PutState(key, invoiceAsBytes)
+}
+
+//GetInvoice returns the invoice invoiceid of the tenant with given Pck (Key)
+func (s *SmartContract) GetInvoice(ctx contractapi.TransactionContextInterface, pck string, invoiceid string) (*Invoice, error) {
+	key, err := invoiceKey(ctx, pck, invoiceid)
+	if err != nil {
+		return nil, err
+	}
+	invoiceAsBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if invoiceAsBytes == nil {
+		return nil, fmt.Errorf("%s has no invoice %s", pck, invoiceid)
+	}
+
+	invoice := new(Invoice)
+	_ = json.Unmarshal(invoiceAsBytes, invoice)
+
+	return invoice, nil
+}
+
+//GetInvoices returns every invoice of the tenant with given Pck (Key)
+func (s *SmartContract) GetInvoices(ctx contractapi.TransactionContextInterface, pck string) ([]*Invoice, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(invoiceObjectType, []string{pck})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	defer iterator.Close()
+
+	invoices := []*Invoice{}
+	for iterator.HasNext() {
+		response, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+		}
+
+		invoice := new(Invoice)
+		_ = json.Unmarshal(response.Value, invoice)
+
+		invoices = append(invoices, invoice)
+	}
+
+	return invoices, nil
+}
+
+//IssueInvoice bills amount to the tenant with given Pck (Key), to be paid by due. An amount without a
+//currency is in the currency of the wallet of the tenant, or in the default currency
+func (s *SmartContract) IssueInvoice(ctx contractapi.TransactionContextInterface, pck string, invoiceid string, amount string, due string) error {
+	if invoiceid == "" {
+		return fmt.Errorf("invoiceid is required")
+	}
+	if err := requireBilling(ctx); err != nil {
+		return err
+	}
+	if err := s.registeredTenant(ctx, pck); err != nil {
+		return err
+	}
+	//the invoice is paid against a hold of the same id, which must not be the hold of a grant
+	if err := s.grantHold(ctx, invoiceid); err != nil {
+		return err
+	}
+
+	key, err := invoiceKey(ctx, pck, invoiceid)
+	if err != nil {
+		return err
+	}
+	existing, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if existing != nil {
+		return fmt.Errorf("%s already has an invoice %s", pck, invoiceid)
+	}
+
+	currency := defaultCurrency
+	wallet, err := s.findWallet(ctx, pck)
+	if err != nil {
+		return err
+	}
+	if wallet != nil {
+		currency = wallet.Balance.Currency
+	}
+
+	invoice := &Invoice{Tenant: pck, InvoiceID: invoiceid, Type: "IN"}
+	if invoice.Amount, err = parseMoney(amount, currency); err != nil {
+		return err
+	}
+	if invoice.Amount.Amount <= 0 {
+		return fmt.Errorf("An invoice must be positive")
+	}
+	if invoice.Due, err = strconv.ParseInt(due, 10, 64); err != nil {
+		return fmt.Errorf("due must be a Unix time")
+	}
+	if invoice.Issued, err = txSeconds(ctx); err != nil {
+		return err
+	}
+	if invoice.Due < invoice.Issued {
+		return fmt.Errorf("An invoice cannot be due before it is issued")
+	}
+
+	return putInvoice(ctx, invoice)
+}
+
+//PayInvoice records the payment of the invoice invoiceid of the tenant with given Pck (Key). A tenant
+//with a wallet pays from it, otherwise reference is the payment made outside the ledger, which only a
+//billing identity can record. The dunning of the tenant is settled when none of its invoices is overdue
+//any more
+func (s *SmartContract) PayInvoice(ctx contractapi.TransactionContextInterface, pck string, invoiceid string, reference string) (*Invoice, error) {
+	invoice, err := s.GetInvoice(ctx, pck, invoiceid)
+	if err != nil {
+		return nil, err
+	}
+	if invoice.Paid {
+		return nil, fmt.Errorf("Invoice %s of %s has already been paid", invoiceid, pck)
+	}
+	//the tenant itself can only settle from its own wallet
+	billing := requireBilling(ctx) == nil
+	if !billing {
+		if err := s.authorizeTenant(ctx, pck); err != nil {
+			return nil, err
+		}
+	}
+
+	wallet, err := s.findWallet(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+	if wallet == nil && !billing {
+		return nil, fmt.Errorf("%s has no wallet, only a billing identity can record a payment made outside the ledger", pck)
+	}
+	if wallet != nil {
+		if err := s.debitWallet(ctx, wallet, invoice.Amount, invoiceid); err != nil {
+			return nil, err
+		}
+		reference = "wallet"
+	} else if reference == "" {
+		return nil, fmt.Errorf("%s has no wallet, the reference of the payment is required", pck)
+	}
+
+	invoice.Paid = true
+	invoice.Payment = reference
+	if invoice.PaidAt, err = txSeconds(ctx); err != nil {
+		return nil, err
+	}
+	if err := putInvoice(ctx, invoice); err != nil {
+		return nil, err
+	}
+
+	if err := s.settleDunning(ctx, pck, invoice.InvoiceID, invoice.PaidAt); err != nil {
+		return nil, err
+	}
+
+	return invoice, nil
+}
+
+//--------------------------------------End Of Tenant Invoices---------------------------------------
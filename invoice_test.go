@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestATenantPaysItsInvoicesOnlyFromItsWallet(t *testing.T) {
+	l := initLedger(t)
+	tenant := certificate(t, "t9", false, `{"attrs":{"tenant.id":"T9"}}`)
+	l.as("Org2MSP", tenant)
+	l.ok("Enroll", l.Enroll("T9", "Tenant Nine", nil))
+	l.asAdmin()
+	l.ok("IssueInvoice", l.IssueInvoice("T9", "I1", "10", time.Now().Add(time.Hour)))
+
+	l.as("Org2MSP", tenant)
+	_, err := l.PayInvoice("T9", "I1", "wire")
+	l.refused("a payment made outside the ledger recorded by the tenant", err)
+	l.ok("OpenWallet", l.OpenWallet("T9", "EUR"))
+
+	l.as("Org2MSP", certificate(t, "t3", false, ""))
+	_, err = l.PayInvoice("T9", "I1", "")
+	l.refused("a payment by another tenant", err)
+
+	l.asAdmin()
+	_, err = l.Deposit("T9", "20", "wire")
+	l.ok("Deposit", err)
+	l.as("Org2MSP", tenant)
+	invoice, err := l.PayInvoice("T9", "I1", "")
+	l.ok("PayInvoice", err)
+	wallet, err := l.GetWallet("T9")
+	l.ok("GetWallet", err)
+	if !invoice.Paid || wallet.Balance.String() != "10.00 EUR" {
+		t.Fatalf("invoice %+v, wallet %+v", invoice, wallet)
+	}
+}
+
+func TestPayingReinstatesOnlyTheGrantsTheDunningSuspended(t *testing.T) {
+	l := initLedger(t)
+	l.grant("D1", "S1", "S2", nil)
+	l.subgrant("SD1", "D1", "T3", 1, nil)
+	l.subgrant("SD2", "D1", "T3", 1, nil)
+	l.refused("an invoice under the pck of a grant", l.IssueInvoice("T3", "D1", "1", time.Now().Add(time.Hour)))
+	l.ok("IssueInvoice", l.IssueInvoice("T3", "I1", "10", time.Now()))
+	overdue()
+
+	l.as("Org2MSP", certificate(t, "nobody", false, ""))
+	_, err := l.RunDunning("T3", 0)
+	l.refused("dunning without the billing role", err)
+	l.asAdmin()
+	dunning, err := l.RunDunning("T3", 0)
+	l.ok("RunDunning", err)
+	if !dunning.Active || len(dunning.Suspended) != 2 {
+		t.Fatalf("the dunning holds %+v", dunning)
+	}
+	for _, pck := range []string{"SD1", "SD2"} {
+		if valid, _ := l.IsSubValid(pck); valid {
+			t.Fatalf("%s is still valid while its payer is dunned", pck)
+		}
+	}
+
+	//suspended again by hand, SD2 stays suspended once the invoice is paid
+	l.ok("SuspendSubDelegation", l.SuspendSubDelegation("SD2"))
+	_, err = l.PayInvoice("T3", "I1", "wire")
+	l.ok("PayInvoice", err)
+	sd1, err := l.IsSubDelegation("SD1")
+	l.ok("IsSubDelegation", err)
+	sd2, err := l.IsSubDelegation("SD2")
+	l.ok("IsSubDelegation", err)
+	if sd1.Suspended || !sd2.Suspended {
+		t.Fatalf("SD1 suspended %v, SD2 suspended %v", sd1.Suspended, sd2.Suspended)
+	}
+}
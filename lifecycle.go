@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+//***************************************************************************************************
+//**																							   **
+//**							The following section Manages the Deregistration Cascade           **
+//**                                                                                               **
+//***************************************************************************************************
+
+//-------------------------------------Deregistration Cascade----------------------------------------
+//a grant only makes sense while the principals it was given between are registered. DestroyTenant,
+//UnRegister_Service and UnRegisterGroup apply the cascade policy of the type of the principal to the
+//active grants it is the grandor or recipient of. The grants below them follow through their chain. With
+//revoke the grants are revoked, with suspend they are suspended, and with block the deregistration
+//is refused while active grants exist. Until a platform admin changes it every type suspends. IsValid and
+//IsSubValid also check that the grandor and recipient of every grant of the chain are still registered,
+//so grants given before the cascade existed stop being valid too
+
+//object type of the composite key of the cascade policies
+const cascadeObjectType = "cascade"
+
+//the cascade modes
+const (
+	cascadeRevoke  = "revoke"
+	cascadeSuspend = "suspend"
+	cascadeBlock   = "block"
+)
+
+//cascadeModes are the modes a cascade policy can be set to
+var cascadeModes = []string{cascadeRevoke, cascadeSuspend, cascadeBlock}
+
+//cascadeTypes are the entity types that can be deregistered
+var cascadeTypes = []string{"T", "S", "G"}
+
+//defaultCascadeMode is the mode of the types no platform admin has set a policy for
+const defaultCascadeMode = cascadeSuspend
+
+//CascadePolicy is what happens to the grants of a principal of the given type when it is deregistered
+type CascadePolicy struct {
+	Principal string `json:"principal"` //entity type, T, S or G
+	Mode      string `json:"mode"`      //revoke, suspend or block
+	Default   bool   `json:"default"`   //true if no platform admin has set the policy
+	Type      string `json:"Type"`      //CP for CascadePolicy
+}
+
+//CascadeReport is the payload of the PrincipalDeregistered event, and part of the ErasureReceipt
+type CascadeReport struct {
+	Principal string   `json:"principal"`
+	Mode      string   `json:"mode"`
+	Grants    []string `json:"grants"` //grants revoked or suspended by the deregistration
+}
+
+//cascadePolicyKey returns the key of the cascade policy of an entity type
+func cascadePolicyKey(ctx contractapi.TransactionContextInterface, principal string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(cascadeObjectType, []string{principal})
+	if err != nil {
+		return "", fmt.Errorf("Failed to create the cascade policy key. %s", err.Error())
+	}
+	return key, nil
+}
+
+//cascadePolicy returns the cascade policy of an entity type, the default when none was set
+func cascadePolicy(ctx contractapi.TransactionContextInterface, principal string) (*CascadePolicy, error) {
+	key, err := cascadePolicyKey(ctx, principal)
+	if err != nil {
+		return nil, err
+	}
+	policyAsBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+
+	if policyAsBytes == nil {
+		return &CascadePolicy{Principal: principal, Mode: defaultCascadeMode, Default: true, Type: "CP"}, nil
+	}
+
+	policy := new(CascadePolicy)
+	_ = json.Unmarshal(policyAsBytes, policy)
+
+	return policy, nil
+}
+
+//activeGrant reports if a grant can still be or become valid
+func activeGrant(grant *Delegation, now uint64) bool {
+	return !grant.Revoked && !grant.Suspended && !grant.Declined && grant.Expiry > now
+}
+
+//dependentGrants returns the active Delegations and SubDelegations the given principal is the grandor or
+//recipient of, from the grant index
+func (s *SmartContract) dependentGrants(ctx contractapi.TransactionContextInterface, principal string) ([]*Delegation, error) {
+	now, err := txSeconds(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	granted, err := s.indexedGrants(ctx, grandorIndex, principal)
+	if err != nil {
+		return nil, err
+	}
+	received, err := s.indexedGrants(ctx, recipientIndex, principal)
+	if err != nil {
+		return nil, err
+	}
+
+	grants := []*Delegation{}
+	for _, delegation := range append(granted, received...) {
+		if activeGrant(delegation, uint64(now)) {
+			grants = append(grants, delegation)
+		}
+	}
+
+	return grants, nil
+}
+
+//cascadeDeregistration applies the cascade policy of its type to the grants of a principal that is
+//about to be deregistered and reports the grants it changed, it returns an error when the policy blocks
+//the deregistration. The changed grants are returned too, a transaction does not read back what it wrote
+//so the caller carries on with them. The caller emits the report, only one event per transaction is kept
+func (s *SmartContract) cascadeDeregistration(ctx contractapi.TransactionContextInterface, principal string, principalType string) (*CascadeReport, []*Delegation, error) {
+	policy, err := cascadePolicy(ctx, principalType)
+	if err != nil {
+		return nil, nil, err
+	}
+	grants, err := s.dependentGrants(ctx, principal)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	report := CascadeReport{Principal: principal, Mode: policy.Mode, Grants: []string{}}
+	for _, grant := range grants {
+		report.Grants = append(report.Grants, grant.Pck)
+	}
+	if policy.Mode == cascadeBlock && len(grants) > 0 {
+		return nil, nil, fmt.Errorf("Cannot deregister %s while it takes part in active grants %v", principal, report.Grants)
+	}
+
+	for _, grant := range grants {
+		//the deregistration is decided by an admin, the revocation policies of the grants do not apply
+		if policy.Mode == cascadeRevoke {
+			grant.Revoked = true
+		} else {
+			grant.Suspended = true
+		}
+		grantAsBytes, _ := json.Marshal(grant)
+		if err := ctx.GetStub().PutState(grant.Pck, grantAsBytes); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return &report, grants, nil
+}
+
+//deregistered sets the PrincipalDeregistered event once the principal has been deregistered
+func deregistered(ctx contractapi.TransactionContextInterface, report *CascadeReport) error {
+	reportAsBytes, _ := json.Marshal(report)
+
+	return ctx.GetStub().SetEvent("PrincipalDeregistered", reportAsBytes)
+}
+
+//principalsRegistered checks that the grandor and recipient of every grant of a chain are registered
+func (s *SmartContract) principalsRegistered(ctx contractapi.TransactionContextInterface, chain []string) (bool, error) {
+	checked := []string{}
+	for _, x := range chain {
+		grant, err := s.IsDelegation(ctx, x)
+		if err != nil {
+			return false, err
+		}
+		for _, principal := range []string{grant.Grandor, grant.Recipient} {
+			if stringInSlice(principal, checked) {
+				continue
+			}
+			entity, err := s.IsTenant(ctx, principal)
+			if err != nil {
+				return false, err
+			}
+			if !entity.Registered {
+				return false, nil
+			}
+			checked = append(checked, principal)
+		}
+	}
+
+	return true, nil
+}
+
+//SetCascadePolicy sets what happens to the active grants of a principal of type principal (T, S or G)
+//when it is deregistered, mode is revoke, suspend or block. Only a platform admin can set it
+func (s *SmartContract) SetCascadePolicy(ctx contractapi.TransactionContextInterface, principal string, mode string) error {
+	if err := requirePlatformAdmin(ctx); err != nil {
+		return err
+	}
+	if !stringInSlice(principal, cascadeTypes) {
+		return fmt.Errorf("principal must be T, S or G")
+	}
+	if !stringInSlice(mode, cascadeModes) {
+		return fmt.Errorf("mode must be revoke, suspend or block")
+	}
+
+	key, err := cascadePolicyKey(ctx, principal)
+	if err != nil {
+		return err
+	}
+
+	policy := CascadePolicy{Principal: principal, Mode: mode, Type: "CP"}
+	policyAsBytes, _ := json.Marshal(policy)
+	if err := ctx.GetStub().PutState(key, policyAsBytes); err != nil {
+		return err
+	}
+
+	return ctx.GetStub().SetEvent("CascadePolicyChanged", policyAsBytes)
+}
+
+//GetCascadePolicies returns the cascade policy of every entity type that can be deregistered
+func (s *SmartContract) GetCascadePolicies(ctx contractapi.TransactionContextInterface) ([]*CascadePolicy, error) {
+	policies := []*CascadePolicy{}
+	for _, principal := range cascadeTypes {
+		policy, err := cascadePolicy(ctx, principal)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+
+	return policies, nil
+}
+
+//GetDependentGrants returns the active grants the principal with given Pck (Key) is the grandor or
+//recipient of, the grants its deregistration would revoke, suspend or be blocked by
+func (s *SmartContract) GetDependentGrants(ctx contractapi.TransactionContextInterface, pck string) ([]*Delegation, error) {
+	if _, err := s.IsTenant(ctx, pck); err != nil {
+		return nil, err
+	}
+
+	return s.dependentGrants(ctx, pck)
+}
+
+//--------------------------------------End Of Deregistration Cascade--------------------------------
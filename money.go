@@ -0,0 +1,474 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+//***************************************************************************************************
+//**																							   **
+//**							The following section Manages the Money and Currencies             **
+//**                                                                                               **
+//***************************************************************************************************
+
+//-------------------------------------Money Management----------------------------------------------
+//every amount of money carries its currency and is kept as a fixed-point integer of millionths of
+//the currency unit, so prices below one unit and costs of a fraction of a unit are never lost. An
+//amount is only rounded to the decimals of its currency when it is reported, with the rounding rule of
+//the service that charges it or of the currency. Exchange rates are recorded per currency pair and
+//are used to consolidate the charges of a tenant in a single currency
+
+//moneyScale is the number of fixed-point units in one unit of a currency
+const moneyScale = 1000000
+
+//moneyDecimals is the number of decimals moneyScale keeps
+const moneyDecimals = 6
+
+//rateScale is the number of fixed-point units in an exchange rate of one
+const rateScale = 1000000000
+
+//rateDecimals is the number of decimals rateScale keeps
+const rateDecimals = 9
+
+//defaultCurrency is the currency of the prices set before currencies existed
+const defaultCurrency = "EUR"
+
+//defaultCostPerHour is the price per core and hour, in whole units of defaultCurrency, of services that
+//never had a price set
+const defaultCostPerHour = 2
+
+//object types of the composite keys of the currencies and the exchange rates
+const (
+	currencyObjectType = "currency"
+	rateObjectType     = "rate"
+)
+
+//the rounding rules an amount can be rounded with
+const (
+	roundHalfEven = "half-even" //to the nearest, ties to the even digit
+	roundHalfUp   = "half-up"   //to the nearest, ties away from zero
+	roundDown     = "down"      //towards zero
+	roundUp       = "up"        //away from zero
+)
+
+//Money is an amount of a currency in millionths of its unit
+type Money struct {
+	Amount   int64  `json:"amount"`   //millionths of the currency unit
+	Currency string `json:"currency"` //ISO 4217 code
+}
+
+//Currency holds the settings of a currency
+type Currency struct {
+	Code     string `json:"code"`     //ISO 4217 code
+	Decimals uint8  `json:"decimals"` //decimals amounts are rounded to when reported
+	Rounding string `json:"rounding"` //rounding rule of the currency
+	Type     string `json:"Type"`     //CU for Currency
+}
+
+//ExchangeRate is the value of one unit of From in units of To
+type ExchangeRate struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Rate      int64  `json:"rate"`      //billionths of a unit of To for one unit of From
+	Timestamp int64  `json:"timestamp"` //Unix seconds of the transaction that set the rate
+	SetBy     string `json:"setby"`     //MSP of the identity that set the rate
+	Type      string `json:"Type"`      //XR for Exchange Rate
+}
+
+//parseDecimal turns a decimal string into a fixed-point integer with the given decimals
+func parseDecimal(value string, decimals int) (int64, error) {
+	value = strings.TrimSpace(value)
+
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimPrefix(value, "-")
+
+	parts := strings.SplitN(value, ".", 2)
+	whole, fraction := parts[0], ""
+	if len(parts) == 2 {
+		fraction = parts[1]
+	}
+	if whole == "" && fraction == "" {
+		return 0, fmt.Errorf("%q is not a number", value)
+	}
+	if len(fraction) > decimals {
+		return 0, fmt.Errorf("%q has more than %d decimals", value, decimals)
+	}
+	fraction += strings.Repeat("0", decimals-len(fraction))
+
+	result, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil || strings.ContainsAny(whole+fraction, "+-") {
+		return 0, fmt.Errorf("%q is not a number", value)
+	}
+	if negative {
+		result = -result
+	}
+
+	return result, nil
+}
+
+//formatDecimal turns a fixed-point integer with the given decimals into a decimal string
+func formatDecimal(value int64, decimals int) string {
+	sign := ""
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+
+	digits := strconv.FormatInt(value, 10)
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+	if decimals == 0 {
+		return sign + digits
+	}
+
+	return sign + digits[:len(digits)-decimals] + "." + digits[len(digits)-decimals:]
+}
+
+//validCurrency checks a currency code, three upper case letters as in ISO 4217
+func validCurrency(code string) error {
+	if len(code) != 3 || strings.ToUpper(code) != code || strings.ContainsAny(code, "0123456789") {
+		return fmt.Errorf("%q is not a currency code, expected three upper case letters", code)
+	}
+	return nil
+}
+
+//validRounding checks a rounding rule
+func validRounding(rounding string) error {
+	switch rounding {
+	case roundHalfEven, roundHalfUp, roundDown, roundUp:
+		return nil
+	}
+	return fmt.Errorf("%q is not a rounding rule, expected %s, %s, %s or %s", rounding, roundHalfEven, roundHalfUp, roundDown, roundUp)
+}
+
+//parseMoney reads an amount such as "2.50" or "2.50 USD", the currency is used when none is given
+func parseMoney(value string, currency string) (Money, error) {
+	fields := strings.Fields(value)
+	if len(fields) == 2 {
+		value, currency = fields[0], fields[1]
+	} else if len(fields) != 1 {
+		return Money{}, fmt.Errorf("%q is not an amount, expected a number and an optional currency", value)
+	}
+
+	if err := validCurrency(currency); err != nil {
+		return Money{}, err
+	}
+	amount, err := parseDecimal(value, moneyDecimals)
+	if err != nil {
+		return Money{}, err
+	}
+
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+//String prints the amount with all its decimals and its currency
+func (m Money) String() string {
+	return formatDecimal(m.Amount, moneyDecimals) + " " + m.Currency
+}
+
+//Round returns the amount rounded to the given decimals with the rounding rule
+func (m Money) Round(decimals uint8, rounding string) Money {
+	if int(decimals) >= moneyDecimals {
+		return m
+	}
+
+	step := int64(1)
+	for i := int(decimals); i < moneyDecimals; i++ {
+		step *= 10
+	}
+
+	quotient, remainder := m.Amount/step, m.Amount%step
+	if remainder == 0 {
+		return m
+	}
+
+	//the remainder has the sign of the amount, away moves the quotient away from zero
+	away := int64(1)
+	if m.Amount < 0 {
+		away = -1
+		remainder = -remainder
+	}
+
+	switch rounding {
+	case roundDown:
+	case roundUp:
+		quotient += away
+	case roundHalfUp:
+		if remainder*2 >= step {
+			quotient += away
+		}
+	default:
+		if remainder*2 > step || (remainder*2 == step && quotient%2 != 0) {
+			quotient += away
+		}
+	}
+
+	return Money{Amount: quotient * step, Currency: m.Currency}
+}
+
+//convert changes an amount to another currency with a rate in billionths
+func (m Money) convert(currency string, rate int64) (Money, error) {
+	amount := new(big.Int).Mul(big.NewInt(m.Amount), big.NewInt(rate))
+	amount.Quo(amount, big.NewInt(rateScale))
+	if !amount.IsInt64() {
+		return Money{}, fmt.Errorf("%s is too large to convert to %s", m.String(), currency)
+	}
+
+	return Money{Amount: amount.Int64(), Currency: currency}, nil
+}
+
+//times multiplies an amount by whole numbers, failing on overflow
+func (m Money) times(factors ...uint64) (Money, error) {
+	amount := big.NewInt(m.Amount)
+	for _, factor := range factors {
+		amount.Mul(amount, new(big.Int).SetUint64(factor))
+	}
+	if !amount.IsInt64() {
+		return Money{}, fmt.Errorf("%s times %v is too large", m.String(), factors)
+	}
+
+	return Money{Amount: amount.Int64(), Currency: m.Currency}, nil
+}
+
+//add sums two amounts of the same currency, failing on overflow
+func (m Money) add(other Money) (Money, error) {
+	amount := new(big.Int).Add(big.NewInt(m.Amount), big.NewInt(other.Amount))
+	if !amount.IsInt64() {
+		return Money{}, fmt.Errorf("%s plus %s is too large", m.String(), other.String())
+	}
+
+	return Money{Amount: amount.Int64(), Currency: m.Currency}, nil
+}
+
+//coreTime prices cores over a number of seconds with a price per core and hour, rounded up to the
+//next millionth
+func (m Money) coreTime(cores uint64, seconds uint64) (Money, error) {
+	amount := new(big.Int).Mul(big.NewInt(m.Amount), new(big.Int).SetUint64(cores))
+	amount.Mul(amount, new(big.Int).SetUint64(seconds))
+	amount.Add(amount, big.NewInt(3599))
+	amount.Quo(amount, big.NewInt(3600))
+	if !amount.IsInt64() {
+		return Money{}, fmt.Errorf("%s for %d cores over %d seconds is too large", m.String(), cores, seconds)
+	}
+
+	return Money{Amount: amount.Int64(), Currency: m.Currency}, nil
+}
+
+//servicePrice returns the price per core and hour of a service
+func servicePrice(service *Service) Money {
+	if service.Price.Currency != "" {
+		return service.Price
+	}
+
+	//services priced before currencies existed have a whole unit price in the default currency
+	units := service.CostPerHour
+	if units == 0 {
+		units = defaultCostPerHour
+	}
+
+	return Money{Amount: int64(units) * moneyScale, Currency: defaultCurrency}
+}
+
+//storagePrice returns the price per GB and hour of the storage of a service in the given currency, a
+//service without storage price charges nothing for its storage
+func (s *SmartContract) storagePrice(ctx contractapi.TransactionContextInterface, service *Service, currency string) (Money, error) {
+	price := service.StoragePrice
+	if price.Amount == 0 || price.Currency == currency {
+		return Money{Amount: price.Amount, Currency: currency}, nil
+	}
+
+	exchangerate, err := s.GetExchangeRate(ctx, price.Currency, currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	return price.convert(currency, exchangerate.Rate)
+}
+
+//GetCurrency returns the settings of the currency with given code, currencies that have not been set
+//are rounded half-even to two decimals
+func (s *SmartContract) GetCurrency(ctx contractapi.TransactionContextInterface, code string) (*Currency, error) {
+	if err := validCurrency(code); err != nil {
+		return nil, err
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(currencyObjectType, []string{code})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create the currency key. %s", err.Error())
+	}
+	currencyAsBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+
+	currency := &Currency{Code: code, Decimals: 2, Rounding: roundHalfEven, Type: "CU"}
+	if currencyAsBytes != nil {
+		_ = json.Unmarshal(currencyAsBytes, currency)
+	}
+
+	return currency, nil
+}
+
+//SetCurrency sets the decimals and the rounding rule of the currency with given code. Only a platform
+//admin can set it
+func (s *SmartContract) SetCurrency(ctx contractapi.TransactionContextInterface, code string, decimals string, rounding string) error {
+	if err := requirePlatformAdmin(ctx); err != nil {
+		return err
+	}
+	if err := validCurrency(code); err != nil {
+		return err
+	}
+	tempdecimals, err := strconv.ParseUint(decimals, 10, 8)
+	if err != nil || tempdecimals > moneyDecimals {
+		return fmt.Errorf("decimals must be a number between 0 and %d", moneyDecimals)
+	}
+	if err := validRounding(rounding); err != nil {
+		return err
+	}
+
+	currency := Currency{Code: code, Decimals: uint8(tempdecimals), Rounding: rounding, Type: "CU"}
+
+	key, err := ctx.GetStub().CreateCompositeKey(currencyObjectType, []string{code})
+	if err != nil {
+		return fmt.Errorf("Failed to create the currency key. %s", err.Error())
+	}
+	currencyAsBytes, _ := json.Marshal(currency)
+
+	return ctx.GetStub().PutState(key, currencyAsBytes)
+}
+
+//roundCharge rounds an amount charged by a service to the decimals of its currency, with the rounding
+//rule of the service or else the one of the currency
+func (s *SmartContract) roundCharge(ctx contractapi.TransactionContextInterface, amount Money, rounding string) (Money, error) {
+	currency, err := s.GetCurrency(ctx, amount.Currency)
+	if err != nil {
+		return Money{}, err
+	}
+	if rounding == "" {
+		rounding = currency.Rounding
+	}
+
+	return amount.Round(currency.Decimals, rounding), nil
+}
+
+//SetServiceRounding sets the rounding rule the service with given Pck (Key) charges with, an empty rule
+//uses the rule of the currency of the service
+func (s *SmartContract) SetServiceRounding(ctx contractapi.TransactionContextInterface, pck string, rounding string) error {
+	if rounding != "" {
+		if err := validRounding(rounding); err != nil {
+			return err
+		}
+	}
+
+	service, err := s.IsService(ctx, pck)
+	if err != nil {
+		return err
+	}
+	if service.Type != "S" {
+		return fmt.Errorf("%s is not a Service", pck)
+	}
+	if err := authorizeServiceOwner(ctx, service); err != nil {
+		return err
+	}
+
+	service.Rounding = rounding
+
+	serviceAsBytes, _ := json.Marshal(service)
+
+	return ctx.GetStub().PutState(pck, serviceAsBytes)
+}
+
+//SetExchangeRate records how many units of to one unit of from is worth, the inverse rate is used for
+//the opposite direction unless it has been set as well. Only a platform admin can set it
+func (s *SmartContract) SetExchangeRate(ctx contractapi.TransactionContextInterface, from string, to string, rate string) error {
+	if err := requirePlatformAdmin(ctx); err != nil {
+		return err
+	}
+	if err := validCurrency(from); err != nil {
+		return err
+	}
+	if err := validCurrency(to); err != nil {
+		return err
+	}
+	if from == to {
+		return fmt.Errorf("An exchange rate needs two different currencies")
+	}
+
+	temprate, err := parseDecimal(rate, rateDecimals)
+	if err != nil {
+		return err
+	}
+	if temprate <= 0 {
+		return fmt.Errorf("rate must be positive")
+	}
+
+	mspid, err := clientMSPID(ctx)
+	if err != nil {
+		return err
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("Failed to read the transaction timestamp. %s", err.Error())
+	}
+
+	exchangerate := ExchangeRate{From: from, To: to, Rate: temprate, Timestamp: timestamp.GetSeconds(), SetBy: mspid, Type: "XR"}
+
+	key, err := ctx.GetStub().CreateCompositeKey(rateObjectType, []string{from, to})
+	if err != nil {
+		return fmt.Errorf("Failed to create the exchange rate key. %s", err.Error())
+	}
+	rateAsBytes, _ := json.Marshal(exchangerate)
+
+	return ctx.GetStub().PutState(key, rateAsBytes)
+}
+
+//GetExchangeRate returns the rate from one currency to another, computed from the opposite rate when
+//only that one has been recorded
+func (s *SmartContract) GetExchangeRate(ctx contractapi.TransactionContextInterface, from string, to string) (*ExchangeRate, error) {
+	if from == to {
+		return &ExchangeRate{From: from, To: to, Rate: rateScale, Type: "XR"}, nil
+	}
+
+	read := func(from string, to string) (*ExchangeRate, error) {
+		key, err := ctx.GetStub().CreateCompositeKey(rateObjectType, []string{from, to})
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create the exchange rate key. %s", err.Error())
+		}
+		rateAsBytes, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+		}
+		if rateAsBytes == nil {
+			return nil, nil
+		}
+		exchangerate := new(ExchangeRate)
+		_ = json.Unmarshal(rateAsBytes, exchangerate)
+		return exchangerate, nil
+	}
+
+	exchangerate, err := read(from, to)
+	if err != nil || exchangerate != nil {
+		return exchangerate, err
+	}
+
+	inverse, err := read(to, from)
+	if err != nil {
+		return nil, err
+	}
+	if inverse == nil {
+		return nil, fmt.Errorf("There is no exchange rate from %s to %s", from, to)
+	}
+
+	inverse.From, inverse.To = from, to
+	inverse.Rate = rateScale * rateScale / inverse.Rate
+
+	return inverse, nil
+}
+
+//--------------------------------------End Of Money Management--------------------------------------
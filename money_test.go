@@ -0,0 +1,19 @@
+package main
+
+import "testing"
+
+func TestOnlyAPlatformAdminSetsCurrenciesAndRates(t *testing.T) {
+	l := initLedger(t)
+	l.as("Org2MSP", certificate(t, "user2", false, ""))
+	l.refused("a currency set by a member", l.SetCurrency("JPY", 0, "half-even"))
+	l.refused("an exchange rate set by a member", l.SetExchangeRate("EUR", "USD", "1.08"))
+
+	l.asAdmin()
+	l.ok("SetCurrency", l.SetCurrency("JPY", 0, "half-even"))
+	l.ok("SetExchangeRate", l.SetExchangeRate("EUR", "JPY", "161.5"))
+	rate, err := l.GetExchangeRate("EUR", "JPY")
+	l.ok("GetExchangeRate", err)
+	if rate.Rate != 161500000000 {
+		t.Fatalf("the rate is %+v", rate)
+	}
+}
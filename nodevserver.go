@@ -0,0 +1,14 @@
+//go:build !devserver
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+//serveREST is only available in chaincode built with the devserver tag, see devserver.go
+func serveREST(addr string, chaincode *contractapi.ContractChaincode) error {
+	return fmt.Errorf("TENANT_REST_ADDR is set but the chaincode was built without the devserver tag")
+}
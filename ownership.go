@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+//***************************************************************************************************
+//**																							   **
+//**							The following section Manages the Service Ownership                **
+//**                                                                                               **
+//***************************************************************************************************
+
+//-------------------------------------Service Ownership---------------------------------------------
+//every Service is owned by a tenant and by the organization (MSP) that registered it for that tenant.
+//Only identities of the owning organization, or the operators the role registry names for the service,
+//can unregister the service, grant Delegations from it or
+//change its price. Ownership moves in two steps, the current owner names the new owner and the
+//organization of the new owner accepts, which records its MSP as the new owning organization
+
+//authorizeServiceOwner checks that the submitting identity belongs to the organization that owns the
+//service or is an operator of it, services registered before ownership existed have no owner and stay
+//open to everyone
+func authorizeServiceOwner(ctx contractapi.TransactionContextInterface, service *Service) error {
+	if service.OwnerMSP == "" {
+		return nil
+	}
+
+	mspid, err := clientMSPID(ctx)
+	if err != nil {
+		return err
+	}
+	if mspid == service.OwnerMSP {
+		return nil
+	}
+
+	operator, err := hasRole(ctx, roleServiceOperator, service.Pck)
+	if err != nil {
+		return err
+	}
+	if !operator {
+		return fmt.Errorf("%s is not authorized to manage %s, only the owner %s of %s is", mspid, service.Pck, service.Owner, service.OwnerMSP)
+	}
+
+	return nil
+}
+
+//registeredTenant checks that pck is a registered tenant which can own a service
+func (s *SmartContract) registeredTenant(ctx contractapi.TransactionContextInterface, pck string) error {
+	tenant, err := s.IsTenant(ctx, pck)
+	if err != nil {
+		return err
+	}
+	if tenant.Type != "T" || tenant.Registered == false {
+		return fmt.Errorf("%s must be a registered Tenant", pck)
+	}
+	return nil
+}
+
+//TransferServiceOwnership names newowner as the next owner of the service with given Pck (Key), the
+//service keeps its current owner until the organization of newowner accepts
+func (s *SmartContract) TransferServiceOwnership(ctx contractapi.TransactionContextInterface, pck string, newowner string) error {
+	service, err := s.IsService(ctx, pck)
+	if err != nil {
+		return err
+	}
+	if service.Type != "S" {
+		return fmt.Errorf("%s is not a Service", pck)
+	}
+
+	if err := authorizeServiceOwner(ctx, service); err != nil {
+		return err
+	}
+	if newowner == service.Owner {
+		return fmt.Errorf("%s already owns %s", newowner, pck)
+	}
+	if err := s.registeredTenant(ctx, newowner); err != nil {
+		return err
+	}
+
+	service.PendingOwner = newowner
+
+	serviceAsBytes, _ := json.Marshal(service)
+
+	return ctx.GetStub().PutState(pck, serviceAsBytes)
+}
+
+//AcceptServiceOwnership completes the transfer of the service with given Pck (Key) on behalf of the new
+//owner, the submitting organization becomes the owning organization of the service through one of its admins
+func (s *SmartContract) AcceptServiceOwnership(ctx contractapi.TransactionContextInterface, pck string) error {
+	service, err := s.IsService(ctx, pck)
+	if err != nil {
+		return err
+	}
+	if service.PendingOwner == "" {
+		return fmt.Errorf("%s has no pending ownership transfer", pck)
+	}
+
+	//the new owner may have been destroyed since the transfer was started
+	if err := s.registeredTenant(ctx, service.PendingOwner); err != nil {
+		return err
+	}
+
+	//only the new owner can accept, and only an admin can commit its organization to the service
+	if err := s.authorizeTenant(ctx, service.PendingOwner); err != nil {
+		return err
+	}
+	mspid, err := clientMSPID(ctx)
+	if err != nil {
+		return err
+	}
+	if err := requireAdmin(ctx, mspid); err != nil {
+		return err
+	}
+
+	//the new owner pays for the grants the service receives from now on
+	received, err := s.indexedGrants(ctx, recipientIndex, pck)
+	if err != nil {
+		return err
+	}
+	for _, grant := range received {
+		if err := moveGrantIndex(ctx, payerIndex, service.Owner, service.PendingOwner, grant.Pck); err != nil {
+			return err
+		}
+	}
+
+	service.Owner = service.PendingOwner
+	service.OwnerMSP = mspid
+	service.PendingOwner = ""
+
+	serviceAsBytes, _ := json.Marshal(service)
+
+	return ctx.GetStub().PutState(pck, serviceAsBytes)
+}
+
+//SetServicePrice changes the price per core and hour of the service with given Pck (Key), the price is
+//a decimal amount with an optional currency such as "0.35 USD", without one the service keeps its currency
+func (s *SmartContract) SetServicePrice(ctx contractapi.TransactionContextInterface, pck string, price string) error {
+	service, err := s.IsService(ctx, pck)
+	if err != nil {
+		return err
+	}
+	if service.Type != "S" {
+		return fmt.Errorf("%s is not a Service", pck)
+	}
+
+	if err := authorizeServiceOwner(ctx, service); err != nil {
+		return err
+	}
+
+	newprice, err := parseMoney(price, servicePrice(service).Currency)
+	if err != nil {
+		return err
+	}
+	if newprice.Amount <= 0 {
+		return fmt.Errorf("price must be positive")
+	}
+
+	service.Price = newprice
+
+	serviceAsBytes, _ := json.Marshal(service)
+
+	return ctx.GetStub().PutState(pck, serviceAsBytes)
+}
+
+//SetServiceStoragePrice changes the price per GB and hour of the storage the grants of the service with
+//given Pck (Key) use, a decimal amount with an optional currency, 0 makes the storage free
+func (s *SmartContract) SetServiceStoragePrice(ctx contractapi.TransactionContextInterface, pck string, price string) error {
+	service, err := s.IsService(ctx, pck)
+	if err != nil {
+		return err
+	}
+	if service.Type != "S" {
+		return fmt.Errorf("%s is not a Service", pck)
+	}
+
+	if err := authorizeServiceOwner(ctx, service); err != nil {
+		return err
+	}
+
+	newprice, err := parseMoney(price, servicePrice(service).Currency)
+	if err != nil {
+		return err
+	}
+	if newprice.Amount < 0 {
+		return fmt.Errorf("price must not be negative")
+	}
+
+	service.StoragePrice = newprice
+
+	serviceAsBytes, _ := json.Marshal(service)
+
+	return ctx.GetStub().PutState(pck, serviceAsBytes)
+}
+
+//--------------------------------------End Of Service Ownership-------------------------------------
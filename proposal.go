@@ -0,0 +1,487 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+//***************************************************************************************************
+//**																							   **
+//**							The following section Manages the Delegation Proposals             **
+//**                                                                                               **
+//***************************************************************************************************
+
+//-------------------------------------Delegation Proposals------------------------------------------
+//a proposal is a Delegation that only takes effect once the parties agree to it. The organization that
+//owns the grandor proposes it, the organization that owns the recipient service accepts it and, when a
+//platform admin has named one for the grandor, the countersigning organization countersigns it. The
+//Delegation is created by the last of these steps with the pck of the proposal, once the organization
+//that proposed it still owns the grandor. A proposal not agreed to within its validity expires. A
+//service can set an approval threshold, Delegations of more cores than it can then only be proposed.
+//Every step emits the proposal as event
+
+//object types of the composite keys of the proposals and of the countersigners of the services
+const (
+	proposalObjectType      = "proposal"
+	countersignerObjectType = "countersigner"
+)
+
+//proposalValidity is how long a proposal stays open when no validity is given
+const proposalValidity = 7 * 24 * 60 * 60
+
+//Proposal describes a Delegation waiting to be agreed to
+type Proposal struct {
+	Pck             string `json:"pck"`             //pck the Delegation is created with
+	Grandor         string `json:"grandor"`         //
+	Recipient       string `json:"recipient"`       //
+	Subdel          string `json:"subdel"`          //arguments of the Delegation as RegisterDelegation takes them
+	Issue           string `json:"issue"`           //
+	Expiry          string `json:"expiry"`          //
+	Scope           string `json:"scope"`           //
+	Proposer        string `json:"proposer"`        //organization that proposed the Delegation
+	GrandorOwner    string `json:"grandorowner"`    //organization that owned the grandor when it was proposed
+	Countersigner   string `json:"countersigner"`   //organization that must countersign, empty when none has to
+	AcceptedBy      string `json:"acceptedby"`      //organization that accepted for the recipient, empty until then
+	CountersignedBy string `json:"countersignedby"` //empty until countersigned
+	ProposedAt      int64  `json:"proposedat"`      //Unix seconds of the transaction that proposed the Delegation
+	ExpiresAt       int64  `json:"expiresat"`       //Unix seconds the proposal must be agreed to by
+	Status          string `json:"status"`          //open, active, declined, withdrawn or expired
+	Type            string `json:"Type"`            //PR for Proposal
+}
+
+//proposalKey returns the key of the proposal of a Delegation
+func proposalKey(ctx contractapi.TransactionContextInterface, pck string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(proposalObjectType, []string{pck})
+	if err != nil {
+		return "", fmt.Errorf("Failed to create the proposal key. %s", err.Error())
+	}
+	return key, nil
+}
+
+//putProposal stores a proposal and emits it as event
+func putProposal(ctx contractapi.TransactionContextInterface, proposal *Proposal, event string) error {
+	key, err := proposalKey(ctx, proposal.Pck)
+	if err != nil {
+		return err
+	}
+
+	proposalAsBytes, _ := json.Marshal(proposal)
+	if err := ctx.GetStub().PutState(key, proposalAsBytes); err != nil {
+		return err
+	}
+
+	return ctx.GetStub().SetEvent(event, proposalAsBytes)
+}
+
+//requireApproval refuses a Delegation registered directly when it grants more cores than the approval
+//threshold of its service, a scope without a core limit grants them all
+func requireApproval(service *Service, scope string) error {
+	if service.ApprovalCores == 0 {
+		return nil
+	}
+
+	delegationscope, err := parseScope(scope)
+	if err != nil {
+		return err
+	}
+	if delegationscope.MaxCores == 0 || delegationscope.MaxCores > service.ApprovalCores {
+		return fmt.Errorf("Delegations of more than %d cores from %s must be proposed and accepted by the recipient", service.ApprovalCores, service.Pck)
+	}
+
+	return nil
+}
+
+//SetApprovalThreshold sets the most cores a Delegation from the Service with given Pck (Key) can grant
+//without being proposed and accepted, 0 lets every Delegation be registered directly
+func (s *SmartContract) SetApprovalThreshold(ctx contractapi.TransactionContextInterface, pck string, cores string) error {
+	threshold, err := strconv.ParseUint(cores, 10, 64)
+	if err != nil {
+		return fmt.Errorf("cores must be a number")
+	}
+
+	service, err := s.IsService(ctx, pck)
+	if err != nil {
+		return err
+	}
+	if service.Type != "S" {
+		return fmt.Errorf("%s is not a Service", pck)
+	}
+	if err := authorizeServiceOwner(ctx, service); err != nil {
+		return err
+	}
+
+	service.ApprovalCores = threshold
+
+	serviceAsBytes, _ := json.Marshal(service)
+
+	return ctx.GetStub().PutState(pck, serviceAsBytes)
+}
+
+//countersignerKey returns the key of the countersigning organization of a service
+func countersignerKey(ctx contractapi.TransactionContextInterface, pck string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(countersignerObjectType, []string{pck})
+	if err != nil {
+		return "", fmt.Errorf("Failed to create the countersigner key. %s", err.Error())
+	}
+	return key, nil
+}
+
+//SetCountersigner names the organization mspid that must countersign the Delegations proposed from the
+//Service with given Pck (Key), an empty mspid lets them be created without. Only a platform admin can
+//set it, proposals already made keep the countersigner they were made with
+func (s *SmartContract) SetCountersigner(ctx contractapi.TransactionContextInterface, pck string, mspid string) error {
+	if err := requirePlatformAdmin(ctx); err != nil {
+		return err
+	}
+
+	service, err := s.IsService(ctx, pck)
+	if err != nil {
+		return err
+	}
+	if service.Type != "S" {
+		return fmt.Errorf("%s is not a Service", pck)
+	}
+
+	key, err := countersignerKey(ctx, pck)
+	if err != nil {
+		return err
+	}
+	if mspid == "" {
+		return ctx.GetStub().DelState(key)
+	}
+
+	return ctx.GetStub().PutState(key, []byte(mspid))
+}
+
+//GetCountersigner returns the organization that must countersign the Delegations proposed from the
+//Service with given Pck (Key), empty when none has to
+func (s *SmartContract) GetCountersigner(ctx contractapi.TransactionContextInterface, pck string) (string, error) {
+	key, err := countersignerKey(ctx, pck)
+	if err != nil {
+		return "", err
+	}
+	mspidAsBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return "", fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+
+	return string(mspidAsBytes), nil
+}
+
+//GetProposal returns the proposal of the Delegation with given Pck (Key)
+func (s *SmartContract) GetProposal(ctx contractapi.TransactionContextInterface, pck string) (*Proposal, error) {
+	key, err := proposalKey(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+	proposalAsBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if proposalAsBytes == nil {
+		return nil, fmt.Errorf("No Delegation has been proposed as %s", pck)
+	}
+
+	proposal := new(Proposal)
+	_ = json.Unmarshal(proposalAsBytes, proposal)
+
+	return proposal, nil
+}
+
+//GetProposals returns every proposal, open or not
+func (s *SmartContract) GetProposals(ctx contractapi.TransactionContextInterface) ([]*Proposal, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(proposalObjectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	defer iterator.Close()
+
+	proposals := []*Proposal{}
+	for iterator.HasNext() {
+		response, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+		}
+
+		proposal := new(Proposal)
+		_ = json.Unmarshal(response.Value, proposal)
+
+		proposals = append(proposals, proposal)
+	}
+
+	return proposals, nil
+}
+
+//openProposal returns the proposal of a Delegation which can still be agreed to
+func (s *SmartContract) openProposal(ctx contractapi.TransactionContextInterface, pck string) (*Proposal, error) {
+	proposal, err := s.GetProposal(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+	if proposal.Status != "open" {
+		return nil, fmt.Errorf("The proposal of %s is %s", pck, proposal.Status)
+	}
+
+	now, err := txSeconds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if proposal.ExpiresAt < now {
+		return nil, fmt.Errorf("The proposal of %s expired, it can only be marked as expired", pck)
+	}
+
+	return proposal, nil
+}
+
+//ProposeDelegation proposes a Delegation with the details RegisterDelegation takes. It is created once the
+//organization of the recipient accepts it and, when the grandor has a countersigner, that organization
+//countersigns it. validity is how many seconds the proposal stays open, the default when empty
+func (s *SmartContract) ProposeDelegation(ctx contractapi.TransactionContextInterface, pck string, grandor string, recipient string, subdel string, issue string, expiry string, scope string, validity string) (*Proposal, error) {
+	if err := unusedPck(ctx, pck); err != nil {
+		return nil, err
+	}
+	if previous, _ := s.GetProposal(ctx, pck); previous != nil && previous.Status == "open" {
+		return nil, fmt.Errorf("%s has already been proposed", pck)
+	}
+
+	var tempvalidity int64 = proposalValidity
+	var err error
+	if validity != "" {
+		if tempvalidity, err = strconv.ParseInt(validity, 10, 64); err != nil || tempvalidity <= 0 {
+			return nil, fmt.Errorf("validity must be a positive number of seconds")
+		}
+	}
+
+	service, err := s.IsService(ctx, grandor)
+	if err != nil {
+		return nil, err
+	}
+	recipientservice, err := s.IsService(ctx, recipient)
+	if err != nil {
+		return nil, err
+	}
+	if service.Type != "S" || !service.Registered || !recipientservice.Registered {
+		return nil, fmt.Errorf("Grandor Or Reciepient Error")
+	}
+	if grandor == recipient {
+		return nil, fmt.Errorf("Cannot Self-Delegate")
+	}
+	if err := checkGrantRule(ctx, "D", service.Type, recipientservice.Type); err != nil {
+		return nil, err
+	}
+	if _, err := parseScope(scope); err != nil {
+		return nil, err
+	}
+	if err := authorizeServiceOwner(ctx, service); err != nil {
+		return nil, err
+	}
+
+	proposer, err := clientMSPID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	countersigner, err := s.GetCountersigner(ctx, grandor)
+	if err != nil {
+		return nil, err
+	}
+	now, err := txSeconds(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal := &Proposal{
+		Pck:           pck,
+		Grandor:       grandor,
+		Recipient:     recipient,
+		Subdel:        subdel,
+		Issue:         issue,
+		Expiry:        expiry,
+		Scope:         scope,
+		Proposer:      proposer,
+		GrandorOwner:  service.OwnerMSP,
+		Countersigner: countersigner,
+		ProposedAt:    now,
+		ExpiresAt:     now + tempvalidity,
+		Status:        "open",
+		Type:          "PR",
+	}
+
+	if err := putProposal(ctx, proposal, "DelegationProposed"); err != nil {
+		return nil, err
+	}
+
+	return proposal, nil
+}
+
+//activateProposal creates the Delegation of a proposal once every party has agreed to it, event is
+//emitted when it still waits for one
+func (s *SmartContract) activateProposal(ctx contractapi.TransactionContextInterface, proposal *Proposal, event string) error {
+	if proposal.AcceptedBy == "" || (proposal.Countersigner != "" && proposal.CountersignedBy == "") {
+		return putProposal(ctx, proposal, event)
+	}
+
+	//the ownership of the grandor may have changed since it was proposed
+	service, err := s.IsService(ctx, proposal.Grandor)
+	if err != nil {
+		return err
+	}
+	if !service.Registered || service.OwnerMSP != proposal.GrandorOwner {
+		return fmt.Errorf("%s is no longer registered or no longer owned by %s, which owned it when %s was proposed", proposal.Grandor, proposal.GrandorOwner, proposal.Pck)
+	}
+
+	//the recipient agreed to it with the proposal, the Delegation is not pending
+	if err := s.createDelegation(ctx, proposal.Pck, proposal.Grandor, proposal.Recipient, proposal.Subdel, proposal.Issue, proposal.Expiry, proposal.Scope, false); err != nil {
+		return err
+	}
+	proposal.Status = "active"
+
+	return putProposal(ctx, proposal, "DelegationActivated")
+}
+
+//AcceptProposal accepts the proposal of the Delegation with given Pck (Key) on behalf of the recipient,
+//it must be submitted by the organization that owns the recipient service, or for a tenant or group
+//recipient by an identity that can act for it
+func (s *SmartContract) AcceptProposal(ctx contractapi.TransactionContextInterface, pck string) (*Proposal, error) {
+	proposal, err := s.openProposal(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+	if proposal.AcceptedBy != "" {
+		return nil, fmt.Errorf("The proposal of %s has already been accepted", pck)
+	}
+	//another record may have been stored at the pck since it was proposed
+	if err := unusedPck(ctx, pck); err != nil {
+		return nil, err
+	}
+
+	if err := s.authorizeParty(ctx, proposal.Recipient); err != nil {
+		return nil, err
+	}
+
+	if proposal.AcceptedBy, err = clientMSPID(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.activateProposal(ctx, proposal, "ProposalAccepted"); err != nil {
+		return nil, err
+	}
+
+	return proposal, nil
+}
+
+//CountersignProposal countersigns the proposal of the Delegation with given Pck (Key), it must be
+//submitted by the countersigning organization the proposal names
+func (s *SmartContract) CountersignProposal(ctx contractapi.TransactionContextInterface, pck string) (*Proposal, error) {
+	proposal, err := s.openProposal(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+	if proposal.Countersigner == "" {
+		return nil, fmt.Errorf("The proposal of %s does not need to be countersigned", pck)
+	}
+	if proposal.CountersignedBy != "" {
+		return nil, fmt.Errorf("The proposal of %s has already been countersigned", pck)
+	}
+	if err := unusedPck(ctx, pck); err != nil {
+		return nil, err
+	}
+
+	mspid, err := clientMSPID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if mspid != proposal.Countersigner {
+		return nil, fmt.Errorf("%s is not authorized to countersign %s, only %s is", mspid, pck, proposal.Countersigner)
+	}
+
+	proposal.CountersignedBy = mspid
+	if err := s.activateProposal(ctx, proposal, "ProposalCountersigned"); err != nil {
+		return nil, err
+	}
+
+	return proposal, nil
+}
+
+//DeclineProposal declines the proposal of the Delegation with given Pck (Key), it must be submitted by
+//the recipient, as AcceptProposal, or by the countersigning organization
+func (s *SmartContract) DeclineProposal(ctx contractapi.TransactionContextInterface, pck string) (*Proposal, error) {
+	proposal, err := s.openProposal(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+
+	mspid, err := clientMSPID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if proposal.Countersigner == "" || mspid != proposal.Countersigner {
+		if err := s.authorizeParty(ctx, proposal.Recipient); err != nil {
+			return nil, err
+		}
+	}
+
+	proposal.Status = "declined"
+	if err := putProposal(ctx, proposal, "ProposalDeclined"); err != nil {
+		return nil, err
+	}
+
+	return proposal, nil
+}
+
+//WithdrawProposal withdraws the proposal of the Delegation with given Pck (Key), it must be submitted
+//by the organization that owns the grandor
+func (s *SmartContract) WithdrawProposal(ctx contractapi.TransactionContextInterface, pck string) (*Proposal, error) {
+	proposal, err := s.GetProposal(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+	if proposal.Status != "open" {
+		return nil, fmt.Errorf("The proposal of %s is %s", pck, proposal.Status)
+	}
+
+	service, err := s.IsService(ctx, proposal.Grandor)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeServiceOwner(ctx, service); err != nil {
+		return nil, err
+	}
+
+	proposal.Status = "withdrawn"
+	if err := putProposal(ctx, proposal, "ProposalWithdrawn"); err != nil {
+		return nil, err
+	}
+
+	return proposal, nil
+}
+
+//ExpireProposal closes the proposal of the Delegation with given Pck (Key) once it has not been agreed
+//to within its validity, anyone can submit it
+func (s *SmartContract) ExpireProposal(ctx contractapi.TransactionContextInterface, pck string) (*Proposal, error) {
+	proposal, err := s.GetProposal(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+	if proposal.Status != "open" {
+		return nil, fmt.Errorf("The proposal of %s is %s", pck, proposal.Status)
+	}
+
+	now, err := txSeconds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if proposal.ExpiresAt >= now {
+		return nil, fmt.Errorf("The proposal of %s is open until %d", pck, proposal.ExpiresAt)
+	}
+
+	proposal.Status = "expired"
+	if err := putProposal(ctx, proposal, "ProposalExpired"); err != nil {
+		return nil, err
+	}
+
+	return proposal, nil
+}
+
+//--------------------------------------End Of Delegation Proposals----------------------------------
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTheProposedPckMustStayFree(t *testing.T) {
+	l := initLedger(t)
+	now := time.Now()
+	_, err := l.ProposeDelegation("T1", "S1", "S2", 1, now.Add(-time.Hour), now.Add(time.Hour), nil, 0)
+	l.refused("a proposal at the pck of a tenant", err)
+	_, err = l.ProposeDelegation("X1", "S1", "S2", 1, now.Add(-time.Hour), now.Add(time.Hour), nil, 0)
+	l.ok("ProposeDelegation", err)
+
+	//the pck is taken while the proposal waits
+	l.ok("RegisterService", l.RegisterService("X1", "taken", "T2"))
+	_, err = l.AcceptProposal("X1")
+	l.refused("accepting a proposal whose pck was taken", err)
+	service, err := l.IsService("X1")
+	l.ok("IsService", err)
+	if service.Name != "taken" {
+		t.Fatalf("X1 was overwritten, %+v", service)
+	}
+}
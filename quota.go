@@ -0,0 +1,247 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+//***************************************************************************************************
+//**																							   **
+//**							The following section Manages the Resource Quotas                  **
+//**                                                                                               **
+//***************************************************************************************************
+
+//-------------------------------------Resource Quotas-----------------------------------------------
+//a Service declares the total cores, memory and storage it has and every Delegation it grants takes
+//the limits of its scope out of that total. In the same way the SubDelegations of a grant take their
+//limits out of the limits of the grant. As with the capacity ledger the allocated amounts are never
+//stored, they are recomputed from the grants that still hold their share, so expired, suspended and
+//revoked grants give their resources back on their own. A zero total means the resource is not limited
+
+//object type of the composite key that links a Service to the Delegations granted from it
+const serviceGrantObjectType = "servicegrant"
+
+//Resources is an amount of cores, memory in MB and storage in GB
+type Resources struct {
+	Cores   uint64 `json:"cores"`
+	Memory  uint64 `json:"memory"`
+	Storage uint64 `json:"storage"`
+}
+
+//Quota describes the total, allocated and free resources of a Service or of a grant
+type Quota struct {
+	Pck       string    `json:"pck"`       //pck of the Service, Delegation or SubDelegation
+	Total     Resources `json:"total"`     //declared by the Service or given by the scope of the grant
+	Allocated Resources `json:"allocated"` //computed on read, held by the active grants below
+	Free      Resources `json:"free"`      //computed on read, Total - Allocated for the limited resources
+	Type      string    `json:"Type"`      //Q for Quota
+}
+
+//scopeResources returns the limits of a scope as Resources
+func scopeResources(scope Scope) Resources {
+	return Resources{Cores: scope.MaxCores, Memory: scope.Memory, Storage: scope.Storage}
+}
+
+//add returns the sum of two amounts of resources
+func (r Resources) add(other Resources) Resources {
+	return Resources{Cores: r.Cores + other.Cores, Memory: r.Memory + other.Memory, Storage: r.Storage + other.Storage}
+}
+
+//freeResource returns how much of a limited resource is still free, an unlimited one has nothing to report
+func freeResource(total uint64, allocated uint64) uint64 {
+	if total > allocated {
+		return total - allocated
+	}
+	return 0
+}
+
+//checkResource checks that a request for a resource fits in what is free of a limited total
+func checkResource(pck string, name string, total uint64, allocated uint64, request uint64) error {
+	if total == 0 {
+		return nil
+	}
+	if request == 0 {
+		return fmt.Errorf("%s limits its %s, the grant must declare the %s it takes", pck, name, name)
+	}
+	if allocated+request > total {
+		return fmt.Errorf("%s has %d %s free, %d were requested", pck, freeResource(total, allocated), name, request)
+	}
+	return nil
+}
+
+//fits checks that request fits in the free resources of a quota
+func (q *Quota) fits(request Resources) error {
+	if err := checkResource(q.Pck, "cores", q.Total.Cores, q.Allocated.Cores, request.Cores); err != nil {
+		return err
+	}
+	if err := checkResource(q.Pck, "memory", q.Total.Memory, q.Allocated.Memory, request.Memory); err != nil {
+		return err
+	}
+	return checkResource(q.Pck, "storage", q.Total.Storage, q.Allocated.Storage, request.Storage)
+}
+
+//newQuota builds a quota from its total and allocated resources
+func newQuota(pck string, total Resources, allocated Resources) *Quota {
+	return &Quota{
+		Pck:       pck,
+		Total:     total,
+		Allocated: allocated,
+		Free: Resources{
+			Cores:   freeResource(total.Cores, allocated.Cores),
+			Memory:  freeResource(total.Memory, allocated.Memory),
+			Storage: freeResource(total.Storage, allocated.Storage),
+		},
+		Type: "Q",
+	}
+}
+
+//recordServiceGrant links a Delegation to the Service it was granted from
+func recordServiceGrant(ctx contractapi.TransactionContextInterface, service string, delegation string) error {
+	key, err := ctx.GetStub().CreateCompositeKey(serviceGrantObjectType, []string{service, delegation})
+	if err != nil {
+		return fmt.Errorf("Failed to create the service grant key. %s", err.Error())
+	}
+
+	//the key is all the index needs, the value only has to be non empty
+	return ctx.GetStub().PutState(key, []byte{0x00})
+}
+
+//serviceAllocated sums the scope limits of the Delegations of a Service that still hold their share
+func (s *SmartContract) serviceAllocated(ctx contractapi.TransactionContextInterface, service string) (Resources, error) {
+	var allocated Resources
+
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(serviceGrantObjectType, []string{service})
+	if err != nil {
+		return allocated, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	defer iterator.Close()
+
+	for iterator.HasNext() {
+		response, err := iterator.Next()
+		if err != nil {
+			return allocated, fmt.Errorf("Failed to read from world state. %s", err.Error())
+		}
+
+		_, keys, err := ctx.GetStub().SplitCompositeKey(response.Key)
+		if err != nil {
+			return allocated, fmt.Errorf("Failed to split the service grant key. %s", err.Error())
+		}
+
+		delegation, err := s.IsDelegation(ctx, keys[1])
+		if err != nil {
+			return allocated, err
+		}
+		active, err := s.holdsSlot(ctx, delegation.Pck)
+		if err != nil {
+			return allocated, err
+		}
+		if active {
+			allocated = allocated.add(scopeResources(delegation.Scope))
+		}
+	}
+
+	return allocated, nil
+}
+
+//grantAllocated sums the scope limits of the children of a grant that still hold their share
+func (s *SmartContract) grantAllocated(ctx contractapi.TransactionContextInterface, grant string) (Resources, error) {
+	var allocated Resources
+
+	allocations, err := s.GetAllocations(ctx, grant)
+	if err != nil {
+		return allocated, err
+	}
+
+	for _, allocation := range allocations {
+		if !allocation.Active {
+			continue
+		}
+		child, err := s.IsSubDelegation(ctx, allocation.Child)
+		if err != nil {
+			return allocated, err
+		}
+		allocated = allocated.add(scopeResources(child.Scope))
+	}
+
+	return allocated, nil
+}
+
+//GetQuota returns the total, allocated and free resources of the Service, Delegation or SubDelegation
+//with given Pck (Key)
+func (s *SmartContract) GetQuota(ctx contractapi.TransactionContextInterface, pck string) (*Quota, error) {
+	service, err := s.IsService(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+
+	if service.Type == "S" {
+		allocated, err := s.serviceAllocated(ctx, pck)
+		if err != nil {
+			return nil, err
+		}
+		return newQuota(pck, service.Quota, allocated), nil
+	}
+
+	delegation, err := s.IsDelegation(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+	if delegation.Type != "D" && delegation.Type != "SD" {
+		return nil, fmt.Errorf("%s is not a Service, Delegation or SubDelegation", pck)
+	}
+
+	allocated, err := s.grantAllocated(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+
+	return newQuota(pck, scopeResources(delegation.Scope), allocated), nil
+}
+
+//SetServiceQuota declares the total resources of the Service with given Pck (Key), 0 leaves a resource
+//unlimited and no total can be set below what the Delegations of the service already hold
+func (s *SmartContract) SetServiceQuota(ctx contractapi.TransactionContextInterface, pck string, cores string, memory string, storage string) error {
+	var total Resources
+	var err error
+
+	if total.Cores, err = strconv.ParseUint(cores, 10, 64); err != nil {
+		return fmt.Errorf("cores must be a number")
+	}
+	if total.Memory, err = strconv.ParseUint(memory, 10, 64); err != nil {
+		return fmt.Errorf("memory must be a number")
+	}
+	if total.Storage, err = strconv.ParseUint(storage, 10, 64); err != nil {
+		return fmt.Errorf("storage must be a number")
+	}
+
+	service, err := s.IsService(ctx, pck)
+	if err != nil {
+		return err
+	}
+	if service.Type != "S" {
+		return fmt.Errorf("%s is not a Service", pck)
+	}
+	if err := authorizeServiceOwner(ctx, service); err != nil {
+		return err
+	}
+
+	//delegations granted before the quota existed are linked to the service by IndexGrants
+	allocated, err := s.serviceAllocated(ctx, pck)
+	if err != nil {
+		return err
+	}
+	if (total.Cores != 0 && total.Cores < allocated.Cores) || (total.Memory != 0 && total.Memory < allocated.Memory) || (total.Storage != 0 && total.Storage < allocated.Storage) {
+		return fmt.Errorf("%s already has %d cores, %d memory and %d storage allocated", pck, allocated.Cores, allocated.Memory, allocated.Storage)
+	}
+
+	service.Quota = total
+
+	serviceAsBytes, _ := json.Marshal(service)
+
+	return ctx.GetStub().PutState(pck, serviceAsBytes)
+}
+
+//--------------------------------------End Of Resource Quotas---------------------------------------
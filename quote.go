@@ -0,0 +1,299 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+//***************************************************************************************************
+//**																							   **
+//**							The following section Manages the Quotes                           **
+//**                                                                                               **
+//***************************************************************************************************
+
+//-------------------------------------Quotes--------------------------------------------------------
+//a quote estimates what a prospective Delegation or SubDelegation costs at most, its cores used over
+//its whole window at the price of the service at the root of the chain, itemized per day. QuoteGrant
+//only evaluates it. IssueQuote stores it under the pck the grant will have and keeps it open for
+//quoteValidity, the grant created with that pck, the same grandor, window and cores locks the quote in
+//and from then on it is charged at the quoted price whatever the service charges later
+
+//object type of the composite key of the quotes
+const quoteObjectType = "quote"
+
+//quoteValidity is how long an issued quote can be locked into a grant
+const quoteValidity = 24 * 60 * 60
+
+//secondsPerDay is the length of the days a quote is itemized by
+const secondsPerDay = 24 * 60 * 60
+
+//QuoteItem is the estimated cost of a grant over one day of its window
+type QuoteItem struct {
+	From    uint64 `json:"from"`    //Unix seconds the item starts at
+	To      uint64 `json:"to"`      //Unix seconds the item ends at
+	Cores   uint64 `json:"cores"`   //cores quoted
+	Seconds uint64 `json:"seconds"` //To - From
+	Amount  Money  `json:"amount"`  //unrounded cost of the cores over the item
+}
+
+//Quote is the itemized estimate of a prospective grant
+type Quote struct {
+	Pck        string      `json:"pck"`        //pck of the grant the quote is issued for, empty for an estimate
+	Grandor    string      `json:"grandor"`    //Service of a Delegation or grant subdelegated by a SubDelegation
+	Service    string      `json:"service"`    //service at the root of the chain, its price is quoted
+	Cores      uint64      `json:"cores"`      //cores of the scope of the grant
+	Issue      uint64      `json:"issue"`      //
+	Expiry     uint64      `json:"expiry"`     //
+	Price      Money       `json:"price"`      //price per core and hour
+	Items      []QuoteItem `json:"items"`      //one per day of the window
+	Total      Money       `json:"total"`      //sum of the items, rounded as the service charges
+	ValidUntil int64       `json:"validuntil"` //Unix seconds an issued quote can be locked until, 0 for an estimate
+	Locked     bool        `json:"locked"`     //true once the grant has been created with the quote
+	Type       string      `json:"Type"`       //QT for Quote
+}
+
+//quoteKey returns the key of the quote issued for a grant
+func quoteKey(ctx contractapi.TransactionContextInterface, pck string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(quoteObjectType, []string{pck})
+	if err != nil {
+		return "", fmt.Errorf("Failed to create the quote key. %s", err.Error())
+	}
+	return key, nil
+}
+
+//findQuote returns the quote issued for a grant, nil if there is none
+func findQuote(ctx contractapi.TransactionContextInterface, pck string) (*Quote, error) {
+	key, err := quoteKey(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+	quoteAsBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if quoteAsBytes == nil {
+		return nil, nil
+	}
+
+	quote := new(Quote)
+	_ = json.Unmarshal(quoteAsBytes, quote)
+
+	return quote, nil
+}
+
+//putQuote stores the quote issued for a grant
+func putQuote(ctx contractapi.TransactionContextInterface, quote *Quote) error {
+	key, err := quoteKey(ctx, quote.Pck)
+	if err != nil {
+		return err
+	}
+
+	quoteAsBytes, _ := json.Marshal(quote)
+
+	return ctx.GetStub().PutState(key, quoteAsBytes)
+}
+
+//buildQuote estimates a grant from grandor, a Service for a Delegation or a grant for a SubDelegation
+func (s *SmartContract) buildQuote(ctx contractapi.TransactionContextInterface, grandor string, cores string, issue string, expiry string) (*Quote, error) {
+	quote := &Quote{Grandor: grandor, Items: []QuoteItem{}, Type: "QT"}
+
+	var err error
+	if quote.Cores, err = strconv.ParseUint(cores, 10, 64); err != nil || quote.Cores == 0 {
+		return nil, fmt.Errorf("cores must be a positive number")
+	}
+	if quote.Issue, err = strconv.ParseUint(issue, 10, 64); err != nil {
+		return nil, fmt.Errorf("issue must be a Unix time")
+	}
+	if quote.Expiry, err = strconv.ParseUint(expiry, 10, 64); err != nil {
+		return nil, fmt.Errorf("expiry must be a Unix time")
+	}
+	if quote.Issue >= quote.Expiry {
+		return nil, fmt.Errorf("Issue must be before the expiry")
+	}
+
+	entity, err := s.IsService(ctx, grandor)
+	if err != nil {
+		return nil, err
+	}
+
+	var service *Service
+	switch entity.Type {
+	case "S":
+		if !entity.Registered {
+			return nil, fmt.Errorf("%s is not registered", grandor)
+		}
+		service = entity
+		quote.Price = servicePrice(service)
+	case "D", "SD":
+		parent, err := s.IsDelegation(ctx, grandor)
+		if err != nil {
+			return nil, err
+		}
+		if quote.Issue < parent.Issue || quote.Expiry > parent.Expiry {
+			return nil, fmt.Errorf("The window must be within the validity of %s", grandor)
+		}
+		if service, err = s.rootService(ctx, parent); err != nil {
+			return nil, err
+		}
+		quote.Price = servicePrice(service)
+	default:
+		return nil, fmt.Errorf("%s is not a Service, Delegation or SubDelegation", grandor)
+	}
+	quote.Service = service.Pck
+
+	//the cores must fit in what the grandor has not granted yet
+	quota, err := s.GetQuota(ctx, grandor)
+	if err != nil {
+		return nil, err
+	}
+	if err := quota.fits(Resources{Cores: quote.Cores}); err != nil {
+		return nil, err
+	}
+
+	total := Money{Currency: quote.Price.Currency}
+	for from := quote.Issue; from < quote.Expiry; {
+		to := (from/secondsPerDay + 1) * secondsPerDay
+		if to > quote.Expiry {
+			to = quote.Expiry
+		}
+
+		amount, err := quote.Price.coreTime(quote.Cores, to-from)
+		if err != nil {
+			return nil, err
+		}
+		quote.Items = append(quote.Items, QuoteItem{From: from, To: to, Cores: quote.Cores, Seconds: to - from, Amount: amount})
+		total.Amount += amount.Amount
+
+		from = to
+	}
+
+	if quote.Total, err = s.roundCharge(ctx, total, service.Rounding); err != nil {
+		return nil, err
+	}
+
+	return quote, nil
+}
+
+//QuoteGrant estimates the most a grant of cores from grandor over issue to expiry costs, grandor is the
+//Service of a Delegation or the grant subdelegated by a SubDelegation. Nothing is stored
+func (s *SmartContract) QuoteGrant(ctx contractapi.TransactionContextInterface, grandor string, cores string, issue string, expiry string) (*Quote, error) {
+	return s.buildQuote(ctx, grandor, cores, issue, expiry)
+}
+
+//IssueQuote estimates a grant as QuoteGrant does and keeps the quote for the grant with given Pck (Key),
+//it is locked in when the grant is created within quoteValidity. Only the grandor can issue it, the
+//owner of the Service or the recipient of the grant subdelegated, and a quote is never replaced
+func (s *SmartContract) IssueQuote(ctx contractapi.TransactionContextInterface, pck string, grandor string, cores string, issue string, expiry string) (*Quote, error) {
+	if pck == "" {
+		return nil, fmt.Errorf("pck is required")
+	}
+	existing, err := ctx.GetStub().GetState(pck)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("%s already exists", pck)
+	}
+	issued, err := findQuote(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+	if issued != nil {
+		return nil, fmt.Errorf("A quote has already been issued for %s", pck)
+	}
+
+	quote, err := s.buildQuote(ctx, grandor, cores, issue, expiry)
+	if err != nil {
+		return nil, err
+	}
+
+	//a grant is subdelegated on behalf of its recipient
+	party := grandor
+	parent, err := s.IsDelegation(ctx, grandor)
+	if err != nil {
+		return nil, err
+	}
+	if parent.Type == "D" || parent.Type == "SD" {
+		party = parent.Recipient
+	}
+	if err := s.authorizeParty(ctx, party); err != nil {
+		return nil, err
+	}
+
+	now, err := txSeconds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	quote.Pck = pck
+	quote.ValidUntil = now + quoteValidity
+
+	if err := putQuote(ctx, quote); err != nil {
+		return nil, err
+	}
+
+	return quote, nil
+}
+
+//GetQuote returns the quote issued for the grant with given Pck (Key)
+func (s *SmartContract) GetQuote(ctx contractapi.TransactionContextInterface, pck string) (*Quote, error) {
+	quote, err := findQuote(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+	if quote == nil {
+		return nil, fmt.Errorf("No quote has been issued for %s", pck)
+	}
+	return quote, nil
+}
+
+//lockQuote locks the quote issued for a grant being created and returns the price the grant is charged
+//at, the price of its service when no quote was issued. A quote that expired or does not match the grant
+//refuses it
+func (s *SmartContract) lockQuote(ctx contractapi.TransactionContextInterface, pck string, grandor string, scope Scope, issue uint64, expiry uint64, service *Service) (Money, error) {
+	quote, err := findQuote(ctx, pck)
+	if err != nil {
+		return Money{}, err
+	}
+	if quote == nil {
+		return servicePrice(service), nil
+	}
+
+	if quote.Locked {
+		return Money{}, fmt.Errorf("The quote of %s has already been used", pck)
+	}
+	now, err := txSeconds(ctx)
+	if err != nil {
+		return Money{}, err
+	}
+	if quote.ValidUntil < now {
+		return Money{}, fmt.Errorf("The quote of %s expired, issue a new one", pck)
+	}
+	if quote.Grandor != grandor || quote.Issue != issue || quote.Expiry != expiry || quote.Cores != scope.MaxCores {
+		return Money{}, fmt.Errorf("%s does not match its quote of %d cores from %s over %d to %d", pck, quote.Cores, quote.Grandor, quote.Issue, quote.Expiry)
+	}
+
+	quote.Locked = true
+	if err := putQuote(ctx, quote); err != nil {
+		return Money{}, err
+	}
+
+	return quote.Price, nil
+}
+
+//grantPrice returns the price per core and hour a grant is charged at, the price of its locked quote or
+//else the current price of its service
+func (s *SmartContract) grantPrice(ctx contractapi.TransactionContextInterface, pck string, service *Service) (Money, error) {
+	quote, err := findQuote(ctx, pck)
+	if err != nil {
+		return Money{}, err
+	}
+	if quote != nil && quote.Locked {
+		return quote.Price, nil
+	}
+	return servicePrice(service), nil
+}
+
+//--------------------------------------End Of Quotes------------------------------------------------
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAnIssuedQuoteIsKept(t *testing.T) {
+	l := initLedger(t)
+	now := time.Now()
+	quote, err := l.IssueQuote("D1", "S1", 2, now.Add(time.Hour), now.Add(3*time.Hour))
+	l.ok("IssueQuote", err)
+
+	_, err = l.IssueQuote("D1", "S1", 1, now.Add(time.Hour), now.Add(2*time.Hour))
+	l.refused("a quote replacing an issued one", err)
+	l.as("Org2MSP", certificate(t, "outsider", false, ""))
+	_, err = l.IssueQuote("D2", "S1", 1, now.Add(time.Hour), now.Add(2*time.Hour))
+	l.refused("a quote by an outsider of the grandor", err)
+
+	l.asAdmin()
+	kept, err := l.GetQuote("D1")
+	l.ok("GetQuote", err)
+	if kept.Total != quote.Total {
+		t.Fatalf("the quote changed from %v to %v", quote.Total, kept.Total)
+	}
+}
@@ -0,0 +1,155 @@
+package rest
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//OpenAPI builds the OpenAPI 3 document of the server from its routes, the schemas are derived
+//from the JSON tags of the request and response types
+func (s *Server) OpenAPI() map[string]interface{} {
+	schemas := map[string]interface{}{
+		"Error": schemaOf(reflect.TypeOf(errorBody{}), nil),
+	}
+	paths := map[string]map[string]interface{}{}
+
+	for _, rt := range s.routes {
+		operation := map[string]interface{}{
+			"summary":     rt.summary,
+			"operationId": operationID(rt),
+			"responses":   responses(rt, schemas),
+		}
+
+		var parameters []interface{}
+		for _, part := range strings.Split(rt.pattern, "/") {
+			if strings.HasPrefix(part, "{") {
+				parameters = append(parameters, map[string]interface{}{
+					"name": strings.Trim(part, "{}"), "in": "path", "required": true,
+					"schema": map[string]interface{}{"type": "string"},
+				})
+			}
+		}
+		for _, param := range rt.query {
+			parameters = append(parameters, map[string]interface{}{
+				"name": param.name, "in": "query", "required": param.required,
+				"schema": map[string]interface{}{"type": param.kind},
+			})
+		}
+		if parameters != nil {
+			operation["parameters"] = parameters
+		}
+
+		if rt.body != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": schemaOf(reflect.TypeOf(rt.body), schemas)},
+				},
+			}
+		}
+
+		if paths[rt.pattern] == nil {
+			paths[rt.pattern] = map[string]interface{}{}
+		}
+		paths[rt.pattern][strings.ToLower(rt.method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Tenant Service Cloud Management",
+			"description": "REST resources mapped to the transactions of the tenant service chaincode",
+			"version":     "1.0.0",
+		},
+		"paths":      paths,
+		"components": map[string]interface{}{"schemas": schemas},
+	}
+}
+
+func operationID(rt route) string {
+	id := strings.ToLower(rt.method)
+	for _, part := range strings.Split(rt.pattern, "/") {
+		part = strings.Trim(part, "{}.")
+		if part != "" {
+			id += strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+	return id
+}
+
+func responses(rt route, schemas map[string]interface{}) map[string]interface{} {
+	errorResponse := map[string]interface{}{
+		"description": "the contract rejected the transaction",
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": map[string]interface{}{"$ref": "#/components/schemas/Error"}},
+		},
+	}
+
+	result := map[string]interface{}{"default": errorResponse}
+	if rt.response == nil {
+		result["204"] = map[string]interface{}{"description": "the transaction has been committed"}
+		return result
+	}
+
+	status := rt.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	result[strconv.Itoa(status)] = map[string]interface{}{
+		"description": http.StatusText(status),
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": schemaOf(reflect.TypeOf(rt.response), schemas)},
+		},
+	}
+	return result
+}
+
+//schemaOf returns the schema of a type, named structs are added to schemas and referenced
+func schemaOf(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return schemaOf(t.Elem(), schemas)
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": schemaOf(t.Elem(), schemas)}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint, reflect.Int, reflect.Int64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Struct:
+		//named structs are only described once, which also ends the recursion of self referencing types
+		ref := map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+		if _, ok := schemas[t.Name()]; ok {
+			return ref
+		}
+		if schemas != nil {
+			schemas[t.Name()] = nil
+		}
+
+		properties := map[string]interface{}{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if name == "" {
+				name = field.Name
+			}
+			properties[name] = schemaOf(field.Type, schemas)
+		}
+		schema := map[string]interface{}{"type": "object", "properties": properties}
+		if schemas == nil {
+			return schema
+		}
+		schemas[t.Name()] = schema
+		return ref
+	default:
+		return map[string]interface{}{}
+	}
+}
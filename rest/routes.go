@@ -0,0 +1,1277 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Kthanasis/Tenant-Service-Cloud-Management-using-Blockchain/client"
+)
+
+//RoleBody is the request body that assigns or revokes a role, identities are not path parameters as
+//they can contain slashes
+type RoleBody struct {
+	Identity string `json:"identity"`
+	MSP      string `json:"msp,omitempty"` //organization of the identity, only read on assignment
+	Role     string `json:"role"`
+	Scope    string `json:"scope,omitempty"` //service of a service-operator, tenant of a tenant role
+}
+
+//AccessPolicyBody is the request body that sets the access policy of a transaction
+type AccessPolicyBody struct {
+	Rules []client.AttributeRule `json:"rules"` //the certificate of the caller must satisfy one of them
+}
+
+//GrantRuleBody is the request body that allows or forbids a kind of grant between two entity types
+type GrantRuleBody struct {
+	Grant   string `json:"grant"` //D or SD
+	From    string `json:"from"`  //S, T or G
+	To      string `json:"to"`    //S, T or G
+	Allowed bool   `json:"allowed"`
+}
+
+//CascadePolicyBody is the request body that sets the cascade policy of a principal type
+type CascadePolicyBody struct {
+	Principal string `json:"principal"` //T, S or G
+	Mode      string `json:"mode"`      //revoke, suspend or block
+}
+
+//TenantBody is the request body that enrolls or updates a tenant, the email and phone are passed
+//to the contract through the transient map and are left unchanged on update when both are empty
+type TenantBody struct {
+	Pck   string `json:"pck,omitempty"` //only read on enrollment, the path gives it on update
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+	Phone string `json:"phone,omitempty"`
+}
+
+//contact returns the contact details of the body, nil if none were given
+func (body TenantBody) contact() *client.Contact {
+	if body.Email == "" && body.Phone == "" {
+		return nil
+	}
+	return &client.Contact{Email: body.Email, Phone: body.Phone}
+}
+
+//EraseBody is the request body that erases a tenant
+type EraseBody struct {
+	Successor string `json:"successor,omitempty"` //tenant that receives the grants, they are revoked if empty
+}
+
+//WalletBody is the request body that opens the wallet of a tenant
+type WalletBody struct {
+	Currency string `json:"currency"`
+}
+
+//AmountBody is the request body that deposits, debits or holds an amount of a wallet
+type AmountBody struct {
+	Amount    string `json:"amount"`              //decimal amount, e.g. 50.00
+	Currency  string `json:"currency,omitempty"`  //the currency of the wallet when omitted
+	Reference string `json:"reference,omitempty"` //payment or invoice, required for debits
+	Reason    string `json:"reason,omitempty"`    //only read for holds
+}
+
+//text returns the amount in the form the contract reads
+func (body AmountBody) text() string {
+	if body.Currency == "" {
+		return body.Amount
+	}
+	return body.Amount + " " + body.Currency
+}
+
+//CreditLimitBody is the request body that sets the credit limit of a wallet
+type CreditLimitBody struct {
+	Limit string `json:"limit"` //decimal amount in the currency of the wallet
+}
+
+//InvoiceBody is the request body that issues an invoice to a tenant
+type InvoiceBody struct {
+	InvoiceID string    `json:"invoiceid"`
+	Amount    string    `json:"amount"`             //decimal amount, e.g. 40.00
+	Currency  string    `json:"currency,omitempty"` //the currency of the wallet of the tenant when omitted
+	Due       time.Time `json:"due"`
+}
+
+//PaymentBody is the request body that records the payment of an invoice
+type PaymentBody struct {
+	Reference string `json:"reference,omitempty"` //payment made outside the ledger, for tenants without a wallet
+}
+
+//DunningBody is the request body that runs the dunning of a tenant
+type DunningBody struct {
+	Grace string `json:"grace"` //Go duration after the due date an unpaid invoice is overdue, e.g. 168h
+}
+
+//QuoteBody is the request body that issues a quote for a prospective grant
+type QuoteBody struct {
+	Pck     string    `json:"pck"`     //pck the grant will be created with
+	Grandor string    `json:"grandor"` //Service of a Delegation or grant subdelegated by a SubDelegation
+	Cores   uint64    `json:"cores"`
+	Issue   time.Time `json:"issue"`
+	Expiry  time.Time `json:"expiry"`
+}
+
+//OrganizationBody is the request body that registers an organization
+type OrganizationBody struct {
+	Pck   string `json:"pck"`
+	Name  string `json:"name"`
+	Owner string `json:"owner"` //tenant that manages the organization
+}
+
+//GroupBody is the request body that registers a group of an organization
+type GroupBody struct {
+	Pck          string `json:"pck"`
+	Name         string `json:"name"`
+	Organization string `json:"organization"`
+}
+
+//MemberBody is the request body that adds a tenant to an organization or group
+type MemberBody struct {
+	Tenant string `json:"tenant"`
+}
+
+//ServiceBody is the request body that registers a service
+type ServiceBody struct {
+	Pck   string `json:"pck"`
+	Name  string `json:"name"`
+	Owner string `json:"owner"` //tenant that owns the service
+}
+
+//OwnershipBody is the request body that transfers the ownership of a service
+type OwnershipBody struct {
+	NewOwner string `json:"newowner"`
+}
+
+//QuotaBody is the request body that declares the total resources of a service
+type QuotaBody struct {
+	Cores   uint64 `json:"cores"`
+	Memory  uint64 `json:"memory"`  //MB
+	Storage uint64 `json:"storage"` //GB
+}
+
+//PriceBody is the request body that changes the price of a service
+type PriceBody struct {
+	Price        string `json:"price"`                  //decimal amount per core and hour, e.g. 0.35
+	StoragePrice string `json:"storageprice,omitempty"` //decimal amount per GB and hour, 0 makes the storage free
+	Currency     string `json:"currency,omitempty"`     //the service keeps its currency when omitted
+	Rounding     string `json:"rounding,omitempty"`     //half-even, half-up, down or up, left unchanged when omitted
+}
+
+//CurrencyBody is the request body that sets the decimals and rounding rule of a currency
+type CurrencyBody struct {
+	Decimals uint8  `json:"decimals"`
+	Rounding string `json:"rounding"`
+}
+
+//RateBody is the request body that records an exchange rate
+type RateBody struct {
+	Rate string `json:"rate"` //decimal units of the target currency for one unit of the source currency
+}
+
+//DelegationBody is the request body that creates a Delegation
+type DelegationBody struct {
+	Pck       string        `json:"pck"`
+	Grandor   string        `json:"grandor"`
+	Recipient string        `json:"recipient"`
+	Subdel    uint8         `json:"subdel"`
+	Issue     time.Time     `json:"issue"`
+	Expiry    time.Time     `json:"expiry"`
+	Scope     *client.Scope `json:"scope,omitempty"` //everything is granted when omitted
+}
+
+//ProposalBody is the request body that proposes a Delegation
+type ProposalBody struct {
+	Pck       string        `json:"pck"`
+	Grandor   string        `json:"grandor"`
+	Recipient string        `json:"recipient"`
+	Subdel    uint8         `json:"subdel"`
+	Issue     time.Time     `json:"issue"`
+	Expiry    time.Time     `json:"expiry"`
+	Scope     *client.Scope `json:"scope,omitempty"`    //everything is granted when omitted
+	Validity  string        `json:"validity,omitempty"` //Go duration the proposal stays open, e.g. 72h, the contract default when omitted
+}
+
+//CountersignerBody is the countersigning organization of a service, as request and response body
+type CountersignerBody struct {
+	MSP string `json:"msp"` //organization that must countersign the proposals, none when empty
+}
+
+//ApprovalBody is the request body that sets the approval threshold of a service
+type ApprovalBody struct {
+	Cores uint64 `json:"cores"` //Delegations of more cores must be proposed and accepted, 0 never
+}
+
+//SubDelegationBody is the request body that creates a SubDelegation
+type SubDelegationBody struct {
+	Pck       string        `json:"pck"`
+	Parent    string        `json:"parent"` //the Delegation or SubDelegation that is subdelegated
+	Recipient string        `json:"recipient"`
+	Subdel    uint8         `json:"subdel"`
+	Issue     time.Time     `json:"issue"`
+	Expiry    time.Time     `json:"expiry"`
+	Scope     *client.Scope `json:"scope,omitempty"` //the scope of the parent is inherited when omitted
+}
+
+//RevokeBody is the request body that revokes a grant
+type RevokeBody struct {
+	Revoker string `json:"revoker"`
+}
+
+//RevocationPolicyBody is the request body that sets the revocation policy of a grant
+type RevocationPolicyBody struct {
+	Rule      string    `json:"rule"`                //any-of, all-of, k-of-n or org-admin
+	Parties   []string  `json:"parties,omitempty"`   //revokers the rule counts, all of the revokers when omitted
+	K         uint8     `json:"k,omitempty"`         //signatures a k-of-n rule needs
+	NotBefore time.Time `json:"notbefore,omitempty"` //the grant cannot be revoked before, never locked when omitted
+}
+
+//policy converts the body to the policy the contract stores
+func (body RevocationPolicyBody) policy() client.RevocationPolicy {
+	policy := client.RevocationPolicy{Rule: body.Rule, Parties: body.Parties, K: body.K}
+	if !body.NotBefore.IsZero() {
+		policy.NotBefore = uint64(body.NotBefore.Unix())
+	}
+	return policy
+}
+
+//TransferBody is the request body that asks to move a grant to a new recipient
+type TransferBody struct {
+	Recipient string `json:"recipient"`
+}
+
+//ScheduleBody is the request body that sets the schedule of a grant
+type ScheduleBody struct {
+	UTCOffset int                     `json:"utcoffset,omitempty"` //minutes the time of the windows is ahead of UTC
+	Windows   []client.ScheduleWindow `json:"windows"`             //the grant is always active when empty
+}
+
+//schedule converts the body to the schedule the contract stores
+func (body ScheduleBody) schedule() client.Schedule {
+	return client.Schedule{UTCOffset: body.UTCOffset, Windows: body.Windows}
+}
+
+//Activation is when a grant is next active, omitted when it will not be active again
+type Activation struct {
+	Pck  string     `json:"pck"`
+	Next *time.Time `json:"next,omitempty"`
+}
+
+//CapacityBody is the request body that changes the capacity of a grant
+type CapacityBody struct {
+	MaxChildren uint8 `json:"maxchildren"`
+}
+
+//Status is the combined result of the status queries of a grant
+type Status struct {
+	Pck       string `json:"pck"`
+	Valid     bool   `json:"valid"`
+	Expired   bool   `json:"expired"`
+	Suspended bool   `json:"suspended"`
+	Revoked   bool   `json:"revoked"`
+}
+
+//ScopeCheck is the result of checking an operation and region against the scope of a grant
+type ScopeCheck struct {
+	Pck       string `json:"pck"`
+	Operation string `json:"operation"`
+	Region    string `json:"region"`
+	Allowed   bool   `json:"allowed"`
+}
+
+//MemberAccess is the result of checking if a grant gives a tenant access, directly or through a group
+type MemberAccess struct {
+	Pck     string `json:"pck"`
+	Tenant  string `json:"tenant"`
+	Allowed bool   `json:"allowed"`
+}
+
+//Charge is the cost of a Delegation computed from its recorded usage
+type Charge struct {
+	Pck  string       `json:"pck"`
+	Cost client.Money `json:"cost"`
+}
+
+//UsageBody is the request body that records a usage sample
+type UsageBody struct {
+	SampleID  string    `json:"sampleid"`
+	Timestamp time.Time `json:"timestamp"` //end of the sampled period
+	Cores     uint64    `json:"cores"`
+	Hours     uint64    `json:"hours"`
+	Storage   uint64    `json:"storage"` //GB
+}
+
+//resources lists every route of the server, the order only matters for the OpenAPI document
+func (s *Server) resources() []route {
+	b := s.backend
+
+	return []route{
+		{method: http.MethodPost, pattern: "/ledger/init", summary: "Adds the base set of tenants and services to the ledger, the first call bootstraps the role registry for an admin of the deploying organization adminmsp", query: []queryParam{{name: "adminmsp", kind: "string"}},
+			handle: func(r request) (interface{}, error) {
+				return nil, b.InitLedger(r.URL.Query().Get("adminmsp"))
+			}},
+		{method: http.MethodPost, pattern: "/ledger/index-grants", summary: "Indexes the grants stored before the grant index existed, by a platform admin",
+			handle: func(r request) (interface{}, error) {
+				return nil, b.IndexGrants()
+			}},
+
+		//------------------------------------------Roles-------------------------------------------
+		{method: http.MethodGet, pattern: "/whoami", summary: "Returns the identity the server submits with and the roles it holds", response: client.CallerIdentity{},
+			handle: func(r request) (interface{}, error) {
+				return b.WhoAmI()
+			}},
+		{method: http.MethodPost, pattern: "/roles", summary: "Assigns a role to a client identity", body: RoleBody{}, status: http.StatusCreated,
+			handle: func(r request) (interface{}, error) {
+				var body RoleBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				return nil, b.AssignRole(body.Identity, body.MSP, body.Role, body.Scope)
+			}},
+		{method: http.MethodGet, pattern: "/roles", summary: "Returns every assignment of the role registry, to auditors and admins", response: []client.RoleAssignment{},
+			handle: func(r request) (interface{}, error) {
+				return b.GetRoleAssignments()
+			}},
+		{method: http.MethodPost, pattern: "/roles/revoke", summary: "Revokes a role from a client identity", body: RoleBody{},
+			handle: func(r request) (interface{}, error) {
+				var body RoleBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				return nil, b.RevokeRole(body.Identity, body.Role, body.Scope)
+			}},
+		{method: http.MethodGet, pattern: "/roles/changes", summary: "Returns the role audit trail, to auditors and admins", response: []client.RoleChange{},
+			handle: func(r request) (interface{}, error) {
+				return b.GetRoleChanges()
+			}},
+		{method: http.MethodPut, pattern: "/access-policies/{transaction}", summary: "Sets the certificate attribute rules of a tenant or delegation transaction, no rules remove the policy", body: AccessPolicyBody{}, response: []client.AccessPolicy{},
+			handle: func(r request) (interface{}, error) {
+				var body AccessPolicyBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				if err := b.SetAccessPolicy(r.params["transaction"], body.Rules); err != nil {
+					return nil, err
+				}
+				return b.GetAccessPolicies()
+			}},
+		{method: http.MethodGet, pattern: "/access-policies", summary: "Returns the access policy of every transaction that has one", response: []client.AccessPolicy{},
+			handle: func(r request) (interface{}, error) {
+				return b.GetAccessPolicies()
+			}},
+		{method: http.MethodPut, pattern: "/grant-policy", summary: "Allows or forbids a kind of grant from one entity type to another", body: GrantRuleBody{}, response: []client.GrantRule{},
+			handle: func(r request) (interface{}, error) {
+				var body GrantRuleBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				if err := b.SetGrantRule(body.Grant, body.From, body.To, body.Allowed); err != nil {
+					return nil, err
+				}
+				return b.GetGrantPolicy()
+			}},
+		{method: http.MethodGet, pattern: "/grant-policy", summary: "Returns the grant policy matrix", response: []client.GrantRule{},
+			handle: func(r request) (interface{}, error) {
+				return b.GetGrantPolicy()
+			}},
+		{method: http.MethodPut, pattern: "/cascade-policy", summary: "Sets what happens to the active grants of a principal type when one is deregistered", body: CascadePolicyBody{}, response: []client.CascadePolicy{},
+			handle: func(r request) (interface{}, error) {
+				var body CascadePolicyBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				if err := b.SetCascadePolicy(body.Principal, body.Mode); err != nil {
+					return nil, err
+				}
+				return b.GetCascadePolicies()
+			}},
+		{method: http.MethodGet, pattern: "/cascade-policy", summary: "Returns the cascade policy of every principal type", response: []client.CascadePolicy{},
+			handle: func(r request) (interface{}, error) {
+				return b.GetCascadePolicies()
+			}},
+
+		//------------------------------------------Tenants-----------------------------------------
+		{method: http.MethodPost, pattern: "/tenants", summary: "Enrolls a tenant", body: TenantBody{}, response: client.Tenant{}, status: http.StatusCreated,
+			handle: func(r request) (interface{}, error) {
+				var body TenantBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				if body.Pck == "" {
+					return nil, badRequest("pck is required")
+				}
+				if err := b.Enroll(body.Pck, body.Name, body.contact()); err != nil {
+					return nil, err
+				}
+				return b.IsTenant(body.Pck)
+			}},
+		{method: http.MethodGet, pattern: "/tenants/{pck}", summary: "Returns a tenant", response: client.Tenant{},
+			handle: func(r request) (interface{}, error) {
+				return b.IsTenant(r.params["pck"])
+			}},
+		{method: http.MethodPut, pattern: "/tenants/{pck}", summary: "Updates the details of a tenant", body: TenantBody{}, response: client.Tenant{},
+			handle: func(r request) (interface{}, error) {
+				var body TenantBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				if err := b.Update(r.params["pck"], body.Name, body.contact()); err != nil {
+					return nil, err
+				}
+				return b.IsTenant(r.params["pck"])
+			}},
+		{method: http.MethodGet, pattern: "/tenants/{pck}/contact", summary: "Returns the private contact details of a tenant to authorized organizations", response: client.TenantContact{},
+			handle: func(r request) (interface{}, error) {
+				return b.GetTenantContact(r.params["pck"])
+			}},
+		{method: http.MethodDelete, pattern: "/tenants/{pck}", summary: "Destroys a tenant and applies the cascade policy of tenants to its grants",
+			handle: func(r request) (interface{}, error) {
+				return nil, b.DestroyTenant(r.params["pck"])
+			}},
+		{method: http.MethodGet, pattern: "/tenants/{pck}/dependent-grants", summary: "Returns the active grants a tenant is the grandor or recipient of, the ones its deregistration applies the cascade policy to", response: []client.Delegation{},
+			handle: func(r request) (interface{}, error) {
+				return b.GetDependentGrants(r.params["pck"])
+			}},
+		{method: http.MethodGet, pattern: "/tenants/{pck}/statement", summary: "Returns the charges of a tenant consolidated in one currency", query: []queryParam{{name: "currency", kind: "string", required: true}}, response: client.Statement{},
+			handle: func(r request) (interface{}, error) {
+				currency := r.URL.Query().Get("currency")
+				if currency == "" {
+					return nil, badRequest("currency is required")
+				}
+				return b.GetTenantStatement(r.params["pck"], currency)
+			}},
+		{method: http.MethodPost, pattern: "/tenants/{pck}/erase", summary: "Erases the personal data of a tenant and revokes or transfers its grants", body: EraseBody{}, response: client.ErasureReceipt{},
+			handle: func(r request) (interface{}, error) {
+				var body EraseBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				if err := b.EraseTenant(r.params["pck"], body.Successor); err != nil {
+					return nil, err
+				}
+				return b.GetErasureReceipt(r.params["pck"])
+			}},
+		{method: http.MethodGet, pattern: "/tenants/{pck}/erasure", summary: "Returns the erasure receipt of a tenant", response: client.ErasureReceipt{},
+			handle: func(r request) (interface{}, error) {
+				return b.GetErasureReceipt(r.params["pck"])
+			}},
+		{method: http.MethodPost, pattern: "/tenants/{pck}/wallet", summary: "Opens the prepaid wallet of a tenant", body: WalletBody{}, response: client.Wallet{}, status: http.StatusCreated,
+			handle: func(r request) (interface{}, error) {
+				var body WalletBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				if err := b.OpenWallet(r.params["pck"], body.Currency); err != nil {
+					return nil, err
+				}
+				return b.GetWallet(r.params["pck"])
+			}},
+		{method: http.MethodGet, pattern: "/tenants/{pck}/wallet", summary: "Returns the balance, credit limit, held and available amounts of a tenant", response: client.Wallet{},
+			handle: func(r request) (interface{}, error) {
+				return b.GetWallet(r.params["pck"])
+			}},
+		{method: http.MethodPost, pattern: "/tenants/{pck}/wallet/deposits", summary: "Pays an amount into the wallet of a tenant", body: AmountBody{}, response: client.Wallet{},
+			handle: func(r request) (interface{}, error) {
+				var body AmountBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				return b.Deposit(r.params["pck"], body.text(), body.Reference)
+			}},
+		{method: http.MethodPost, pattern: "/tenants/{pck}/wallet/debits", summary: "Takes an amount out of the wallet of a tenant against an invoice", body: AmountBody{}, response: client.Wallet{},
+			handle: func(r request) (interface{}, error) {
+				var body AmountBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				if body.Reference == "" {
+					return nil, badRequest("reference is required")
+				}
+				return b.Debit(r.params["pck"], body.text(), body.Reference)
+			}},
+		{method: http.MethodPut, pattern: "/tenants/{pck}/wallet/credit-limit", summary: "Sets how far below zero the balance of a tenant may go", body: CreditLimitBody{}, response: client.Wallet{},
+			handle: func(r request) (interface{}, error) {
+				var body CreditLimitBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				return b.SetCreditLimit(r.params["pck"], body.Limit)
+			}},
+		{method: http.MethodGet, pattern: "/tenants/{pck}/wallet/holds", summary: "Returns the holds on the wallet of a tenant", response: []client.Hold{},
+			handle: func(r request) (interface{}, error) {
+				return b.GetHolds(r.params["pck"])
+			}},
+		{method: http.MethodPut, pattern: "/tenants/{pck}/wallet/holds/{holdid}", summary: "Reserves an amount on the wallet of a tenant", body: AmountBody{}, response: client.Wallet{},
+			handle: func(r request) (interface{}, error) {
+				var body AmountBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				return b.PlaceHold(r.params["pck"], r.params["holdid"], body.text(), body.Reason)
+			}},
+		{method: http.MethodDelete, pattern: "/tenants/{pck}/wallet/holds/{holdid}", summary: "Releases what is left of a hold on the wallet of a tenant", response: client.Wallet{},
+			handle: func(r request) (interface{}, error) {
+				return b.ReleaseHold(r.params["pck"], r.params["holdid"])
+			}},
+		{method: http.MethodGet, pattern: "/tenants/{pck}/wallet/entries", summary: "Returns the movements of the wallet of a tenant, oldest first", response: []client.WalletEntry{},
+			handle: func(r request) (interface{}, error) {
+				return b.GetWalletEntries(r.params["pck"])
+			}},
+		{method: http.MethodPost, pattern: "/tenants/{pck}/invoices", summary: "Issues an invoice to a tenant", body: InvoiceBody{}, response: client.Invoice{}, status: http.StatusCreated,
+			handle: func(r request) (interface{}, error) {
+				var body InvoiceBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				if body.InvoiceID == "" {
+					return nil, badRequest("invoiceid is required")
+				}
+				amount := AmountBody{Amount: body.Amount, Currency: body.Currency}
+				if err := b.IssueInvoice(r.params["pck"], body.InvoiceID, amount.text(), body.Due); err != nil {
+					return nil, err
+				}
+				return b.GetInvoice(r.params["pck"], body.InvoiceID)
+			}},
+		{method: http.MethodGet, pattern: "/tenants/{pck}/invoices", summary: "Returns the invoices of a tenant", response: []client.Invoice{},
+			handle: func(r request) (interface{}, error) {
+				return b.GetInvoices(r.params["pck"])
+			}},
+		{method: http.MethodGet, pattern: "/tenants/{pck}/invoices/{invoiceid}", summary: "Returns an invoice of a tenant", response: client.Invoice{},
+			handle: func(r request) (interface{}, error) {
+				return b.GetInvoice(r.params["pck"], r.params["invoiceid"])
+			}},
+		{method: http.MethodPost, pattern: "/tenants/{pck}/invoices/{invoiceid}/pay", summary: "Records the payment of an invoice and reinstates the tenant once nothing is overdue", body: PaymentBody{}, response: client.Invoice{},
+			handle: func(r request) (interface{}, error) {
+				var body PaymentBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				return b.PayInvoice(r.params["pck"], r.params["invoiceid"], body.Reference)
+			}},
+		{method: http.MethodPost, pattern: "/tenants/{pck}/dunning", summary: "Suspends the grants of a tenant with invoices unpaid past the grace period", body: DunningBody{}, response: client.Dunning{},
+			handle: func(r request) (interface{}, error) {
+				var body DunningBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				grace, err := time.ParseDuration(body.Grace)
+				if err != nil {
+					return nil, badRequest("grace must be a duration such as 168h")
+				}
+				return b.RunDunning(r.params["pck"], grace)
+			}},
+		{method: http.MethodGet, pattern: "/tenants/{pck}/dunning", summary: "Returns the dunning record of a tenant", response: client.Dunning{},
+			handle: func(r request) (interface{}, error) {
+				return b.GetDunning(r.params["pck"])
+			}},
+		{method: http.MethodGet, pattern: "/tenants/{pck}/memberships", summary: "Returns the organizations and groups a tenant is a member of", response: []client.Membership{},
+			handle: func(r request) (interface{}, error) {
+				return b.GetMemberships(r.params["pck"])
+			}},
+
+		//------------------------------------------Organizations-----------------------------------
+		{method: http.MethodPost, pattern: "/organizations", summary: "Registers an organization managed by its owner tenant", body: OrganizationBody{}, response: client.Organization{}, status: http.StatusCreated,
+			handle: func(r request) (interface{}, error) {
+				var body OrganizationBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				if body.Pck == "" {
+					return nil, badRequest("pck is required")
+				}
+				if err := b.RegisterOrganization(body.Pck, body.Name, body.Owner); err != nil {
+					return nil, err
+				}
+				return b.IsOrganization(body.Pck)
+			}},
+		{method: http.MethodGet, pattern: "/organizations/{pck}", summary: "Returns an organization", response: client.Organization{},
+			handle: func(r request) (interface{}, error) {
+				return b.IsOrganization(r.params["pck"])
+			}},
+		{method: http.MethodPost, pattern: "/organizations/{pck}/members", summary: "Adds a tenant to an organization, or invites it when the caller cannot act for it", body: MemberBody{}, response: client.Organization{},
+			handle: func(r request) (interface{}, error) {
+				var body MemberBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				if err := b.AddMember(r.params["pck"], body.Tenant); err != nil {
+					return nil, err
+				}
+				return b.IsOrganization(r.params["pck"])
+			}},
+		{method: http.MethodPost, pattern: "/organizations/{pck}/members/{tenant}/accept", summary: "Accepts the invitation of a tenant to an organization", response: client.Organization{},
+			handle: func(r request) (interface{}, error) {
+				if err := b.AcceptMembership(r.params["pck"], r.params["tenant"]); err != nil {
+					return nil, err
+				}
+				return b.IsOrganization(r.params["pck"])
+			}},
+		{method: http.MethodDelete, pattern: "/organizations/{pck}/members/{tenant}", summary: "Removes a tenant from an organization and its groups", response: client.Organization{},
+			handle: func(r request) (interface{}, error) {
+				if err := b.RemoveMember(r.params["pck"], r.params["tenant"]); err != nil {
+					return nil, err
+				}
+				return b.IsOrganization(r.params["pck"])
+			}},
+
+		//------------------------------------------Groups------------------------------------------
+		{method: http.MethodPost, pattern: "/groups", summary: "Registers a group of an organization", body: GroupBody{}, response: client.Group{}, status: http.StatusCreated,
+			handle: func(r request) (interface{}, error) {
+				var body GroupBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				if body.Pck == "" {
+					return nil, badRequest("pck is required")
+				}
+				if err := b.RegisterGroup(body.Pck, body.Name, body.Organization); err != nil {
+					return nil, err
+				}
+				return b.IsGroup(body.Pck)
+			}},
+		{method: http.MethodGet, pattern: "/groups/{pck}", summary: "Returns a group", response: client.Group{},
+			handle: func(r request) (interface{}, error) {
+				return b.IsGroup(r.params["pck"])
+			}},
+		{method: http.MethodDelete, pattern: "/groups/{pck}", summary: "Unregisters a group, its grants no longer give its members access",
+			handle: func(r request) (interface{}, error) {
+				return nil, b.UnRegisterGroup(r.params["pck"])
+			}},
+		{method: http.MethodGet, pattern: "/groups/{pck}/dependent-grants", summary: "Returns the active grants a group is the grandor or recipient of, the ones its deregistration applies the cascade policy to", response: []client.Delegation{},
+			handle: func(r request) (interface{}, error) {
+				return b.GetDependentGrants(r.params["pck"])
+			}},
+		{method: http.MethodPost, pattern: "/groups/{pck}/members", summary: "Adds a member of its organization to a group, or invites it when the caller cannot act for it", body: MemberBody{}, response: client.Group{},
+			handle: func(r request) (interface{}, error) {
+				var body MemberBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				if err := b.AddMember(r.params["pck"], body.Tenant); err != nil {
+					return nil, err
+				}
+				return b.IsGroup(r.params["pck"])
+			}},
+		{method: http.MethodPost, pattern: "/groups/{pck}/members/{tenant}/accept", summary: "Accepts the invitation of a tenant to a group", response: client.Group{},
+			handle: func(r request) (interface{}, error) {
+				if err := b.AcceptMembership(r.params["pck"], r.params["tenant"]); err != nil {
+					return nil, err
+				}
+				return b.IsGroup(r.params["pck"])
+			}},
+		{method: http.MethodDelete, pattern: "/groups/{pck}/members/{tenant}", summary: "Removes a tenant from a group", response: client.Group{},
+			handle: func(r request) (interface{}, error) {
+				if err := b.RemoveMember(r.params["pck"], r.params["tenant"]); err != nil {
+					return nil, err
+				}
+				return b.IsGroup(r.params["pck"])
+			}},
+
+		//------------------------------------------Services----------------------------------------
+		{method: http.MethodPost, pattern: "/services", summary: "Registers a service", body: ServiceBody{}, response: client.Service{}, status: http.StatusCreated,
+			handle: func(r request) (interface{}, error) {
+				var body ServiceBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				if body.Pck == "" {
+					return nil, badRequest("pck is required")
+				}
+				if err := b.RegisterService(body.Pck, body.Name, body.Owner); err != nil {
+					return nil, err
+				}
+				return b.IsService(body.Pck)
+			}},
+		{method: http.MethodGet, pattern: "/services/{pck}", summary: "Returns a service", response: client.Service{},
+			handle: func(r request) (interface{}, error) {
+				return b.IsService(r.params["pck"])
+			}},
+		{method: http.MethodDelete, pattern: "/services/{pck}", summary: "Unregisters a service and applies the cascade policy of services to its grants",
+			handle: func(r request) (interface{}, error) {
+				return nil, b.UnRegisterService(r.params["pck"])
+			}},
+		{method: http.MethodGet, pattern: "/services/{pck}/dependent-grants", summary: "Returns the active grants a service is the grandor or recipient of, the ones its deregistration applies the cascade policy to", response: []client.Delegation{},
+			handle: func(r request) (interface{}, error) {
+				return b.GetDependentGrants(r.params["pck"])
+			}},
+		{method: http.MethodPost, pattern: "/services/{pck}/transfer", summary: "Offers the ownership of a service to another tenant", body: OwnershipBody{}, response: client.Service{},
+			handle: func(r request) (interface{}, error) {
+				var body OwnershipBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				if err := b.TransferServiceOwnership(r.params["pck"], body.NewOwner); err != nil {
+					return nil, err
+				}
+				return b.IsService(r.params["pck"])
+			}},
+		{method: http.MethodPost, pattern: "/services/{pck}/accept", summary: "Accepts a pending ownership transfer for the calling organization", response: client.Service{},
+			handle: func(r request) (interface{}, error) {
+				if err := b.AcceptServiceOwnership(r.params["pck"]); err != nil {
+					return nil, err
+				}
+				return b.IsService(r.params["pck"])
+			}},
+		{method: http.MethodGet, pattern: "/services/{pck}/quota", summary: "Returns the total, allocated and free resources of a service", response: client.Quota{},
+			handle: func(r request) (interface{}, error) {
+				return b.GetQuota(r.params["pck"])
+			}},
+		{method: http.MethodPut, pattern: "/services/{pck}/quota", summary: "Declares the total resources of a service, 0 is unlimited", body: QuotaBody{}, response: client.Quota{},
+			handle: func(r request) (interface{}, error) {
+				var body QuotaBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				if err := b.SetServiceQuota(r.params["pck"], client.Resources{Cores: body.Cores, Memory: body.Memory, Storage: body.Storage}); err != nil {
+					return nil, err
+				}
+				return b.GetQuota(r.params["pck"])
+			}},
+		{method: http.MethodPut, pattern: "/services/{pck}/approval", summary: "Sets the most cores a Delegation from a service can grant without being proposed and accepted", body: ApprovalBody{}, response: client.Service{},
+			handle: func(r request) (interface{}, error) {
+				var body ApprovalBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				if err := b.SetApprovalThreshold(r.params["pck"], body.Cores); err != nil {
+					return nil, err
+				}
+				return b.IsService(r.params["pck"])
+			}},
+		{method: http.MethodGet, pattern: "/services/{pck}/countersigner", summary: "Returns the organization that must countersign the Delegations proposed from a service", response: CountersignerBody{},
+			handle: func(r request) (interface{}, error) {
+				mspid, err := b.GetCountersigner(r.params["pck"])
+				if err != nil {
+					return nil, err
+				}
+				return CountersignerBody{MSP: mspid}, nil
+			}},
+		{method: http.MethodPut, pattern: "/services/{pck}/countersigner", summary: "Names the organization that must countersign the Delegations proposed from a service, by a platform admin", body: CountersignerBody{}, response: CountersignerBody{},
+			handle: func(r request) (interface{}, error) {
+				var body CountersignerBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				if err := b.SetCountersigner(r.params["pck"], body.MSP); err != nil {
+					return nil, err
+				}
+				return body, nil
+			}},
+		{method: http.MethodPut, pattern: "/services/{pck}/price", summary: "Changes the price per core and hour of a service", body: PriceBody{}, response: client.Service{},
+			handle: func(r request) (interface{}, error) {
+				var body PriceBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				if body.Price != "" {
+					price := body.Price
+					if body.Currency != "" {
+						price += " " + body.Currency
+					}
+					if err := b.SetServicePrice(r.params["pck"], price); err != nil {
+						return nil, err
+					}
+				}
+				if body.StoragePrice != "" {
+					price := body.StoragePrice
+					if body.Currency != "" {
+						price += " " + body.Currency
+					}
+					if err := b.SetServiceStoragePrice(r.params["pck"], price); err != nil {
+						return nil, err
+					}
+				}
+				if body.Rounding != "" {
+					if err := b.SetServiceRounding(r.params["pck"], body.Rounding); err != nil {
+						return nil, err
+					}
+				}
+				return b.IsService(r.params["pck"])
+			}},
+
+		//------------------------------------------Currencies--------------------------------------
+		{method: http.MethodGet, pattern: "/currencies/{code}", summary: "Returns the decimals and rounding rule of a currency", response: client.Currency{},
+			handle: func(r request) (interface{}, error) {
+				return b.GetCurrency(r.params["code"])
+			}},
+		{method: http.MethodPut, pattern: "/currencies/{code}", summary: "Sets the decimals and rounding rule of a currency", body: CurrencyBody{}, response: client.Currency{},
+			handle: func(r request) (interface{}, error) {
+				var body CurrencyBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				if err := b.SetCurrency(r.params["code"], body.Decimals, body.Rounding); err != nil {
+					return nil, err
+				}
+				return b.GetCurrency(r.params["code"])
+			}},
+		{method: http.MethodGet, pattern: "/currencies/{from}/rates/{to}", summary: "Returns the exchange rate between two currencies", response: client.ExchangeRate{},
+			handle: func(r request) (interface{}, error) {
+				return b.GetExchangeRate(r.params["from"], r.params["to"])
+			}},
+		{method: http.MethodPut, pattern: "/currencies/{from}/rates/{to}", summary: "Records the exchange rate between two currencies", body: RateBody{}, response: client.ExchangeRate{},
+			handle: func(r request) (interface{}, error) {
+				var body RateBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				if err := b.SetExchangeRate(r.params["from"], r.params["to"], body.Rate); err != nil {
+					return nil, err
+				}
+				return b.GetExchangeRate(r.params["from"], r.params["to"])
+			}},
+
+		//------------------------------------------Quotes------------------------------------------
+		{method: http.MethodGet, pattern: "/quotes", summary: "Estimates the most a prospective grant costs, itemized per day, without storing anything", query: []queryParam{{name: "grandor", kind: "string", required: true}, {name: "cores", kind: "integer", required: true}, {name: "issue", kind: "string", required: true}, {name: "expiry", kind: "string", required: true}}, response: client.Quote{},
+			handle: func(r request) (interface{}, error) {
+				query := r.URL.Query()
+				cores, err := strconv.ParseUint(query.Get("cores"), 10, 64)
+				if err != nil {
+					return nil, badRequest("cores must be a number")
+				}
+				issue, err := time.Parse(time.RFC3339, query.Get("issue"))
+				if err != nil {
+					return nil, badRequest("issue must be an RFC 3339 time")
+				}
+				expiry, err := time.Parse(time.RFC3339, query.Get("expiry"))
+				if err != nil {
+					return nil, badRequest("expiry must be an RFC 3339 time")
+				}
+				return b.QuoteGrant(query.Get("grandor"), cores, issue, expiry)
+			}},
+		{method: http.MethodPost, pattern: "/quotes", summary: "Issues a quote for the pck a grant will be created with, creating the grant with the same grandor, window and cores locks the price in", body: QuoteBody{}, response: client.Quote{}, status: http.StatusCreated,
+			handle: func(r request) (interface{}, error) {
+				var body QuoteBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				if body.Pck == "" {
+					return nil, badRequest("pck is required")
+				}
+				return b.IssueQuote(body.Pck, body.Grandor, body.Cores, body.Issue, body.Expiry)
+			}},
+		{method: http.MethodGet, pattern: "/quotes/{pck}", summary: "Returns the quote issued for a grant", response: client.Quote{},
+			handle: func(r request) (interface{}, error) {
+				return b.GetQuote(r.params["pck"])
+			}},
+
+		//------------------------------------------Proposals---------------------------------------
+		{method: http.MethodPost, pattern: "/proposals", summary: "Proposes a Delegation that is created once the recipient accepts it and the countersigner, if any, countersigns it", body: ProposalBody{}, response: client.Proposal{}, status: http.StatusCreated,
+			handle: func(r request) (interface{}, error) {
+				var body ProposalBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				if body.Pck == "" {
+					return nil, badRequest("pck is required")
+				}
+				var validity time.Duration
+				if body.Validity != "" {
+					var err error
+					if validity, err = time.ParseDuration(body.Validity); err != nil || validity <= 0 {
+						return nil, badRequest("validity must be a positive duration")
+					}
+				}
+				return b.ProposeDelegation(body.Pck, body.Grandor, body.Recipient, body.Subdel, body.Issue, body.Expiry, body.Scope, validity)
+			}},
+		{method: http.MethodGet, pattern: "/proposals", summary: "Returns every proposal, open or not", response: []client.Proposal{},
+			handle: func(r request) (interface{}, error) {
+				return b.GetProposals()
+			}},
+		{method: http.MethodGet, pattern: "/proposals/{pck}", summary: "Returns the proposal of a Delegation", response: client.Proposal{},
+			handle: func(r request) (interface{}, error) {
+				return b.GetProposal(r.params["pck"])
+			}},
+		{method: http.MethodPost, pattern: "/proposals/{pck}/accept", summary: "Accepts a proposal on behalf of the organization that owns the recipient service", response: client.Proposal{},
+			handle: func(r request) (interface{}, error) {
+				return b.AcceptProposal(r.params["pck"])
+			}},
+		{method: http.MethodPost, pattern: "/proposals/{pck}/countersign", summary: "Countersigns a proposal on behalf of the countersigning organization", response: client.Proposal{},
+			handle: func(r request) (interface{}, error) {
+				return b.CountersignProposal(r.params["pck"])
+			}},
+		{method: http.MethodPost, pattern: "/proposals/{pck}/decline", summary: "Declines a proposal on behalf of the recipient or the countersigning organization", response: client.Proposal{},
+			handle: func(r request) (interface{}, error) {
+				return b.DeclineProposal(r.params["pck"])
+			}},
+		{method: http.MethodPost, pattern: "/proposals/{pck}/withdraw", summary: "Withdraws a proposal on behalf of the organization that owns the grandor", response: client.Proposal{},
+			handle: func(r request) (interface{}, error) {
+				return b.WithdrawProposal(r.params["pck"])
+			}},
+		{method: http.MethodPost, pattern: "/proposals/{pck}/expire", summary: "Closes a proposal that was not agreed to within its validity", response: client.Proposal{},
+			handle: func(r request) (interface{}, error) {
+				return b.ExpireProposal(r.params["pck"])
+			}},
+
+		//------------------------------------------Delegations-------------------------------------
+		{method: http.MethodPost, pattern: "/delegations", summary: "Creates a Delegation between two services", body: DelegationBody{}, response: client.Delegation{}, status: http.StatusCreated,
+			handle: func(r request) (interface{}, error) {
+				var body DelegationBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				if body.Pck == "" {
+					return nil, badRequest("pck is required")
+				}
+				if err := b.RegisterDelegation(body.Pck, body.Grandor, body.Recipient, body.Subdel, body.Issue, body.Expiry, body.Scope); err != nil {
+					return nil, err
+				}
+				return b.IsDelegation(body.Pck)
+			}},
+		{method: http.MethodGet, pattern: "/delegations/{pck}", summary: "Returns a Delegation", response: client.Delegation{},
+			handle: func(r request) (interface{}, error) {
+				return b.IsDelegation(r.params["pck"])
+			}},
+		{method: http.MethodGet, pattern: "/delegations/{pck}/scope", summary: "Checks an operation and a region against the scope of a Delegation or SubDelegation", query: []queryParam{{name: "operation", kind: "string"}, {name: "region", kind: "string"}}, response: ScopeCheck{},
+			handle: func(r request) (interface{}, error) {
+				check := ScopeCheck{Pck: r.params["pck"], Operation: r.URL.Query().Get("operation"), Region: r.URL.Query().Get("region")}
+				allowed, err := b.IsInScope(check.Pck, check.Operation, check.Region)
+				if err != nil {
+					return nil, err
+				}
+				check.Allowed = allowed
+				return check, nil
+			}},
+		{method: http.MethodGet, pattern: "/delegations/{pck}/access", summary: "Checks if a Delegation or SubDelegation gives a tenant access now, directly or as a member of the group that received it", query: []queryParam{{name: "tenant", kind: "string"}}, response: MemberAccess{},
+			handle: func(r request) (interface{}, error) {
+				check := MemberAccess{Pck: r.params["pck"], Tenant: r.URL.Query().Get("tenant")}
+				if check.Tenant == "" {
+					return nil, badRequest("tenant is required")
+				}
+				allowed, err := b.CheckMemberAccess(check.Pck, check.Tenant)
+				if err != nil {
+					return nil, err
+				}
+				check.Allowed = allowed
+				return check, nil
+			}},
+		{method: http.MethodGet, pattern: "/delegations/{pck}/status", summary: "Returns the validity, expiry, suspension and revocation of a Delegation", response: Status{},
+			handle: func(r request) (interface{}, error) {
+				if _, err := b.IsDelegation(r.params["pck"]); err != nil {
+					return nil, err
+				}
+				return status(r.params["pck"], b.IsValid, b.IsExpired, b.IsSuspended, b.IsRevoked)
+			}},
+		{method: http.MethodPost, pattern: "/delegations/{pck}/suspend", summary: "Suspends a Delegation",
+			handle: func(r request) (interface{}, error) {
+				return nil, b.SuspendDelegation(r.params["pck"])
+			}},
+		{method: http.MethodPost, pattern: "/delegations/{pck}/resume", summary: "Lifts the suspension of a Delegation",
+			handle: func(r request) (interface{}, error) {
+				return nil, b.ResumeDelegation(r.params["pck"])
+			}},
+		{method: http.MethodPost, pattern: "/delegations/{pck}/revoke", summary: "Revokes a Delegation once its revocation policy is satisfied", body: RevokeBody{},
+			handle: func(r request) (interface{}, error) {
+				var body RevokeBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				return nil, b.RevokeDelegation(r.params["pck"], body.Revoker)
+			}},
+		{method: http.MethodPut, pattern: "/delegations/{pck}/revocation-policy", summary: "Sets the rule the revokers of a Delegation have to satisfy to revoke it, on behalf of its grandor", body: RevocationPolicyBody{}, response: client.RevocationPolicy{},
+			handle: func(r request) (interface{}, error) {
+				var body RevocationPolicyBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				if err := b.SetRevocationPolicy(r.params["pck"], body.policy()); err != nil {
+					return nil, err
+				}
+				return b.GetRevocationPolicy(r.params["pck"])
+			}},
+		{method: http.MethodGet, pattern: "/delegations/{pck}/revocation-policy", summary: "Returns the revocation policy of a Delegation and the revokers that have signed it", response: client.RevocationPolicy{},
+			handle: func(r request) (interface{}, error) {
+				return b.GetRevocationPolicy(r.params["pck"])
+			}},
+		{method: http.MethodPut, pattern: "/delegations/{pck}/schedule", summary: "Sets the windows in which a Delegation is active, on behalf of its grandor, no windows remove the schedule", body: ScheduleBody{}, response: client.Schedule{},
+			handle: func(r request) (interface{}, error) {
+				var body ScheduleBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				if err := b.SetSchedule(r.params["pck"], body.schedule()); err != nil {
+					return nil, err
+				}
+				return b.GetSchedule(r.params["pck"])
+			}},
+		{method: http.MethodGet, pattern: "/delegations/{pck}/schedule", summary: "Returns the schedule of a Delegation", response: client.Schedule{},
+			handle: func(r request) (interface{}, error) {
+				return b.GetSchedule(r.params["pck"])
+			}},
+		{method: http.MethodGet, pattern: "/delegations/{pck}/schedule/next", summary: "Returns when a Delegation is next active", response: Activation{},
+			handle: func(r request) (interface{}, error) {
+				return activation(r.params["pck"], b.NextActivation)
+			}},
+		{method: http.MethodPost, pattern: "/delegations/{pck}/transfers", summary: "Asks to move a Delegation to a new recipient, on behalf of its current recipient", body: TransferBody{}, response: []client.GrantTransfer{}, status: http.StatusCreated,
+			handle: func(r request) (interface{}, error) {
+				var body TransferBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				if err := b.TransferDelegation(r.params["pck"], body.Recipient); err != nil {
+					return nil, err
+				}
+				return b.GetTransfers(r.params["pck"])
+			}},
+		{method: http.MethodGet, pattern: "/delegations/{pck}/transfers", summary: "Returns every transfer of a Delegation", response: []client.GrantTransfer{},
+			handle: func(r request) (interface{}, error) {
+				return b.GetTransfers(r.params["pck"])
+			}},
+		{method: http.MethodPost, pattern: "/delegations/{pck}/transfers/approve", summary: "Moves a Delegation to the recipient of its open transfer, on behalf of its grandor", response: client.Delegation{},
+			handle: func(r request) (interface{}, error) {
+				if err := b.ApproveTransfer(r.params["pck"]); err != nil {
+					return nil, err
+				}
+				return b.IsDelegation(r.params["pck"])
+			}},
+		{method: http.MethodPost, pattern: "/delegations/{pck}/transfers/reject", summary: "Closes the open transfer of a Delegation without moving it", response: []client.GrantTransfer{},
+			handle: func(r request) (interface{}, error) {
+				if err := b.RejectTransfer(r.params["pck"]); err != nil {
+					return nil, err
+				}
+				return b.GetTransfers(r.params["pck"])
+			}},
+		{method: http.MethodPost, pattern: "/delegations/{pck}/accept", summary: "Accepts a pending Delegation on behalf of its recipient", response: client.Delegation{},
+			handle: func(r request) (interface{}, error) {
+				if err := b.AcceptGrant(r.params["pck"]); err != nil {
+					return nil, err
+				}
+				return b.IsDelegation(r.params["pck"])
+			}},
+		{method: http.MethodPost, pattern: "/delegations/{pck}/decline", summary: "Declines a pending Delegation on behalf of its recipient, its budget returns to its parent", response: client.Delegation{},
+			handle: func(r request) (interface{}, error) {
+				if err := b.DeclineGrant(r.params["pck"]); err != nil {
+					return nil, err
+				}
+				return b.IsDelegation(r.params["pck"])
+			}},
+		{method: http.MethodGet, pattern: "/delegations/{pck}/charge", summary: "Returns the cost of a Delegation computed from its recorded usage", response: Charge{},
+			handle: func(r request) (interface{}, error) {
+				cost, err := b.ChargingDel(r.params["pck"])
+				if err != nil {
+					return nil, err
+				}
+				return Charge{Pck: r.params["pck"], Cost: *cost}, nil
+			}},
+		{method: http.MethodPost, pattern: "/delegations/{pck}/usage", summary: "Records a usage sample of a Delegation or SubDelegation, submitted by the organization that owns its service", body: UsageBody{}, response: []client.UsageSample{}, status: http.StatusCreated,
+			handle: func(r request) (interface{}, error) {
+				var body UsageBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				if body.SampleID == "" {
+					return nil, badRequest("sampleid is required")
+				}
+				sample := client.UsageSample{SampleID: body.SampleID, Timestamp: uint64(body.Timestamp.Unix()), Cores: body.Cores, Hours: body.Hours, Storage: body.Storage}
+				if err := b.RecordUsage(r.params["pck"], sample); err != nil {
+					return nil, err
+				}
+				return b.GetUsage(r.params["pck"])
+			}},
+		{method: http.MethodGet, pattern: "/delegations/{pck}/usage", summary: "Returns the usage samples recorded for a Delegation or SubDelegation", response: []client.UsageSample{},
+			handle: func(r request) (interface{}, error) {
+				return b.GetUsage(r.params["pck"])
+			}},
+		{method: http.MethodPost, pattern: "/delegations/{pck}/bill", summary: "Debits the paying tenant of a Delegation or SubDelegation with its usage charged since it was last billed", response: client.Wallet{},
+			handle: func(r request) (interface{}, error) {
+				return b.BillUsage(r.params["pck"])
+			}},
+		{method: http.MethodGet, pattern: "/delegations/{pck}/tree", summary: "Returns the tree of SubDelegations under a Delegation or SubDelegation, as JSON or rendered with format=dot or format=mermaid", query: []queryParam{{name: "format", kind: "string"}}, response: client.DelegationTreeNode{},
+			handle: func(r request) (interface{}, error) {
+				tree, err := b.GetDelegationTree(r.params["pck"])
+				if err != nil {
+					return nil, err
+				}
+				switch r.URL.Query().Get("format") {
+				case "", "json":
+					return tree, nil
+				case "dot":
+					return text{contentType: "text/vnd.graphviz", body: tree.DOT()}, nil
+				case "mermaid":
+					return text{contentType: "text/plain", body: tree.Mermaid()}, nil
+				default:
+					return nil, badRequest("format must be json, dot or mermaid")
+				}
+			}},
+		{method: http.MethodGet, pattern: "/delegations/{pck}/capacity", summary: "Returns the subdelegation budget of a Delegation or SubDelegation", response: client.Capacity{},
+			handle: func(r request) (interface{}, error) {
+				return b.GetCapacity(r.params["pck"])
+			}},
+		{method: http.MethodPut, pattern: "/delegations/{pck}/capacity", summary: "Changes how many direct children a Delegation or SubDelegation can have", body: CapacityBody{}, response: client.Capacity{},
+			handle: func(r request) (interface{}, error) {
+				var body CapacityBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				if err := b.SetMaxChildren(r.params["pck"], body.MaxChildren); err != nil {
+					return nil, err
+				}
+				return b.GetCapacity(r.params["pck"])
+			}},
+		{method: http.MethodGet, pattern: "/delegations/{pck}/quota", summary: "Returns the resources of a Delegation or SubDelegation and how much of them is subdelegated", response: client.Quota{},
+			handle: func(r request) (interface{}, error) {
+				return b.GetQuota(r.params["pck"])
+			}},
+		{method: http.MethodGet, pattern: "/delegations/{pck}/allocations", summary: "Returns the SubDelegations allocated under a Delegation or SubDelegation", response: []client.Allocation{},
+			handle: func(r request) (interface{}, error) {
+				return b.GetAllocations(r.params["pck"])
+			}},
+
+		//------------------------------------------SubDelegations----------------------------------
+		{method: http.MethodPost, pattern: "/subdelegations", summary: "Creates a SubDelegation of a Delegation or SubDelegation to a tenant", body: SubDelegationBody{}, response: client.SubDelegation{}, status: http.StatusCreated,
+			handle: func(r request) (interface{}, error) {
+				var body SubDelegationBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				if body.Pck == "" {
+					return nil, badRequest("pck is required")
+				}
+				if err := b.RegisterSubDelegation(body.Pck, body.Parent, body.Recipient, body.Subdel, body.Issue, body.Expiry, body.Scope); err != nil {
+					return nil, err
+				}
+				return b.IsSubDelegation(body.Pck)
+			}},
+		{method: http.MethodGet, pattern: "/subdelegations/{pck}", summary: "Returns a SubDelegation", response: client.SubDelegation{},
+			handle: func(r request) (interface{}, error) {
+				return b.IsSubDelegation(r.params["pck"])
+			}},
+		{method: http.MethodGet, pattern: "/subdelegations/{pck}/status", summary: "Returns the validity, expiry, suspension and revocation of a SubDelegation and the grants above it", response: Status{},
+			handle: func(r request) (interface{}, error) {
+				if _, err := b.IsSubDelegation(r.params["pck"]); err != nil {
+					return nil, err
+				}
+				return status(r.params["pck"], b.IsSubValid, b.IsSubExpired, b.IsSubSuspended, b.IsSubRevoked)
+			}},
+		{method: http.MethodPost, pattern: "/subdelegations/{pck}/suspend", summary: "Suspends a SubDelegation",
+			handle: func(r request) (interface{}, error) {
+				return nil, b.SuspendSubDelegation(r.params["pck"])
+			}},
+		{method: http.MethodPost, pattern: "/subdelegations/{pck}/resume", summary: "Lifts the suspension of a SubDelegation",
+			handle: func(r request) (interface{}, error) {
+				return nil, b.ResumeSubDelegation(r.params["pck"])
+			}},
+		{method: http.MethodPost, pattern: "/subdelegations/{pck}/revoke", summary: "Revokes a SubDelegation once its revocation policy is satisfied", body: RevokeBody{},
+			handle: func(r request) (interface{}, error) {
+				var body RevokeBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				return nil, b.RevokeSubDelegation(r.params["pck"], body.Revoker)
+			}},
+		{method: http.MethodPut, pattern: "/subdelegations/{pck}/revocation-policy", summary: "Sets the rule the revokers of a SubDelegation have to satisfy to revoke it, on behalf of its grandor", body: RevocationPolicyBody{}, response: client.RevocationPolicy{},
+			handle: func(r request) (interface{}, error) {
+				var body RevocationPolicyBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				if err := b.SetRevocationPolicy(r.params["pck"], body.policy()); err != nil {
+					return nil, err
+				}
+				return b.GetRevocationPolicy(r.params["pck"])
+			}},
+		{method: http.MethodGet, pattern: "/subdelegations/{pck}/revocation-policy", summary: "Returns the revocation policy of a SubDelegation and the revokers that have signed it", response: client.RevocationPolicy{},
+			handle: func(r request) (interface{}, error) {
+				return b.GetRevocationPolicy(r.params["pck"])
+			}},
+		{method: http.MethodPut, pattern: "/subdelegations/{pck}/schedule", summary: "Sets the windows in which a SubDelegation is active, on behalf of its grandor, no windows remove the schedule", body: ScheduleBody{}, response: client.Schedule{},
+			handle: func(r request) (interface{}, error) {
+				var body ScheduleBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				if err := b.SetSchedule(r.params["pck"], body.schedule()); err != nil {
+					return nil, err
+				}
+				return b.GetSchedule(r.params["pck"])
+			}},
+		{method: http.MethodGet, pattern: "/subdelegations/{pck}/schedule", summary: "Returns the schedule of a SubDelegation", response: client.Schedule{},
+			handle: func(r request) (interface{}, error) {
+				return b.GetSchedule(r.params["pck"])
+			}},
+		{method: http.MethodGet, pattern: "/subdelegations/{pck}/schedule/next", summary: "Returns when a SubDelegation is next active", response: Activation{},
+			handle: func(r request) (interface{}, error) {
+				return activation(r.params["pck"], b.NextActivation)
+			}},
+		{method: http.MethodPost, pattern: "/subdelegations/{pck}/transfers", summary: "Asks to move a SubDelegation to a new recipient, on behalf of its current recipient", body: TransferBody{}, response: []client.GrantTransfer{}, status: http.StatusCreated,
+			handle: func(r request) (interface{}, error) {
+				var body TransferBody
+				if err := r.decode(&body); err != nil {
+					return nil, err
+				}
+				if err := b.TransferDelegation(r.params["pck"], body.Recipient); err != nil {
+					return nil, err
+				}
+				return b.GetTransfers(r.params["pck"])
+			}},
+		{method: http.MethodGet, pattern: "/subdelegations/{pck}/transfers", summary: "Returns every transfer of a SubDelegation", response: []client.GrantTransfer{},
+			handle: func(r request) (interface{}, error) {
+				return b.GetTransfers(r.params["pck"])
+			}},
+		{method: http.MethodPost, pattern: "/subdelegations/{pck}/transfers/approve", summary: "Moves a SubDelegation to the recipient of its open transfer, on behalf of its grandor", response: client.SubDelegation{},
+			handle: func(r request) (interface{}, error) {
+				if err := b.ApproveTransfer(r.params["pck"]); err != nil {
+					return nil, err
+				}
+				return b.IsSubDelegation(r.params["pck"])
+			}},
+		{method: http.MethodPost, pattern: "/subdelegations/{pck}/transfers/reject", summary: "Closes the open transfer of a SubDelegation without moving it", response: []client.GrantTransfer{},
+			handle: func(r request) (interface{}, error) {
+				if err := b.RejectTransfer(r.params["pck"]); err != nil {
+					return nil, err
+				}
+				return b.GetTransfers(r.params["pck"])
+			}},
+		{method: http.MethodPost, pattern: "/subdelegations/{pck}/accept", summary: "Accepts a pending SubDelegation on behalf of its recipient", response: client.SubDelegation{},
+			handle: func(r request) (interface{}, error) {
+				if err := b.AcceptGrant(r.params["pck"]); err != nil {
+					return nil, err
+				}
+				return b.IsSubDelegation(r.params["pck"])
+			}},
+		{method: http.MethodPost, pattern: "/subdelegations/{pck}/decline", summary: "Declines a pending SubDelegation on behalf of its recipient, its budget returns to its parent", response: client.SubDelegation{},
+			handle: func(r request) (interface{}, error) {
+				if err := b.DeclineGrant(r.params["pck"]); err != nil {
+					return nil, err
+				}
+				return b.IsSubDelegation(r.params["pck"])
+			}},
+
+		//------------------------------------------OpenAPI-----------------------------------------
+		{method: http.MethodGet, pattern: "/openapi.json", summary: "Returns this OpenAPI document",
+			handle: func(r request) (interface{}, error) {
+				return s.OpenAPI(), nil
+			}},
+	}
+}
+
+//status runs the four status queries of a grant
+func status(pck string, valid, expired, suspended, revoked func(string) (bool, error)) (interface{}, error) {
+	st := Status{Pck: pck}
+	var err error
+	if st.Valid, err = valid(pck); err != nil {
+		return nil, err
+	}
+	if st.Expired, err = expired(pck); err != nil {
+		return nil, err
+	}
+	if st.Suspended, err = suspended(pck); err != nil {
+		return nil, err
+	}
+	if st.Revoked, err = revoked(pck); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+//activation looks up when a grant is next active
+func activation(pck string, next func(string) (time.Time, error)) (Activation, error) {
+	at, err := next(pck)
+	if err != nil {
+		return Activation{}, err
+	}
+	result := Activation{Pck: pck}
+	if !at.IsZero() {
+		result.Next = &at
+	}
+	return result, nil
+}
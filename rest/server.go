@@ -0,0 +1,310 @@
+//Package rest exposes the tenant service chaincode as REST resources over HTTP and JSON, for
+//clients such as the web portal that cannot speak the Fabric gRPC protocol
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Kthanasis/Tenant-Service-Cloud-Management-using-Blockchain/client"
+)
+
+//Backend is the set of contract transactions the REST resources are mapped to. *client.Client
+//implements it, over a Fabric gateway or over the in-process chaincode
+type Backend interface {
+	InitLedger(adminmsp string) error
+	IndexGrants() error
+
+	AssignRole(identity string, msp string, role string, scope string) error
+	RevokeRole(identity string, role string, scope string) error
+	WhoAmI() (*client.CallerIdentity, error)
+	GetRoleAssignments() ([]*client.RoleAssignment, error)
+	GetRoleChanges() ([]*client.RoleChange, error)
+	SetAccessPolicy(transaction string, rules []client.AttributeRule) error
+	GetAccessPolicies() ([]*client.AccessPolicy, error)
+	SetGrantRule(grant string, from string, to string, allowed bool) error
+	GetGrantPolicy() ([]*client.GrantRule, error)
+	SetCascadePolicy(principal string, mode string) error
+	GetCascadePolicies() ([]*client.CascadePolicy, error)
+	GetDependentGrants(pck string) ([]*client.Delegation, error)
+
+	Enroll(pck string, name string, contact *client.Contact) error
+	Update(pck string, name string, contact *client.Contact) error
+	DestroyTenant(pck string) error
+	IsTenant(pck string) (*client.Tenant, error)
+	GetTenantContact(pck string) (*client.TenantContact, error)
+	EraseTenant(pck string, successor string) error
+	GetErasureReceipt(pck string) (*client.ErasureReceipt, error)
+	GetTenantStatement(pck string, currency string) (*client.Statement, error)
+
+	OpenWallet(pck string, currency string) error
+	GetWallet(pck string) (*client.Wallet, error)
+	Deposit(pck string, amount string, reference string) (*client.Wallet, error)
+	Debit(pck string, amount string, reference string) (*client.Wallet, error)
+	SetCreditLimit(pck string, limit string) (*client.Wallet, error)
+	PlaceHold(pck string, holdid string, amount string, reason string) (*client.Wallet, error)
+	ReleaseHold(pck string, holdid string) (*client.Wallet, error)
+	GetHolds(pck string) ([]*client.Hold, error)
+	GetWalletEntries(pck string) ([]*client.WalletEntry, error)
+	BillUsage(pck string) (*client.Wallet, error)
+
+	IssueInvoice(pck string, invoiceid string, amount string, due time.Time) error
+	GetInvoice(pck string, invoiceid string) (*client.Invoice, error)
+	GetInvoices(pck string) ([]*client.Invoice, error)
+	PayInvoice(pck string, invoiceid string, reference string) (*client.Invoice, error)
+	RunDunning(pck string, grace time.Duration) (*client.Dunning, error)
+	GetDunning(pck string) (*client.Dunning, error)
+
+	SetCurrency(code string, decimals uint8, rounding string) error
+	GetCurrency(code string) (*client.Currency, error)
+	SetExchangeRate(from string, to string, rate string) error
+	GetExchangeRate(from string, to string) (*client.ExchangeRate, error)
+
+	RegisterOrganization(pck string, name string, owner string) error
+	RegisterGroup(pck string, name string, organization string) error
+	UnRegisterGroup(pck string) error
+	AddMember(pck string, tenant string) error
+	AcceptMembership(pck string, tenant string) error
+	RemoveMember(pck string, tenant string) error
+	IsOrganization(pck string) (*client.Organization, error)
+	IsGroup(pck string) (*client.Group, error)
+	GetMemberships(tenant string) ([]*client.Membership, error)
+	CheckMemberAccess(pck string, tenant string) (bool, error)
+
+	RegisterService(pck string, name string, owner string) error
+	UnRegisterService(pck string) error
+	TransferServiceOwnership(pck string, newowner string) error
+	AcceptServiceOwnership(pck string) error
+	SetServicePrice(pck string, price string) error
+	SetServiceStoragePrice(pck string, price string) error
+	SetServiceRounding(pck string, rounding string) error
+	SetServiceQuota(pck string, total client.Resources) error
+	SetApprovalThreshold(pck string, cores uint64) error
+	GetQuota(pck string) (*client.Quota, error)
+	IsService(pck string) (*client.Service, error)
+
+	QuoteGrant(grandor string, cores uint64, issue time.Time, expiry time.Time) (*client.Quote, error)
+	IssueQuote(pck string, grandor string, cores uint64, issue time.Time, expiry time.Time) (*client.Quote, error)
+	GetQuote(pck string) (*client.Quote, error)
+
+	ProposeDelegation(pck string, grandor string, recipient string, subdel uint8, issue time.Time, expiry time.Time, scope *client.Scope, validity time.Duration) (*client.Proposal, error)
+	SetCountersigner(pck string, mspid string) error
+	GetCountersigner(pck string) (string, error)
+	AcceptProposal(pck string) (*client.Proposal, error)
+	CountersignProposal(pck string) (*client.Proposal, error)
+	DeclineProposal(pck string) (*client.Proposal, error)
+	WithdrawProposal(pck string) (*client.Proposal, error)
+	ExpireProposal(pck string) (*client.Proposal, error)
+	GetProposal(pck string) (*client.Proposal, error)
+	GetProposals() ([]*client.Proposal, error)
+
+	RegisterDelegation(pck string, grandor string, recipient string, subdel uint8, issue time.Time, expiry time.Time, scope *client.Scope) error
+	SuspendDelegation(pck string) error
+	ResumeDelegation(pck string) error
+	RevokeDelegation(pck string, revoker string) error
+	SetRevocationPolicy(pck string, policy client.RevocationPolicy) error
+	GetRevocationPolicy(pck string) (*client.RevocationPolicy, error)
+	SetSchedule(pck string, schedule client.Schedule) error
+	GetSchedule(pck string) (*client.Schedule, error)
+	NextActivation(pck string) (time.Time, error)
+	TransferDelegation(pck string, recipient string) error
+	ApproveTransfer(pck string) error
+	RejectTransfer(pck string) error
+	GetTransfers(pck string) ([]*client.GrantTransfer, error)
+	AcceptGrant(pck string) error
+	DeclineGrant(pck string) error
+	IsDelegation(pck string) (*client.Delegation, error)
+	IsValid(pck string) (bool, error)
+	IsExpired(pck string) (bool, error)
+	IsSuspended(pck string) (bool, error)
+	IsRevoked(pck string) (bool, error)
+	ChargingDel(pck string) (*client.Money, error)
+	RecordUsage(pck string, sample client.UsageSample) error
+	GetUsage(pck string) ([]*client.UsageSample, error)
+	IsInScope(pck string, operation string, region string) (bool, error)
+
+	RegisterSubDelegation(pck string, parent string, recipient string, subdel uint8, issue time.Time, expiry time.Time, scope *client.Scope) error
+	SuspendSubDelegation(pck string) error
+	ResumeSubDelegation(pck string) error
+	RevokeSubDelegation(pck string, revoker string) error
+	IsSubDelegation(pck string) (*client.SubDelegation, error)
+	IsSubValid(pck string) (bool, error)
+	IsSubExpired(pck string) (bool, error)
+	IsSubSuspended(pck string) (bool, error)
+	IsSubRevoked(pck string) (bool, error)
+
+	GetDelegationTree(pck string) (*client.DelegationTreeNode, error)
+
+	GetCapacity(pck string) (*client.Capacity, error)
+	GetAllocations(pck string) ([]*client.Allocation, error)
+	SetMaxChildren(pck string, maxchildren uint8) error
+}
+
+//Server is an http.Handler serving the REST resources of a Backend
+type Server struct {
+	backend Backend
+	routes  []route
+}
+
+//NewServer returns a Server over the given Backend
+func NewServer(backend Backend) *Server {
+	s := &Server{backend: backend}
+	s.routes = s.resources()
+	return s
+}
+
+//request carries the path parameters and the body of a matched route to its handler
+type request struct {
+	*http.Request
+	params map[string]string
+}
+
+//queryParam describes a query parameter of a route in the OpenAPI document
+type queryParam struct {
+	name     string
+	kind     string //OpenAPI type of the parameter
+	required bool
+}
+
+//handler serves a matched route and returns the value to encode as the JSON response
+type handler func(r request) (interface{}, error)
+
+//route maps a method and a path pattern like /delegations/{pck}/revoke to its handler, the
+//remaining fields describe the route in the OpenAPI document
+type route struct {
+	method   string
+	pattern  string
+	summary  string
+	body     interface{} //zero value of the request body, nil if there is none
+	response interface{} //zero value of the response body
+	query    []queryParam
+	status   int //status of a successful response, 200 when not set
+	handle   handler
+}
+
+//match checks if the path matches the pattern of the route and extracts its parameters
+func (rt route) match(path string) (map[string]string, bool) {
+	patternParts := strings.Split(strings.Trim(rt.pattern, "/"), "/")
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(patternParts) != len(pathParts) {
+		return nil, false
+	}
+
+	params := map[string]string{}
+	for i, part := range patternParts {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			if pathParts[i] == "" {
+				return nil, false
+			}
+			params[strings.Trim(part, "{}")] = pathParts[i]
+		} else if part != pathParts[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+//ServeHTTP routes the request to the matching resource
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	allowed := false
+	for _, rt := range s.routes {
+		params, ok := rt.match(r.URL.Path)
+		if !ok {
+			continue
+		}
+		if rt.method != r.Method {
+			allowed = true
+			continue
+		}
+
+		value, err := rt.handle(request{Request: r, params: params})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		status := http.StatusOK
+		if rt.status != 0 {
+			status = rt.status
+		}
+		if value == nil {
+			status = http.StatusNoContent
+		}
+		writeJSON(w, status, value)
+		return
+	}
+
+	if allowed {
+		writeError(w, &httpError{status: http.StatusMethodNotAllowed, message: fmt.Sprintf("%s is not allowed on %s", r.Method, r.URL.Path)})
+		return
+	}
+	writeError(w, &httpError{status: http.StatusNotFound, message: fmt.Sprintf("no resource at %s", r.URL.Path)})
+}
+
+//httpError is an error with the HTTP status it should be answered with
+type httpError struct {
+	status  int
+	message string
+}
+
+func (e *httpError) Error() string {
+	return e.message
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return &httpError{status: http.StatusBadRequest, message: fmt.Sprintf(format, args...)}
+}
+
+//errorBody is the JSON body of every error response
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+//writeError answers with the status of an httpError, contract errors about missing records
+//become 404, authorization errors 403 and every other contract error 422
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusUnprocessableEntity
+	if e, ok := err.(*httpError); ok {
+		status = e.status
+	} else if strings.Contains(err.Error(), "does not exist") {
+		status = http.StatusNotFound
+	} else if strings.Contains(err.Error(), "not authorized") {
+		status = http.StatusForbidden
+	}
+	writeJSON(w, status, errorBody{Error: err.Error()})
+}
+
+//text is a handler result that is written as plain text instead of JSON
+type text struct {
+	contentType string
+	body        string
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	if status == http.StatusNoContent {
+		w.WriteHeader(status)
+		return
+	}
+	if t, ok := value.(text); ok {
+		w.Header().Set("Content-Type", t.contentType)
+		w.WriteHeader(status)
+		io.WriteString(w, t.body)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+//decode reads the JSON body of the request into value
+func (r request) decode(value interface{}) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(value); err != nil {
+		return badRequest("invalid request body: %s", err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+//***************************************************************************************************
+//**																							   **
+//**							The following section Manages the Revocation Policies              **
+//**                                                                                               **
+//***************************************************************************************************
+
+//-------------------------------------Revocation Policies-------------------------------------------
+//the Revokers of a grant are the parties that can take part in revoking it, its revocation policy is
+//the rule they have to satisfy. Without a policy any one of the Revokers revokes the grant, as it has
+//always been. A policy can instead require all of its parties, k of them, or an admin of the
+//organization of the grandor, and can lock the revocation until a time. Every revocation is checked
+//against the identity that submits it, only the owning organization of a service can sign for it.
+//The signatures of a policy that needs more than one party are kept until the grant is revoked
+
+//object type of the composite key of the revocation policies
+const revocationObjectType = "revocation"
+
+//the rules of a revocation policy
+const (
+	ruleAnyOf    = "any-of"    //one of the parties
+	ruleAllOf    = "all-of"    //every party
+	ruleKOfN     = "k-of-n"    //K of the parties
+	ruleOrgAdmin = "org-admin" //an admin of the organization of the grandor
+)
+
+//RevocationPolicy describes who has to revoke a grant for it to be revoked
+type RevocationPolicy struct {
+	Grant     string   `json:"grant"`                                 //pck of the Delegation or SubDelegation
+	Rule      string   `json:"rule"`                                  //any-of, all-of, k-of-n or org-admin
+	Parties   []string `json:"parties" metadata:",optional"`          //revokers the rule counts, all of the Revokers when empty
+	K         uint8    `json:"k"`                                     //signatures a k-of-n rule needs
+	NotBefore uint64   `json:"notbefore"`                             //Unix seconds before which the grant cannot be revoked, 0 for none
+	Signed    []string `json:"signed,omitempty" metadata:",optional"` //parties that have signed the revocation so far
+	Type      string   `json:"Type,omitempty" metadata:",optional"`   //RP for RevocationPolicy
+}
+
+//revocationKey returns the key of the revocation policy of a grant
+func revocationKey(ctx contractapi.TransactionContextInterface, pck string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(revocationObjectType, []string{pck})
+	if err != nil {
+		return "", fmt.Errorf("Failed to create the revocation policy key. %s", err.Error())
+	}
+	return key, nil
+}
+
+//putRevocationPolicy stores the revocation policy of a grant
+func putRevocationPolicy(ctx contractapi.TransactionContextInterface, policy *RevocationPolicy) error {
+	key, err := revocationKey(ctx, policy.Grant)
+	if err != nil {
+		return err
+	}
+
+	policyAsBytes, _ := json.Marshal(policy)
+
+	return ctx.GetStub().PutState(key, policyAsBytes)
+}
+
+//revocationPolicy returns the revocation policy of a grant, the default any-of policy over its Revokers
+//when none was set
+func revocationPolicy(ctx contractapi.TransactionContextInterface, grant *Delegation) (*RevocationPolicy, error) {
+	key, err := revocationKey(ctx, grant.Pck)
+	if err != nil {
+		return nil, err
+	}
+	policyAsBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+
+	policy := &RevocationPolicy{Grant: grant.Pck, Rule: ruleAnyOf, Parties: []string{}, Signed: []string{}, Type: "RP"}
+	if policyAsBytes != nil {
+		_ = json.Unmarshal(policyAsBytes, policy)
+	}
+	if len(policy.Parties) == 0 {
+		policy.Parties = grant.Revokers
+	}
+
+	return policy, nil
+}
+
+//authorizeParty checks that the submitting identity can sign for a party, only the owning organization
+//can sign for a service and only its identity for a tenant bound to one
+func (s *SmartContract) authorizeParty(ctx contractapi.TransactionContextInterface, party string) error {
+	entity, err := s.IsService(ctx, party)
+	if err != nil {
+		return err
+	}
+	if entity.Type != "S" {
+		return s.authorizeTenant(ctx, party)
+	}
+
+	return authorizeServiceOwner(ctx, entity)
+}
+
+//authorizeSuspension checks that the submitting identity can suspend or resume a grant of grandor, the
+//grandor can as can billing identities and platform admins
+func (s *SmartContract) authorizeSuspension(ctx contractapi.TransactionContextInterface, grandor string) error {
+	if s.authorizeParty(ctx, grandor) == nil || requireBilling(ctx) == nil {
+		return nil
+	}
+	return fmt.Errorf("Only the grandor %s, a billing identity or a platform admin can suspend or resume its grants", grandor)
+}
+
+//isOrgAdmin checks if the submitting identity is an admin of the organization mspid, Fabric gives the
+//admins of an organization the admin organizational unit and the role registry can name others
+func isOrgAdmin(ctx contractapi.TransactionContextInterface, mspid string) (bool, error) {
+	clientmsp, err := clientMSPID(ctx)
+	if err != nil {
+		return false, err
+	}
+	if mspid == "" || clientmsp != mspid {
+		return false, nil
+	}
+
+	admin, err := hasRole(ctx, roleOrgAdmin, "")
+	if err != nil || admin {
+		return admin, err
+	}
+
+	certificate, err := cid.GetX509Certificate(ctx.GetStub())
+	if err != nil {
+		return false, fmt.Errorf("Failed to read the certificate of the client. %s", err.Error())
+	}
+
+	return stringInSlice("admin", certificate.Subject.OrganizationalUnit), nil
+}
+
+//grandorMSP returns the organization of the grandor of a grant, the owning organization of the service
+//at the root of the chain when the grandor is a tenant
+func (s *SmartContract) grandorMSP(ctx contractapi.TransactionContextInterface, grant *Delegation) (string, error) {
+	grandor, err := s.IsService(ctx, grant.Grandor)
+	if err != nil {
+		return "", err
+	}
+	if grandor.Type != "S" {
+		if grandor, err = s.rootService(ctx, grant); err != nil {
+			return "", err
+		}
+	}
+
+	return grandor.OwnerMSP, nil
+}
+
+//rewriteRevocationParties replaces previous with recipient among the parties of the revocation policy of
+//a grant that moved to a new recipient, a signature previous gave is dropped
+func rewriteRevocationParties(ctx contractapi.TransactionContextInterface, pck string, previous string, recipient string) error {
+	key, err := revocationKey(ctx, pck)
+	if err != nil {
+		return err
+	}
+	policyAsBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if policyAsBytes == nil {
+		return nil
+	}
+
+	policy := new(RevocationPolicy)
+	_ = json.Unmarshal(policyAsBytes, policy)
+	if !stringInSlice(previous, policy.Parties) && !stringInSlice(previous, policy.Signed) {
+		return nil
+	}
+	policy.Parties = replaceInSlice(policy.Parties, previous, recipient)
+	policy.Signed = removeFromSlice(policy.Signed, previous)
+
+	return putRevocationPolicy(ctx, policy)
+}
+
+//signRevocation signs the revocation of the grant with given Pck (Key) for revoker and reports if its
+//policy is satisfied, the signature is kept when more are needed
+func (s *SmartContract) signRevocation(ctx contractapi.TransactionContextInterface, pck string, revoker string) (bool, error) {
+	//Delegations and SubDelegations share the same fields
+	grant, err := s.IsDelegation(ctx, pck)
+	if err != nil {
+		return false, err
+	}
+	policy, err := revocationPolicy(ctx, grant)
+	if err != nil {
+		return false, err
+	}
+
+	now, err := txSeconds(ctx)
+	if err != nil {
+		return false, err
+	}
+	if policy.NotBefore > uint64(now) {
+		return false, fmt.Errorf("%s cannot be revoked before %d", grant.Pck, policy.NotBefore)
+	}
+
+	if policy.Rule == ruleOrgAdmin {
+		mspid, err := s.grandorMSP(ctx, grant)
+		if err != nil {
+			return false, err
+		}
+		admin, err := isOrgAdmin(ctx, mspid)
+		if err != nil {
+			return false, err
+		}
+		if !admin {
+			return false, fmt.Errorf("%s can only be revoked by an admin of %s", grant.Pck, mspid)
+		}
+		return true, nil
+	}
+
+	if !stringInSlice(revoker, policy.Parties) {
+		return false, fmt.Errorf("%s is not an authorized Revoker", revoker)
+	}
+	if err := s.authorizeParty(ctx, revoker); err != nil {
+		return false, err
+	}
+	if !stringInSlice(revoker, policy.Signed) {
+		policy.Signed = append(policy.Signed, revoker)
+	}
+
+	needed := 1
+	if policy.Rule == ruleAllOf {
+		needed = len(policy.Parties)
+	} else if policy.Rule == ruleKOfN {
+		needed = int(policy.K)
+	}
+	if len(policy.Signed) >= needed {
+		return true, nil
+	}
+
+	//the grant stays valid until enough parties have signed
+	key, err := revocationKey(ctx, grant.Pck)
+	if err != nil {
+		return false, err
+	}
+	policyAsBytes, _ := json.Marshal(policy)
+	if err := ctx.GetStub().PutState(key, policyAsBytes); err != nil {
+		return false, err
+	}
+	if err := ctx.GetStub().SetEvent("RevocationSigned", policyAsBytes); err != nil {
+		return false, err
+	}
+
+	return false, nil
+}
+
+//SetRevocationPolicy sets the revocation policy of the Delegation or SubDelegation with given Pck (Key),
+//policy is a JSON object such as {"rule":"k-of-n","parties":["S1","T2","T4"],"k":2,"notbefore":0}. It
+//must be submitted for the grandor and clears the signatures collected under the previous policy
+func (s *SmartContract) SetRevocationPolicy(ctx contractapi.TransactionContextInterface, pck string, policy string) error {
+	grant, err := s.IsDelegation(ctx, pck)
+	if err != nil {
+		return err
+	}
+	if grant.Type != "D" && grant.Type != "SD" {
+		return fmt.Errorf("%s is not a Delegation or SubDelegation", pck)
+	}
+	if grant.Revoked {
+		return fmt.Errorf("%s has already been revoked", pck)
+	}
+	if err := s.authorizeParty(ctx, grant.Grandor); err != nil {
+		return err
+	}
+
+	newpolicy := new(RevocationPolicy)
+	decoder := json.NewDecoder(bytes.NewReader([]byte(policy)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(newpolicy); err != nil {
+		return fmt.Errorf("policy must be a JSON object. %s", err.Error())
+	}
+
+	for _, party := range newpolicy.Parties {
+		if !stringInSlice(party, grant.Revokers) {
+			return fmt.Errorf("%s is not one of the Revokers of %s", party, pck)
+		}
+	}
+	parties := len(newpolicy.Parties)
+	if parties == 0 {
+		parties = len(grant.Revokers)
+	}
+
+	switch newpolicy.Rule {
+	case ruleAnyOf, ruleAllOf, ruleOrgAdmin:
+		newpolicy.K = 0
+	case ruleKOfN:
+		if newpolicy.K == 0 || int(newpolicy.K) > parties {
+			return fmt.Errorf("k must be between 1 and the %d parties", parties)
+		}
+	default:
+		return fmt.Errorf("rule must be %s, %s, %s or %s", ruleAnyOf, ruleAllOf, ruleKOfN, ruleOrgAdmin)
+	}
+
+	newpolicy.Grant = pck
+	newpolicy.Signed = []string{}
+	newpolicy.Type = "RP"
+	if newpolicy.Parties == nil {
+		newpolicy.Parties = []string{}
+	}
+
+	return putRevocationPolicy(ctx, newpolicy)
+}
+
+//GetRevocationPolicy returns the revocation policy of the Delegation or SubDelegation with given Pck (Key)
+//and the signatures collected so far
+func (s *SmartContract) GetRevocationPolicy(ctx contractapi.TransactionContextInterface, pck string) (*RevocationPolicy, error) {
+	grant, err := s.IsDelegation(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+	if grant.Type != "D" && grant.Type != "SD" {
+		return nil, fmt.Errorf("%s is not a Delegation or SubDelegation", pck)
+	}
+
+	return revocationPolicy(ctx, grant)
+}
+
+//--------------------------------------End Of Revocation Policies-----------------------------------
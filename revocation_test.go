@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOnlyAPartySuspendsAndResumesAGrant(t *testing.T) {
+	l := initLedger(t)
+	l.grant("D1", "S1", "S2", nil)
+	l.subgrant("SD1", "D1", "T3", 1, nil)
+
+	l.as("Org3MSP", certificate(t, "nobody", false, ""))
+	l.refused("a suspension by an outsider", l.SuspendDelegation("D1"))
+	l.refused("a suspension by an outsider", l.SuspendSubDelegation("SD1"))
+	l.asAdmin()
+	l.ok("SuspendSubDelegation", l.SuspendSubDelegation("SD1"))
+	l.as("Org3MSP", certificate(t, "nobody", false, ""))
+	l.refused("a resumption by an outsider", l.ResumeSubDelegation("SD1"))
+
+	l.asAdmin()
+	l.ok("ResumeSubDelegation", l.ResumeSubDelegation("SD1"))
+	if valid, _ := l.IsSubValid("SD1"); !valid {
+		t.Fatal("SD1 is not valid once resumed")
+	}
+	l.refused("resuming a grant that is not suspended", l.ResumeDelegation("D1"))
+
+	//a grant held by a dunning is only reinstated by the payment
+	l.ok("IssueInvoice", l.IssueInvoice("T3", "I1", "10", time.Now()))
+	overdue()
+	_, err := l.RunDunning("T3", 0)
+	l.ok("RunDunning", err)
+	l.refused("resuming a dunned grant", l.ResumeSubDelegation("SD1"))
+}
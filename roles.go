@@ -0,0 +1,522 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+//***************************************************************************************************
+//**																							   **
+//**							The following section Manages the Roles                            **
+//**                                                                                               **
+//***************************************************************************************************
+
+//-------------------------------------Role Management-----------------------------------------------
+//the role registry assigns roles to client identities, an identity is the subject and issuer of its
+//certificate as given by cid.GetID and is bound to the organization it was assigned for. The first call
+//of InitLedger, made by the admin of the deploying organization when the chaincode is instantiated, makes
+//that admin the platform admin and the admin of its organization. It must carry the admin organizational
+//unit of the organization named by InitLedger, or of its own when none is named, and the registry is only
+//bootstrapped once. From then on InitLedger and the destructive operations need an admin.
+//Platform admins assign every role, organization admins the org-admin, service-operator and tenant roles
+//to identities of their own organization. The roles that reach beyond one organization, platform admin,
+//billing and auditor, are only assigned by platform admins. Every assignment and revocation is recorded in the role audit trail
+
+//object types of the composite keys of the role registry
+const (
+	roleObjectType       = "role"
+	roleChangeObjectType = "rolechange"
+	bootstrapObjectType  = "bootstrap"
+)
+
+//the roles of the registry, the scope of a role names what it applies to
+const (
+	rolePlatformAdmin   = "platform-admin"   //manages the platform, no scope
+	roleOrgAdmin        = "org-admin"        //manages the organization it was assigned for, no scope
+	roleServiceOperator = "service-operator" //manages the service given as scope as its owning organization does
+	roleAuditor         = "auditor"          //reads the role registry and its audit trail, no scope
+	roleBilling         = "billing"          //manages the wallets and invoices of the tenants, no scope
+	roleTenant          = "tenant"           //acts for the tenant given as scope
+)
+
+//roles lists every role of the registry
+var roles = []string{rolePlatformAdmin, roleOrgAdmin, roleServiceOperator, roleAuditor, roleBilling, roleTenant}
+
+//RoleAssignment gives a role to a client identity
+type RoleAssignment struct {
+	Identity  string `json:"identity"`  //the client identity as given by cid.GetID
+	MSP       string `json:"msp"`       //organization of the identity
+	Role      string `json:"role"`      //one of roles
+	Scope     string `json:"scope"`     //pck of the service or tenant the role applies to, empty for the others
+	GrantedBy string `json:"grantedby"` //identity that assigned the role
+	GrantedAt int64  `json:"grantedat"` //Unix seconds of the assignment
+	Type      string `json:"Type"`      //RA for RoleAssignment
+}
+
+//RoleChange is an entry of the role audit trail
+type RoleChange struct {
+	TxID      string `json:"txid"`
+	Timestamp int64  `json:"timestamp"`
+	Actor     string `json:"actor"`    //identity that made the change
+	ActorMSP  string `json:"actormsp"` //organization of the actor
+	Action    string `json:"action"`   //bootstrap, assign or revoke
+	Identity  string `json:"identity"`
+	MSP       string `json:"msp"`
+	Role      string `json:"role"`
+	Scope     string `json:"scope"`
+	Type      string `json:"Type"` //RC for RoleChange
+}
+
+//Bootstrap records the bootstrap of the role registry, there is only one
+type Bootstrap struct {
+	Identity  string `json:"identity"`  //the identity that became the platform admin
+	MSP       string `json:"msp"`       //the deploying organization
+	TxID      string `json:"txid"`      //transaction that bootstrapped the registry
+	Timestamp int64  `json:"timestamp"` //Unix seconds of the transaction
+	Type      string `json:"Type"`      //RB for Role Bootstrap
+}
+
+//CallerIdentity describes the submitting identity and the roles it holds
+type CallerIdentity struct {
+	Identity string            `json:"identity"`
+	MSP      string            `json:"msp"`
+	Roles    []*RoleAssignment `json:"roles"`
+}
+
+//clientID returns the identity of the submitting client
+func clientID(ctx contractapi.TransactionContextInterface) (string, error) {
+	id, err := cid.GetID(ctx.GetStub())
+	if err != nil {
+		return "", fmt.Errorf("Failed to read the identity of the client. %s", err.Error())
+	}
+	return id, nil
+}
+
+//roleKey returns the key of an assignment, the role comes first so the holders of a role can be listed
+func roleKey(ctx contractapi.TransactionContextInterface, role string, identity string, scope string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(roleObjectType, []string{role, identity, scope})
+	if err != nil {
+		return "", fmt.Errorf("Failed to create the role key. %s", err.Error())
+	}
+	return key, nil
+}
+
+//findRole returns the assignment of role with scope to identity, nil when there is none
+func findRole(ctx contractapi.TransactionContextInterface, role string, identity string, scope string) (*RoleAssignment, error) {
+	key, err := roleKey(ctx, role, identity, scope)
+	if err != nil {
+		return nil, err
+	}
+	assignmentAsBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if assignmentAsBytes == nil {
+		return nil, nil
+	}
+
+	assignment := new(RoleAssignment)
+	_ = json.Unmarshal(assignmentAsBytes, assignment)
+
+	return assignment, nil
+}
+
+//roleAssignments returns the assignments whose key starts with attributes
+func roleAssignments(ctx contractapi.TransactionContextInterface, attributes ...string) ([]*RoleAssignment, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(roleObjectType, attributes)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	defer iterator.Close()
+
+	assignments := []*RoleAssignment{}
+	for iterator.HasNext() {
+		response, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+		}
+
+		assignment := new(RoleAssignment)
+		_ = json.Unmarshal(response.Value, assignment)
+		assignments = append(assignments, assignment)
+	}
+
+	return assignments, nil
+}
+
+//hasRole checks if the submitting identity holds role with scope for its own organization
+func hasRole(ctx contractapi.TransactionContextInterface, role string, scope string) (bool, error) {
+	identity, err := clientID(ctx)
+	if err != nil {
+		return false, err
+	}
+	mspid, err := clientMSPID(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	assignment, err := findRole(ctx, role, identity, scope)
+	if err != nil {
+		return false, err
+	}
+
+	return assignment != nil && assignment.MSP == mspid, nil
+}
+
+//requirePlatformAdmin checks that the submitting identity is a platform admin
+func requirePlatformAdmin(ctx contractapi.TransactionContextInterface) error {
+	admin, err := hasRole(ctx, rolePlatformAdmin, "")
+	if err != nil {
+		return err
+	}
+	if !admin {
+		return fmt.Errorf("Only a platform admin is authorized to do this")
+	}
+	return nil
+}
+
+//requireAdmin checks that the submitting identity is a platform admin or an admin of the organization
+//mspid
+func requireAdmin(ctx contractapi.TransactionContextInterface, mspid string) error {
+	admin, err := hasRole(ctx, rolePlatformAdmin, "")
+	if err != nil || admin {
+		return err
+	}
+	if admin, err = isOrgAdmin(ctx, mspid); err != nil || admin {
+		return err
+	}
+	return fmt.Errorf("Only a platform admin or an admin of %s is authorized to do this", mspid)
+}
+
+//requireBilling checks that the submitting identity can manage the wallets and invoices of the tenants,
+//billing identities and platform admins can
+func requireBilling(ctx contractapi.TransactionContextInterface) error {
+	for _, role := range []string{roleBilling, rolePlatformAdmin} {
+		allowed, err := hasRole(ctx, role, "")
+		if err != nil || allowed {
+			return err
+		}
+	}
+	return fmt.Errorf("Only a billing identity or a platform admin is authorized to do this")
+}
+
+//recordRoleChange appends a change of the registry to the role audit trail and emits it as event
+func recordRoleChange(ctx contractapi.TransactionContextInterface, action string, assignment *RoleAssignment) error {
+	actor, err := clientID(ctx)
+	if err != nil {
+		return err
+	}
+	actormsp, err := clientMSPID(ctx)
+	if err != nil {
+		return err
+	}
+	timestamp, err := txSeconds(ctx)
+	if err != nil {
+		return err
+	}
+
+	change := RoleChange{
+		TxID:      ctx.GetStub().GetTxID(),
+		Timestamp: timestamp,
+		Actor:     actor,
+		ActorMSP:  actormsp,
+		Action:    action,
+		Identity:  assignment.Identity,
+		MSP:       assignment.MSP,
+		Role:      assignment.Role,
+		Scope:     assignment.Scope,
+		Type:      "RC",
+	}
+
+	//the padded timestamp keeps the audit trail in the order the changes were made, a transaction can
+	//make more than one change so the role and identity keep the keys apart
+	key, err := ctx.GetStub().CreateCompositeKey(roleChangeObjectType, []string{fmt.Sprintf("%020d", timestamp), change.TxID, assignment.Role, assignment.Identity, assignment.Scope})
+	if err != nil {
+		return fmt.Errorf("Failed to create the role change key. %s", err.Error())
+	}
+	changeAsBytes, _ := json.Marshal(change)
+	if err := ctx.GetStub().PutState(key, changeAsBytes); err != nil {
+		return err
+	}
+
+	return ctx.GetStub().SetEvent("RoleChanged", changeAsBytes)
+}
+
+//putRole stores an assignment and records it in the audit trail
+func putRole(ctx contractapi.TransactionContextInterface, action string, assignment *RoleAssignment) error {
+	key, err := roleKey(ctx, assignment.Role, assignment.Identity, assignment.Scope)
+	if err != nil {
+		return err
+	}
+	assignmentAsBytes, _ := json.Marshal(assignment)
+	if err := ctx.GetStub().PutState(key, assignmentAsBytes); err != nil {
+		return err
+	}
+
+	return recordRoleChange(ctx, action, assignment)
+}
+
+//bootstrapRoles makes the submitting identity the platform admin and the admin of its organization when
+//the registry has never been bootstrapped, and reports if it did. adminmsp is the deploying organization,
+//the submitting identity must be one of its admins, and is its own organization when empty
+func bootstrapRoles(ctx contractapi.TransactionContextInterface, adminmsp string) (bool, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(bootstrapObjectType, []string{})
+	if err != nil {
+		return false, fmt.Errorf("Failed to create the bootstrap key. %s", err.Error())
+	}
+	bootstrapAsBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return false, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	//registries bootstrapped before the record existed already have a platform admin
+	admins, err := roleAssignments(ctx, rolePlatformAdmin)
+	if err != nil {
+		return false, err
+	}
+	if bootstrapAsBytes != nil || len(admins) > 0 {
+		bootstrap := new(Bootstrap)
+		_ = json.Unmarshal(bootstrapAsBytes, bootstrap)
+		if adminmsp != "" && bootstrap.MSP != "" && adminmsp != bootstrap.MSP {
+			return false, fmt.Errorf("The role registry has already been bootstrapped for %s", bootstrap.MSP)
+		}
+		return false, nil
+	}
+
+	identity, err := clientID(ctx)
+	if err != nil {
+		return false, err
+	}
+	mspid, err := clientMSPID(ctx)
+	if err != nil {
+		return false, err
+	}
+	if adminmsp == "" {
+		adminmsp = mspid
+	}
+	certificate, err := cid.GetX509Certificate(ctx.GetStub())
+	if err != nil {
+		return false, fmt.Errorf("Failed to read the certificate of the client. %s", err.Error())
+	}
+	if mspid != adminmsp || !stringInSlice("admin", certificate.Subject.OrganizationalUnit) {
+		return false, fmt.Errorf("Only an admin of %s can bootstrap the role registry", adminmsp)
+	}
+	timestamp, err := txSeconds(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	bootstrapAsBytes, _ = json.Marshal(Bootstrap{Identity: identity, MSP: mspid, TxID: ctx.GetStub().GetTxID(), Timestamp: timestamp, Type: "RB"})
+	if err := ctx.GetStub().PutState(key, bootstrapAsBytes); err != nil {
+		return false, err
+	}
+
+	for _, role := range []string{rolePlatformAdmin, roleOrgAdmin} {
+		assignment := &RoleAssignment{Identity: identity, MSP: mspid, Role: role, GrantedBy: identity, GrantedAt: timestamp, Type: "RA"}
+		if err := putRole(ctx, "bootstrap", assignment); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+//globalRoles lists the roles that apply across every organization
+var globalRoles = []string{rolePlatformAdmin, roleBilling, roleAuditor}
+
+//authorizeRoleChange checks that the submitting identity can assign or revoke role for identities of
+//the organization mspid
+func authorizeRoleChange(ctx contractapi.TransactionContextInterface, mspid string, role string) error {
+	if stringInSlice(role, globalRoles) {
+		return requirePlatformAdmin(ctx)
+	}
+
+	admin, err := hasRole(ctx, rolePlatformAdmin, "")
+	if err != nil || admin {
+		return err
+	}
+	clientmsp, err := clientMSPID(ctx)
+	if err != nil {
+		return err
+	}
+	if clientmsp == mspid {
+		if admin, err = hasRole(ctx, roleOrgAdmin, ""); err != nil || admin {
+			return err
+		}
+	}
+
+	return fmt.Errorf("Only a platform admin or an admin of %s is authorized to change its roles", mspid)
+}
+
+//checkRoleScope checks that scope names what role applies to
+func (s *SmartContract) checkRoleScope(ctx contractapi.TransactionContextInterface, role string, scope string) error {
+	switch role {
+	case rolePlatformAdmin, roleOrgAdmin, roleAuditor, roleBilling:
+		if scope != "" {
+			return fmt.Errorf("The %s role has no scope", role)
+		}
+		return nil
+	case roleServiceOperator, roleTenant:
+		entity, err := s.IsService(ctx, scope)
+		if err != nil {
+			return err
+		}
+		if role == roleServiceOperator && entity.Type != "S" {
+			return fmt.Errorf("The scope of the %s role must be a Service", role)
+		}
+		if role == roleTenant && entity.Type != "T" {
+			return fmt.Errorf("The scope of the %s role must be a Tenant", role)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("role must be one of %v", roles)
+}
+
+//AssignRole gives role to the client identity of the organization mspid, scope is the pck of the service
+//of a service-operator or of the tenant of a tenant role and empty for the others
+func (s *SmartContract) AssignRole(ctx contractapi.TransactionContextInterface, identity string, mspid string, role string, scope string) error {
+	if identity == "" || mspid == "" {
+		return fmt.Errorf("identity and msp must be given")
+	}
+	if err := s.checkRoleScope(ctx, role, scope); err != nil {
+		return err
+	}
+	if err := authorizeRoleChange(ctx, mspid, role); err != nil {
+		return err
+	}
+
+	existing, err := findRole(ctx, role, identity, scope)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("%s already holds the %s role", identity, role)
+	}
+
+	grantedby, err := clientID(ctx)
+	if err != nil {
+		return err
+	}
+	timestamp, err := txSeconds(ctx)
+	if err != nil {
+		return err
+	}
+
+	assignment := &RoleAssignment{Identity: identity, MSP: mspid, Role: role, Scope: scope, GrantedBy: grantedby, GrantedAt: timestamp, Type: "RA"}
+
+	return putRole(ctx, "assign", assignment)
+}
+
+//RevokeRole takes role with scope away from the client identity, the last platform admin cannot be
+//revoked
+func (s *SmartContract) RevokeRole(ctx contractapi.TransactionContextInterface, identity string, role string, scope string) error {
+	assignment, err := findRole(ctx, role, identity, scope)
+	if err != nil {
+		return err
+	}
+	if assignment == nil {
+		return fmt.Errorf("%s does not hold the %s role", identity, role)
+	}
+	if err := authorizeRoleChange(ctx, assignment.MSP, role); err != nil {
+		return err
+	}
+
+	if role == rolePlatformAdmin {
+		admins, err := roleAssignments(ctx, rolePlatformAdmin)
+		if err != nil {
+			return err
+		}
+		if len(admins) == 1 {
+			return fmt.Errorf("The last platform admin cannot be revoked")
+		}
+	}
+
+	key, err := roleKey(ctx, role, identity, scope)
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().DelState(key); err != nil {
+		return err
+	}
+
+	return recordRoleChange(ctx, "revoke", assignment)
+}
+
+//WhoAmI returns the identity of the submitting client and the roles it holds, the identity is what
+//an admin needs to assign it a role
+func (s *SmartContract) WhoAmI(ctx contractapi.TransactionContextInterface) (*CallerIdentity, error) {
+	identity, err := clientID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mspid, err := clientMSPID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	assignments, err := roleAssignments(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	caller := &CallerIdentity{Identity: identity, MSP: mspid, Roles: []*RoleAssignment{}}
+	for _, assignment := range assignments {
+		if assignment.Identity == identity && assignment.MSP == mspid {
+			caller.Roles = append(caller.Roles, assignment)
+		}
+	}
+
+	return caller, nil
+}
+
+//requireAuditor checks that the submitting identity can read the role registry, auditors and admins can
+func requireAuditor(ctx contractapi.TransactionContextInterface) error {
+	for _, role := range []string{roleAuditor, rolePlatformAdmin, roleOrgAdmin} {
+		allowed, err := hasRole(ctx, role, "")
+		if err != nil || allowed {
+			return err
+		}
+	}
+	return fmt.Errorf("Only auditors and admins are authorized to read the role registry")
+}
+
+//GetRoleAssignments returns every assignment of the role registry, to auditors and admins
+func (s *SmartContract) GetRoleAssignments(ctx contractapi.TransactionContextInterface) ([]*RoleAssignment, error) {
+	if err := requireAuditor(ctx); err != nil {
+		return nil, err
+	}
+
+	return roleAssignments(ctx)
+}
+
+//GetRoleChanges returns the role audit trail in the order the changes were made, to auditors and admins
+func (s *SmartContract) GetRoleChanges(ctx contractapi.TransactionContextInterface) ([]*RoleChange, error) {
+	if err := requireAuditor(ctx); err != nil {
+		return nil, err
+	}
+
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(roleChangeObjectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	defer iterator.Close()
+
+	changes := []*RoleChange{}
+	for iterator.HasNext() {
+		response, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+		}
+
+		change := new(RoleChange)
+		_ = json.Unmarshal(response.Value, change)
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+//--------------------------------------End Of Role Management---------------------------------------
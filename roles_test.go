@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+func TestInitLedgerBootstrapsTheDeployingAdminOnce(t *testing.T) {
+	l := newLedger(t)
+
+	l.as("Org1MSP", certificate(t, "user1", false, ""))
+	l.refused("a bootstrap by a member that is not an admin", l.InitLedger(""))
+	l.as("Org2MSP", certificate(t, "admin2", true, ""))
+	l.refused("a bootstrap by the admin of another organization", l.InitLedger("Org1MSP"))
+
+	l.asAdmin()
+	l.ok("bootstrap", l.InitLedger("Org1MSP"))
+	l.refused("a bootstrap for another organization", l.InitLedger("Org2MSP"))
+	l.ok("InitLedger by the platform admin", l.InitLedger(""))
+
+	l.as("Org2MSP", certificate(t, "admin2", true, ""))
+	l.refused("a second bootstrap", l.InitLedger(""))
+}
+
+func TestOnlyAPlatformAdminAssignsTheGlobalRoles(t *testing.T) {
+	l := initLedger(t)
+	orgadmin := certificate(t, "admin2", true, "")
+
+	l.as("Org2MSP", orgadmin)
+	me, err := l.WhoAmI()
+	l.ok("WhoAmI", err)
+	l.asAdmin()
+	l.ok("AssignRole org-admin", l.AssignRole(me.Identity, "Org2MSP", "org-admin", ""))
+
+	l.as("Org2MSP", orgadmin)
+	for _, role := range []string{"platform-admin", "billing", "auditor"} {
+		l.refused("an org admin assigning "+role, l.AssignRole(me.Identity, "Org2MSP", role, ""))
+	}
+	l.ok("AssignRole service-operator", l.AssignRole(me.Identity, "Org2MSP", "service-operator", "S2"))
+
+	l.asAdmin()
+	l.ok("AssignRole billing", l.AssignRole(me.Identity, "Org2MSP", "billing", ""))
+}
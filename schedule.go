@@ -0,0 +1,381 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+//***************************************************************************************************
+//**																							   **
+//**							The following section Manages the Schedules                        **
+//**                                                                                               **
+//***************************************************************************************************
+
+//-------------------------------------Schedules-----------------------------------------------------
+//a schedule makes a grant recurring, inside its Issue and Expiry the grant is only active during the
+//windows of its schedule, such as weekdays from 09:00 to 18:00, the first day of every month or a fixed
+//period in the future. A grant without a schedule is active for its whole validity, as before. The
+//windows are read in the time of the schedule, a fixed offset from UTC so every peer evaluates them the
+//same way. A SubDelegation is only active when every grant of its chain is, IsValid and IsSubValid
+//check the schedules and ChargingDel only bills the hours of the usage samples that fall in a window
+
+//object type of the composite key of the schedules
+const scheduleObjectType = "schedule"
+
+//the kinds of schedule windows
+const (
+	windowWeekly  = "weekly"  //on the Days of every week from Start to End
+	windowMonthly = "monthly" //on the Day of every month from Start to End
+	windowOnce    = "once"    //from the Unix time From until the Unix time Until
+)
+
+//how far ahead NextActivation looks for a window, two months covers every monthly window
+const scheduleHorizonDays = 62
+
+//weekdays are the names of the Days of a weekly window, in the order of time.Weekday
+var weekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+//ScheduleWindow is a period in which a scheduled grant is active. Start and End are HH:MM, a window that
+//ends before it starts runs past midnight into the next day. Monthly windows on a day a month does not
+//have are skipped that month
+type ScheduleWindow struct {
+	Kind  string   `json:"kind"`                                 //weekly, monthly or once
+	Days  []string `json:"days,omitempty" metadata:",optional"`  //mon, tue, wed, thu, fri, sat or sun, for weekly windows
+	Day   uint8    `json:"day,omitempty" metadata:",optional"`   //day of the month, for monthly windows
+	Start string   `json:"start,omitempty" metadata:",optional"` //HH:MM the window opens, for weekly and monthly windows
+	End   string   `json:"end,omitempty" metadata:",optional"`   //HH:MM the window closes
+	From  uint64   `json:"from,omitempty" metadata:",optional"`  //Unix seconds the window opens, for once windows
+	Until uint64   `json:"until,omitempty" metadata:",optional"` //Unix seconds the window closes
+}
+
+//Schedule lists the windows in which a grant is active
+type Schedule struct {
+	Grant     string           `json:"grant"`                               //pck of the Delegation or SubDelegation
+	UTCOffset int              `json:"utcoffset"`                           //minutes the time of the windows is ahead of UTC
+	Windows   []ScheduleWindow `json:"windows"`                             //the grant is always active when empty
+	Type      string           `json:"Type,omitempty" metadata:",optional"` //SC for Schedule
+}
+
+//clockMinutes parses an HH:MM time of day into minutes after midnight, 24:00 is the end of the day
+func clockMinutes(clock string) (int, error) {
+	parts := strings.Split(clock, ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("%q is not a HH:MM time", clock)
+	}
+	hours, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a HH:MM time", clock)
+	}
+	minutes, err := strconv.Atoi(parts[1])
+	if err != nil || hours < 0 || minutes < 0 || minutes > 59 || hours*60+minutes > 24*60 {
+		return 0, fmt.Errorf("%q is not a HH:MM time", clock)
+	}
+	return hours*60 + minutes, nil
+}
+
+//check validates a window
+func (w ScheduleWindow) check() error {
+	switch w.Kind {
+	case windowOnce:
+		if w.From >= w.Until {
+			return fmt.Errorf("A once window must open before it closes")
+		}
+		return nil
+	case windowWeekly:
+		if len(w.Days) == 0 {
+			return fmt.Errorf("A weekly window needs days")
+		}
+		for _, day := range w.Days {
+			if !stringInSlice(day, weekdays) {
+				return fmt.Errorf("%q is not one of %s", day, strings.Join(weekdays, ", "))
+			}
+		}
+	case windowMonthly:
+		if w.Day < 1 || w.Day > 31 {
+			return fmt.Errorf("The day of a monthly window must be between 1 and 31")
+		}
+	default:
+		return fmt.Errorf("kind must be %s, %s or %s", windowWeekly, windowMonthly, windowOnce)
+	}
+
+	start, err := clockMinutes(w.Start)
+	if err != nil {
+		return err
+	}
+	end, err := clockMinutes(w.End)
+	if err != nil {
+		return err
+	}
+	if start == end {
+		return fmt.Errorf("A window must not open and close at the same time")
+	}
+	return nil
+}
+
+//opensOn checks if a weekly or monthly window opens on the day of t
+func (w ScheduleWindow) opensOn(t time.Time) bool {
+	if w.Kind == windowWeekly {
+		return stringInSlice(weekdays[t.Weekday()], w.Days)
+	}
+	return t.Day() == int(w.Day)
+}
+
+//contains checks if the window is open at t, given in the time of the schedule
+func (w ScheduleWindow) contains(t time.Time) bool {
+	if w.Kind == windowOnce {
+		unix := uint64(t.Unix())
+		return w.From <= unix && unix < w.Until
+	}
+
+	//the windows were checked when the schedule was set
+	start, _ := clockMinutes(w.Start)
+	end, _ := clockMinutes(w.End)
+	minute := t.Hour()*60 + t.Minute()
+
+	if start < end {
+		return w.opensOn(t) && start <= minute && minute < end
+	}
+	//a window past midnight is open from Start on the day it opens and until End on the day after
+	return (w.opensOn(t) && minute >= start) || (w.opensOn(t.AddDate(0, 0, -1)) && minute < end)
+}
+
+//active checks if the schedule lets its grant be used at the Unix time t
+func (sc *Schedule) active(t uint64) bool {
+	if len(sc.Windows) == 0 {
+		return true
+	}
+
+	local := time.Unix(int64(t), 0).In(time.FixedZone("", sc.UTCOffset*60))
+	for _, window := range sc.Windows {
+		if window.contains(local) {
+			return true
+		}
+	}
+	return false
+}
+
+//scheduleKey returns the key of the schedule of a grant
+func scheduleKey(ctx contractapi.TransactionContextInterface, pck string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(scheduleObjectType, []string{pck})
+	if err != nil {
+		return "", fmt.Errorf("Failed to create the schedule key. %s", err.Error())
+	}
+	return key, nil
+}
+
+//grantSchedule returns the schedule of a grant, one without windows when none was set
+func grantSchedule(ctx contractapi.TransactionContextInterface, pck string) (*Schedule, error) {
+	key, err := scheduleKey(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+	scheduleAsBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+
+	schedule := &Schedule{Grant: pck, Windows: []ScheduleWindow{}, Type: "SC"}
+	if scheduleAsBytes != nil {
+		_ = json.Unmarshal(scheduleAsBytes, schedule)
+	}
+
+	return schedule, nil
+}
+
+//chainSchedules returns the schedules of the grant pck and of the grants of its chain it was subdelegated
+//from, only the schedules that have windows
+func chainSchedules(ctx contractapi.TransactionContextInterface, pck string, chain []string) ([]*Schedule, error) {
+	if !stringInSlice(pck, chain) {
+		chain = append([]string{pck}, chain...)
+	}
+
+	schedules := []*Schedule{}
+	for _, pck := range chain {
+		schedule, err := grantSchedule(ctx, pck)
+		if err != nil {
+			return nil, err
+		}
+		if len(schedule.Windows) > 0 {
+			schedules = append(schedules, schedule)
+		}
+	}
+
+	return schedules, nil
+}
+
+//schedulesActive checks if every one of the schedules is active at the Unix time t
+func schedulesActive(schedules []*Schedule, t uint64) bool {
+	for _, schedule := range schedules {
+		if !schedule.active(t) {
+			return false
+		}
+	}
+	return true
+}
+
+//scheduleActive checks if the schedules of a grant and of its chain let it be used at the Unix time t
+func scheduleActive(ctx contractapi.TransactionContextInterface, pck string, chain []string, t uint64) (bool, error) {
+	schedules, err := chainSchedules(ctx, pck, chain)
+	if err != nil {
+		return false, err
+	}
+	return schedulesActive(schedules, t), nil
+}
+
+//activeHours returns how many of the hours of a usage sample ending at the Unix time end fall in the
+//windows of the schedules, an hour counts when the schedules are active at its middle
+func activeHours(schedules []*Schedule, end uint64, hours uint64) uint64 {
+	if len(schedules) == 0 {
+		return hours
+	}
+
+	var active uint64
+	for h := uint64(0); h < hours && h*3600 < end; h++ {
+		if schedulesActive(schedules, end-h*3600-1800) {
+			active++
+		}
+	}
+	return active
+}
+
+//SetSchedule sets the schedule of the Delegation or SubDelegation with given Pck (Key), schedule is a JSON
+//object such as {"utcoffset":60,"windows":[{"kind":"weekly","days":["mon","tue","wed","thu","fri"],
+//"start":"09:00","end":"18:00"},{"kind":"monthly","day":1,"start":"22:00","end":"06:00"}]}. It must be
+//submitted for the grandor, no windows remove the schedule
+func (s *SmartContract) SetSchedule(ctx contractapi.TransactionContextInterface, pck string, schedule string) error {
+	grant, err := s.IsDelegation(ctx, pck)
+	if err != nil {
+		return err
+	}
+	if grant.Type != "D" && grant.Type != "SD" {
+		return fmt.Errorf("%s is not a Delegation or SubDelegation", pck)
+	}
+	if grant.Revoked {
+		return fmt.Errorf("%s has already been revoked", pck)
+	}
+	if err := s.authorizeParty(ctx, grant.Grandor); err != nil {
+		return err
+	}
+
+	newschedule := new(Schedule)
+	decoder := json.NewDecoder(bytes.NewReader([]byte(schedule)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(newschedule); err != nil {
+		return fmt.Errorf("schedule must be a JSON object. %s", err.Error())
+	}
+	if newschedule.UTCOffset < -14*60 || newschedule.UTCOffset > 14*60 {
+		return fmt.Errorf("utcoffset must be between -840 and 840 minutes")
+	}
+	for _, window := range newschedule.Windows {
+		if err := window.check(); err != nil {
+			return err
+		}
+	}
+
+	key, err := scheduleKey(ctx, pck)
+	if err != nil {
+		return err
+	}
+	newschedule.Grant = pck
+	newschedule.Type = "SC"
+	if newschedule.Windows == nil {
+		newschedule.Windows = []ScheduleWindow{}
+	}
+	scheduleAsBytes, _ := json.Marshal(newschedule)
+
+	if len(newschedule.Windows) == 0 {
+		err = ctx.GetStub().DelState(key)
+	} else {
+		err = ctx.GetStub().PutState(key, scheduleAsBytes)
+	}
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().SetEvent("ScheduleChanged", scheduleAsBytes)
+}
+
+//GetSchedule returns the schedule of the Delegation or SubDelegation with given Pck (Key), without
+//windows when the grant is active for its whole validity
+func (s *SmartContract) GetSchedule(ctx contractapi.TransactionContextInterface, pck string) (*Schedule, error) {
+	grant, err := s.IsDelegation(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+	if grant.Type != "D" && grant.Type != "SD" {
+		return nil, fmt.Errorf("%s is not a Delegation or SubDelegation", pck)
+	}
+
+	return grantSchedule(ctx, pck)
+}
+
+//NextActivation returns the Unix time from which the Delegation or SubDelegation with given Pck (Key) is
+//next active, now when it is active, its Issue when it is not valid yet and the opening of its next
+//window when it is scheduled. It returns 0 when the grant will not be active again within its validity
+//or the next two months
+func (s *SmartContract) NextActivation(ctx contractapi.TransactionContextInterface, pck string) (uint64, error) {
+	grant, err := s.IsDelegation(ctx, pck)
+	if err != nil {
+		return 0, err
+	}
+	if grant.Type != "D" && grant.Type != "SD" {
+		return 0, fmt.Errorf("%s is not a Delegation or SubDelegation", pck)
+	}
+	if grant.Revoked || grant.Declined {
+		return 0, nil
+	}
+	schedules, err := chainSchedules(ctx, pck, grant.DelegationChain)
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp, err := txSeconds(ctx)
+	if err != nil {
+		return 0, err
+	}
+	from := uint64(timestamp)
+	if grant.Issue > from {
+		from = grant.Issue
+	}
+
+	//the grant becomes active at from or when one of the windows of its schedules opens
+	candidates := []uint64{from}
+	for _, schedule := range schedules {
+		zone := time.FixedZone("", schedule.UTCOffset*60)
+		local := time.Unix(int64(from), 0).In(zone)
+		midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, zone)
+		for _, window := range schedule.Windows {
+			if window.Kind == windowOnce {
+				candidates = append(candidates, window.From)
+				continue
+			}
+			start, _ := clockMinutes(window.Start)
+			for d := 0; d <= scheduleHorizonDays; d++ {
+				day := midnight.AddDate(0, 0, d)
+				if window.opensOn(day) {
+					candidates = append(candidates, uint64(day.Add(time.Duration(start)*time.Minute).Unix()))
+				}
+			}
+		}
+	}
+
+	var next uint64
+	for _, candidate := range candidates {
+		if candidate < from || candidate >= grant.Expiry || (next != 0 && candidate >= next) {
+			continue
+		}
+		if schedulesActive(schedules, candidate) {
+			next = candidate
+		}
+	}
+
+	return next, nil
+}
+
+//--------------------------------------End Of Schedules---------------------------------------------
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+//***************************************************************************************************
+//**																							   **
+//**							The following section Manages the Capability Scopes                **
+//**                                                                                               **
+//***************************************************************************************************
+
+//-------------------------------------Capability Scopes---------------------------------------------
+//every Delegation and SubDelegation carries the scope of what it grants. A zero value or an empty
+//list means the grant is not restricted on that field. A SubDelegation can only narrow the scope of
+//the grant it comes from, the fields it leaves empty are inherited from its parent
+
+//Scope describes what a Delegation or SubDelegation allows its recipient to use
+type Scope struct {
+	ResourceType string   `json:"resourcetype"`                              //kind of resource granted, e.g. vm, container, storage
+	MaxCores     uint64   `json:"maxcores"`                                  //most cores the recipient can use
+	Memory       uint64   `json:"memory"`                                    //most memory in MB
+	Storage      uint64   `json:"storage"`                                   //most storage in GB
+	Regions      []string `json:"regions,omitempty" metadata:",optional"`    //regions the resources can be used in
+	Operations   []string `json:"operations,omitempty" metadata:",optional"` //operations the recipient can perform
+}
+
+//parseScope reads the scope argument of a transaction, an empty argument is an unrestricted scope
+func parseScope(scope string) (Scope, error) {
+	var result Scope
+	if scope == "" {
+		return result, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader([]byte(scope)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&result); err != nil {
+		return result, fmt.Errorf("scope must be a JSON object. %s", err.Error())
+	}
+
+	return result, nil
+}
+
+//narrowLimit returns the limit of the child, which cannot be above the limit of the parent
+func narrowLimit(name string, parent uint64, child uint64) (uint64, error) {
+	if child == 0 {
+		return parent, nil
+	}
+	if parent != 0 && child > parent {
+		return 0, fmt.Errorf("%s %d exceeds the %d of the previous delegation", name, child, parent)
+	}
+	return child, nil
+}
+
+//narrowList returns the list of the child, which must be part of the list of the parent
+func narrowList(name string, parent []string, child []string) ([]string, error) {
+	if len(child) == 0 {
+		return parent, nil
+	}
+	if len(parent) == 0 {
+		return child, nil
+	}
+	for _, x := range child {
+		if !stringInSlice(x, parent) {
+			return nil, fmt.Errorf("%s %s is not granted by the previous delegation", name, x)
+		}
+	}
+	return child, nil
+}
+
+//narrowScope checks that child is a subset of parent and returns the scope the child ends up with
+func narrowScope(parent Scope, child Scope) (Scope, error) {
+	var result Scope
+	var err error
+
+	switch {
+	case child.ResourceType == "":
+		result.ResourceType = parent.ResourceType
+	case parent.ResourceType == "" || parent.ResourceType == child.ResourceType:
+		result.ResourceType = child.ResourceType
+	default:
+		return result, fmt.Errorf("resource type %s is not granted by the previous delegation", child.ResourceType)
+	}
+
+	if result.MaxCores, err = narrowLimit("maxcores", parent.MaxCores, child.MaxCores); err != nil {
+		return result, err
+	}
+	if result.Memory, err = narrowLimit("memory", parent.Memory, child.Memory); err != nil {
+		return result, err
+	}
+	if result.Storage, err = narrowLimit("storage", parent.Storage, child.Storage); err != nil {
+		return result, err
+	}
+	if result.Regions, err = narrowList("region", parent.Regions, child.Regions); err != nil {
+		return result, err
+	}
+	if result.Operations, err = narrowList("operation", parent.Operations, child.Operations); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+//IsInScope checks if the scope of the grant with given Pck (Key) allows the operation in the region,
+//an empty operation or region is not checked
+func (s *SmartContract) IsInScope(ctx contractapi.TransactionContextInterface, pck string, operation string, region string) (bool, error) {
+	delegation, err := s.IsDelegation(ctx, pck)
+	if err != nil {
+		return false, err
+	}
+
+	if operation != "" && len(delegation.Scope.Operations) > 0 && !stringInSlice(operation, delegation.Scope.Operations) {
+		return false, nil
+	}
+	if region != "" && len(delegation.Scope.Regions) > 0 && !stringInSlice(region, delegation.Scope.Regions) {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+//--------------------------------------End Of Capability Scopes-------------------------------------
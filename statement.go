@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+//***************************************************************************************************
+//**																							   **
+//**							The following section Manages the Tenant Statements                **
+//**                                                                                               **
+//***************************************************************************************************
+
+//-------------------------------------Tenant Statements---------------------------------------------
+//a statement lists the charges of every SubDelegation a tenant receives, each one in the currency of
+//its service, and consolidates them in the currency the statement is asked in with the recorded
+//exchange rates. The converted amounts are summed unrounded and only the total is rounded
+
+//StatementLine is the charge of a single grant in a statement
+type StatementLine struct {
+	Grant     string `json:"grant"`     //pck of the SubDelegation
+	Service   string `json:"service"`   //service at the root of the grant
+	Charge    Money  `json:"charge"`    //charge in the currency of the service
+	Rate      int64  `json:"rate"`      //billionths of the statement currency for one unit of the service currency
+	Converted Money  `json:"converted"` //charge in the currency of the statement, unrounded
+}
+
+//Statement is the consolidated charges of a tenant in one currency
+type Statement struct {
+	Tenant   string          `json:"tenant"`
+	Currency string          `json:"currency"`
+	Lines    []StatementLine `json:"lines"`
+	Total    Money           `json:"total"` //rounded with the settings of the currency
+	Type     string          `json:"Type"`  //ST for Statement
+}
+
+//GetTenantStatement returns the charges of the tenant with given Pck (Key) consolidated in currency
+func (s *SmartContract) GetTenantStatement(ctx contractapi.TransactionContextInterface, pck string, currency string) (*Statement, error) {
+	tenant, err := s.IsTenant(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+	if tenant.Type != "T" {
+		return nil, fmt.Errorf("%s is not a Tenant", pck)
+	}
+
+	settings, err := s.GetCurrency(ctx, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	grants, err := s.receivedGrants(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+
+	statement := &Statement{
+		Tenant:   pck,
+		Currency: currency,
+		Lines:    []StatementLine{},
+		Total:    Money{Currency: currency},
+		Type:     "ST",
+	}
+
+	for _, grant := range grants {
+		delegation, err := s.IsDelegation(ctx, grant.Pck)
+		if err != nil {
+			return nil, err
+		}
+		//a grant the tenant has not accepted is not charged
+		if delegation.Pending || delegation.Declined {
+			continue
+		}
+
+		charge, err := s.ChargingDel(ctx, grant.Pck)
+		if err != nil {
+			return nil, err
+		}
+		service, err := s.rootService(ctx, delegation)
+		if err != nil {
+			return nil, err
+		}
+
+		exchangerate, err := s.GetExchangeRate(ctx, charge.Currency, currency)
+		if err != nil {
+			return nil, err
+		}
+		converted, err := charge.convert(currency, exchangerate.Rate)
+		if err != nil {
+			return nil, err
+		}
+
+		statement.Lines = append(statement.Lines, StatementLine{
+			Grant:     grant.Pck,
+			Service:   service.Pck,
+			Charge:    *charge,
+			Rate:      exchangerate.Rate,
+			Converted: converted,
+		})
+		statement.Total.Amount += converted.Amount
+	}
+
+	statement.Total = statement.Total.Round(settings.Decimals, settings.Rounding)
+
+	return statement, nil
+}
+
+//--------------------------------------End Of Tenant Statements-------------------------------------
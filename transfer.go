@@ -0,0 +1,362 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+//***************************************************************************************************
+//**																							   **
+//**							The following section Manages the Grant Transfers                  **
+//**                                                                                               **
+//***************************************************************************************************
+
+//-------------------------------------Grant Transfers-----------------------------------------------
+//a Delegation or SubDelegation can move to a new recipient instead of being revoked and recreated with
+//everything below it, when a tenant is merged or a team changes. Transfers move in two steps, the current
+//recipient asks for the transfer and the grandor approves it, then the grant is pending again until the
+//new recipient accepts it with AcceptGrant. The grant keeps its pck, so its usage,
+//schedule, capacity and subdelegations stay with it. The new recipient takes the place of the old one in
+//the Revokers and revocation policies of the grant and of every grant below it, and becomes the Grandor
+//of its direct SubDelegations. The usage so far is billed to the old payer before the new one takes over
+//the hold of the grant, and every transfer is kept as the history of the grant
+
+//object type of the composite key of the transfers, grant first so the history of a grant can be listed
+const transferObjectType = "transfer"
+
+//the states of a transfer
+const (
+	transferOpen      = "open"
+	transferCompleted = "completed"
+	transferRejected  = "rejected"
+)
+
+//GrantTransfer records a request to move a grant to a new recipient
+type GrantTransfer struct {
+	Grant       string `json:"grant"`       //pck of the Delegation or SubDelegation
+	From        string `json:"from"`        //recipient when the transfer was requested
+	To          string `json:"to"`          //new recipient
+	Status      string `json:"status"`      //open, completed or rejected
+	RequestedBy string `json:"requestedby"` //MSP of the identity that requested the transfer
+	Requested   int64  `json:"requested"`   //Unix seconds of the request
+	ClosedBy    string `json:"closedby"`    //MSP of the identity that approved or rejected it, empty while open
+	Closed      int64  `json:"closed"`      //Unix seconds it was approved or rejected, 0 while open
+	TxID        string `json:"txid"`        //transaction that requested the transfer
+	Type        string `json:"Type"`        //GT for GrantTransfer
+}
+
+//putTransfer stores a transfer under the time it was requested
+func putTransfer(ctx contractapi.TransactionContextInterface, transfer *GrantTransfer, event string) error {
+	key, err := ctx.GetStub().CreateCompositeKey(transferObjectType, []string{transfer.Grant, fmt.Sprintf("%020d", transfer.Requested), transfer.TxID})
+	if err != nil {
+		return fmt.Errorf("Failed to create the transfer key. %s", err.Error())
+	}
+
+	transferAsBytes, _ := json.Marshal(transfer)
+	if err := ctx.GetStub().PutState(key, transferAsBytes); err != nil {
+		return err
+	}
+
+	return ctx.GetStub().SetEvent(event, transferAsBytes)
+}
+
+//openTransfer returns the open transfer of a grant, nil when there is none
+func (s *SmartContract) openTransfer(ctx contractapi.TransactionContextInterface, pck string) (*GrantTransfer, error) {
+	transfers, err := s.GetTransfers(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+	for _, transfer := range transfers {
+		if transfer.Status == transferOpen {
+			return transfer, nil
+		}
+	}
+	return nil, nil
+}
+
+//transferableGrant returns the grant with given Pck (Key) after checking that it can be moved to recipient
+func (s *SmartContract) transferableGrant(ctx contractapi.TransactionContextInterface, pck string, recipient string) (*Delegation, error) {
+	grant, err := s.IsDelegation(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+	if grant.Type != "D" && grant.Type != "SD" {
+		return nil, fmt.Errorf("%s is not a Delegation or SubDelegation", pck)
+	}
+	if grant.Revoked {
+		return nil, fmt.Errorf("%s has already been revoked", pck)
+	}
+	if grant.Pending || grant.Declined {
+		return nil, fmt.Errorf("%s has not been accepted by its recipient", pck)
+	}
+
+	if recipient == grant.Recipient {
+		return nil, fmt.Errorf("%s is already the recipient of %s", recipient, pck)
+	}
+	if recipient == grant.Grandor {
+		return nil, fmt.Errorf("Cannot transfer %s to its own grandor", pck)
+	}
+	next, err := s.IsTenant(ctx, recipient)
+	if err != nil {
+		return nil, err
+	}
+	if !next.Registered {
+		return nil, fmt.Errorf("The new recipient %s is not registered", recipient)
+	}
+	grandor, err := s.IsTenant(ctx, grant.Grandor)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkGrantRule(ctx, grant.Type, grandor.Type, next.Type); err != nil {
+		return nil, err
+	}
+
+	//the new recipient becomes the grandor of the direct subdelegations, it cannot subdelegate to itself
+	allocations, err := s.GetAllocations(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+	for _, allocation := range allocations {
+		child, err := s.IsSubDelegation(ctx, allocation.Child)
+		if err != nil {
+			return nil, err
+		}
+		if child.Recipient == recipient && !child.Revoked {
+			return nil, fmt.Errorf("%s already receives %s from %s, it cannot be subdelegated to itself", recipient, child.Pck, pck)
+		}
+	}
+
+	return grant, nil
+}
+
+//settleGrant bills the usage of a grant so far to the tenant that paid for it and gives back what is
+//left of its hold, the usage after it is billed to the next payer. Billing may suspend the grant, which
+//is updated in place
+func (s *SmartContract) settleGrant(ctx contractapi.TransactionContextInterface, grant *Delegation, payer string) error {
+	if payer == "" {
+		return nil
+	}
+	wallet, err := s.findWallet(ctx, payer)
+	if err != nil {
+		return err
+	}
+	var hold *Hold
+	if wallet != nil {
+		if hold, err = findHold(ctx, payer, grant.Pck); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case grant.Pending || grant.Declined:
+		//a grant its recipient has not accepted was never charged, there is nothing to bill
+	case wallet != nil:
+		if err := s.billGrant(ctx, grant, wallet, hold); err != nil {
+			return err
+		}
+	default:
+		//a payer without a wallet is billed elsewhere, the next payer only pays from now on
+		charge, err := s.ChargingDel(ctx, grant.Pck)
+		if err != nil {
+			return err
+		}
+		key, err := ctx.GetStub().CreateCompositeKey(billedObjectType, []string{grant.Pck})
+		if err != nil {
+			return fmt.Errorf("Failed to create the billed key. %s", err.Error())
+		}
+		chargeAsBytes, _ := json.Marshal(charge)
+		if err := ctx.GetStub().PutState(key, chargeAsBytes); err != nil {
+			return err
+		}
+	}
+
+	//holds are only placed on wallets, what is left of the hold of the grant goes back to the wallet
+	if wallet == nil || hold == nil {
+		return nil
+	}
+	if _, err := captureHold(ctx, wallet, hold, hold.Amount.Amount); err != nil {
+		return err
+	}
+
+	return putWallet(ctx, wallet)
+}
+
+//TransferDelegation asks to move the Delegation or SubDelegation with given Pck (Key) to recipient, on
+//behalf of its current recipient. The grant stays with its current recipient until the grandor
+//approves the transfer with ApproveTransfer
+func (s *SmartContract) TransferDelegation(ctx contractapi.TransactionContextInterface, pck string, recipient string) error {
+	grant, err := s.transferableGrant(ctx, pck, recipient)
+	if err != nil {
+		return err
+	}
+	if err := s.authorizeRecipient(ctx, grant); err != nil {
+		return err
+	}
+	if err := checkAccessPolicy(ctx, "TransferDelegation", grant.Recipient); err != nil {
+		return err
+	}
+
+	open, err := s.openTransfer(ctx, pck)
+	if err != nil {
+		return err
+	}
+	if open != nil {
+		return fmt.Errorf("%s already has an open transfer to %s", pck, open.To)
+	}
+
+	mspid, err := clientMSPID(ctx)
+	if err != nil {
+		return err
+	}
+	now, err := txSeconds(ctx)
+	if err != nil {
+		return err
+	}
+
+	return putTransfer(ctx, &GrantTransfer{
+		Grant:       pck,
+		From:        grant.Recipient,
+		To:          recipient,
+		Status:      transferOpen,
+		RequestedBy: mspid,
+		Requested:   now,
+		TxID:        ctx.GetStub().GetTxID(),
+		Type:        "GT",
+	}, "TransferRequested")
+}
+
+//ApproveTransfer completes the open transfer of the grant with given Pck (Key) on behalf of its grandor.
+//The usage so far is billed to the old payer and the new payer must afford the rest of the grant. The grant
+//is pending until the new recipient accepts it with AcceptGrant, or declines it
+func (s *SmartContract) ApproveTransfer(ctx contractapi.TransactionContextInterface, pck string) error {
+	transfer, err := s.openTransfer(ctx, pck)
+	if err != nil {
+		return err
+	}
+	if transfer == nil {
+		return fmt.Errorf("%s has no open transfer", pck)
+	}
+
+	//the grant or the new recipient may have changed since the transfer was requested
+	grant, err := s.transferableGrant(ctx, pck, transfer.To)
+	if err != nil {
+		return err
+	}
+	if grant.Recipient != transfer.From {
+		return fmt.Errorf("%s has changed recipient since the transfer was requested", pck)
+	}
+	if err := s.authorizeParty(ctx, grant.Grandor); err != nil {
+		return err
+	}
+	if err := checkAccessPolicy(ctx, "ApproveTransfer", grant.Grandor); err != nil {
+		return err
+	}
+
+	oldpayer, err := s.payingTenant(ctx, grant.Recipient)
+	if err != nil {
+		return err
+	}
+	newpayer, err := s.payingTenant(ctx, transfer.To)
+	if err != nil {
+		return err
+	}
+	if oldpayer != newpayer {
+		if err := s.settleGrant(ctx, grant, oldpayer); err != nil {
+			return err
+		}
+
+		//the new payer holds what is left of the grant at the price it was granted at
+		service, err := s.rootService(ctx, grant)
+		if err != nil {
+			return err
+		}
+		price, err := s.grantPrice(ctx, pck, service)
+		if err != nil {
+			return err
+		}
+		now, err := txSeconds(ctx)
+		if err != nil {
+			return err
+		}
+		if err := s.admitGrant(ctx, pck, newpayer, price, grant.Scope, uint64(now), grant.Expiry); err != nil {
+			return err
+		}
+	}
+
+	//billing the usage may have suspended the grant, the transfer keeps its current state, and the new
+	//recipient has not accepted the grant yet. The grant billed above is moved as it is, a transaction
+	//does not read back what it wrote
+	subdelegation := (*SubDelegation)(grant)
+	subdelegation.Pending = true
+	if err := s.transferGrant(ctx, subdelegation, transfer.To, map[string]*SubDelegation{}); err != nil {
+		return err
+	}
+
+	if transfer.ClosedBy, err = clientMSPID(ctx); err != nil {
+		return err
+	}
+	if transfer.Closed, err = txSeconds(ctx); err != nil {
+		return err
+	}
+	transfer.Status = transferCompleted
+
+	return putTransfer(ctx, transfer, "GrantTransferred")
+}
+
+//RejectTransfer closes the open transfer of the grant with given Pck (Key) without moving it, on behalf
+//of its grandor or of the recipient that requested it
+func (s *SmartContract) RejectTransfer(ctx contractapi.TransactionContextInterface, pck string) error {
+	transfer, err := s.openTransfer(ctx, pck)
+	if err != nil {
+		return err
+	}
+	if transfer == nil {
+		return fmt.Errorf("%s has no open transfer", pck)
+	}
+	grant, err := s.IsDelegation(ctx, pck)
+	if err != nil {
+		return err
+	}
+	if s.authorizeParty(ctx, grant.Grandor) != nil {
+		if err := s.authorizeParty(ctx, transfer.From); err != nil {
+			return err
+		}
+	}
+
+	if transfer.ClosedBy, err = clientMSPID(ctx); err != nil {
+		return err
+	}
+	if transfer.Closed, err = txSeconds(ctx); err != nil {
+		return err
+	}
+	transfer.Status = transferRejected
+
+	return putTransfer(ctx, transfer, "TransferRejected")
+}
+
+//GetTransfers returns every transfer of the grant with given Pck (Key) in the order they were requested
+func (s *SmartContract) GetTransfers(ctx contractapi.TransactionContextInterface, pck string) ([]*GrantTransfer, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(transferObjectType, []string{pck})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	defer iterator.Close()
+
+	transfers := []*GrantTransfer{}
+	for iterator.HasNext() {
+		response, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+		}
+
+		transfer := new(GrantTransfer)
+		_ = json.Unmarshal(response.Value, transfer)
+		transfers = append(transfers, transfer)
+	}
+
+	return transfers, nil
+}
+
+//--------------------------------------End Of Grant Transfers---------------------------------------
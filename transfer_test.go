@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Kthanasis/Tenant-Service-Cloud-Management-using-Blockchain/client"
+)
+
+//transferLedger gives SD1 of D1 to T4 at 1 EUR per core and hour, with 2 core hours used. T4 pays from
+//a wallet in currency with deposit on it, T5 from a wallet in EUR
+func transferLedger(t *testing.T, currency string, deposit string) *ledger {
+	l := initLedger(t)
+	now := time.Now()
+	l.ok("SetExchangeRate", l.SetExchangeRate("EUR", "USD", "1"))
+	l.ok("OpenWallet", l.OpenWallet("T4", currency))
+	l.ok("OpenWallet", l.OpenWallet("T5", "EUR"))
+	_, err := l.Deposit("T4", deposit, "wire")
+	l.ok("Deposit", err)
+	_, err = l.Deposit("T5", "1000", "wire")
+	l.ok("Deposit", err)
+	l.ok("SetServicePrice", l.SetServicePrice("S1", "1"))
+
+	l.ok("RegisterDelegation", l.RegisterDelegation("D1", "S1", "S2", 3, now.Add(-3*time.Hour), now.Add(10*time.Hour), &client.Scope{MaxCores: 4}))
+	l.ok("AcceptGrant", l.AcceptGrant("D1"))
+	l.ok("RegisterSubDelegation", l.RegisterSubDelegation("SD1", "D1", "T4", 2, now.Add(-2*time.Hour), now.Add(5*time.Hour), &client.Scope{MaxCores: 2}))
+	l.ok("AcceptGrant", l.AcceptGrant("SD1"))
+	l.ok("RecordUsage", l.RecordUsage("SD1", client.UsageSample{SampleID: "u1", Timestamp: uint64(now.Add(-time.Hour).Unix()), Cores: 2, Hours: 1}))
+
+	return l
+}
+
+func TestApproveTransferSettlesTheOldPayer(t *testing.T) {
+	l := transferLedger(t, "EUR", "1000")
+	l.refused("a transfer to the grandor", l.TransferDelegation("SD1", "S2"))
+	l.refused("approving without an open transfer", l.ApproveTransfer("SD1"))
+	l.ok("TransferDelegation", l.TransferDelegation("SD1", "T5"))
+	l.refused("a second open transfer", l.TransferDelegation("SD1", "T6"))
+	l.ok("ApproveTransfer", l.ApproveTransfer("SD1"))
+
+	//the old payer paid the 2 core hours used and got back the rest of its hold
+	old, err := l.GetWallet("T4")
+	l.ok("GetWallet", err)
+	if old.Balance.String() != "998.00 EUR" || old.Held.Amount != 0 {
+		t.Fatalf("the old payer was left with %+v", old)
+	}
+	holds, err := l.GetHolds("T5")
+	l.ok("GetHolds", err)
+	if len(holds) != 1 || holds[0].HoldID != "SD1" {
+		t.Fatalf("the new payer holds %+v", holds)
+	}
+
+	sd1, err := l.IsSubDelegation("SD1")
+	l.ok("IsSubDelegation", err)
+	if sd1.Recipient != "T5" || !sd1.Pending {
+		t.Fatalf("SD1 after the transfer, %+v", sd1)
+	}
+	if valid, _ := l.IsSubValid("SD1"); valid {
+		t.Fatal("SD1 is valid before the new recipient accepts it")
+	}
+	l.ok("AcceptGrant", l.AcceptGrant("SD1"))
+	if valid, _ := l.IsSubValid("SD1"); !valid {
+		t.Fatal("SD1 is not valid once accepted")
+	}
+}
+
+func TestApproveTransferKeepsTheSuspensionOfTheFinalBill(t *testing.T) {
+	//the wallet of T4 covers SD1 at par, a dearer euro takes its final bill past its credit limit
+	l := transferLedger(t, "USD", "14")
+	l.ok("SetExchangeRate", l.SetExchangeRate("EUR", "USD", "20"))
+	l.ok("TransferDelegation", l.TransferDelegation("SD1", "T5"))
+	l.ok("ApproveTransfer", l.ApproveTransfer("SD1"))
+
+	sd1, err := l.IsSubDelegation("SD1")
+	l.ok("IsSubDelegation", err)
+	if !sd1.Suspended || !sd1.Pending || sd1.Recipient != "T5" {
+		t.Fatalf("SD1 after the transfer, %+v", sd1)
+	}
+	old, err := l.GetWallet("T4")
+	l.ok("GetWallet", err)
+	holds, err := l.GetHolds("T4")
+	l.ok("GetHolds", err)
+	if old.Balance.Amount >= 0 || old.Held.Amount != 0 || len(holds) != 0 {
+		t.Fatalf("the old payer was left with %+v and holds %+v", old, holds)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+//***************************************************************************************************
+//**																							   **
+//**							The following section Manages the Delegation Trees                 **
+//**                                                                                               **
+//***************************************************************************************************
+
+//-------------------------------------Delegation Tree-----------------------------------------------
+//the DelegationChain of every record only points upwards to the root, the tree below a grant is
+//rebuilt by following the allocations of the capacity ledger downwards
+
+//DelegationTreeNode describes a grant of a delegation tree together with the grants below it
+type DelegationTreeNode struct {
+	Pck         string                `json:"pck"`
+	Type        string                `json:"Type"` //D for Delegation, SD for SubDelegation
+	Grandor     string                `json:"grandor"`
+	Recipient   string                `json:"recipient"`
+	Status      string                `json:"status"` //active, pending, unaccepted, expired, suspended, revoked, declined, deregistered or inactive, by the grant itself
+	Valid       bool                  `json:"valid"`  //true if the grant and every grant above it give access now
+	Issue       uint64                `json:"issue"`
+	Expiry      uint64                `json:"expiry"`
+	MaxDepth    uint8                 `json:"maxdepth"`
+	MaxChildren uint8                 `json:"maxchildren"`
+	Consumed    uint8                 `json:"consumed"`
+	Available   uint8                 `json:"available"` //remaining budget of direct children
+	Children    []*DelegationTreeNode `json:"children"`
+}
+
+//grantStatus returns the state of a single grant without looking at the grants above it, a grant whose
+//grandor or recipient is deregistered is deregistered and one outside the windows of its schedule inactive
+func (s *SmartContract) grantStatus(ctx contractapi.TransactionContextInterface, delegation *Delegation, timenow uint64) (string, error) {
+	if delegation.Declined {
+		return "declined", nil
+	} else if delegation.Revoked {
+		return "revoked", nil
+	} else if delegation.Suspended {
+		return "suspended", nil
+	} else if delegation.Expiry <= timenow {
+		return "expired", nil
+	} else if delegation.Pending {
+		return "unaccepted", nil
+	} else if delegation.Issue > timenow {
+		return "pending", nil
+	}
+
+	registered, err := s.principalsRegistered(ctx, []string{delegation.Pck})
+	if err != nil {
+		return "", err
+	}
+	if !registered {
+		return "deregistered", nil
+	}
+	active, err := scheduleActive(ctx, delegation.Pck, []string{delegation.Pck}, timenow)
+	if err != nil {
+		return "", err
+	}
+	if !active {
+		return "inactive", nil
+	}
+	return "active", nil
+}
+
+//GetDelegationTree returns the complete tree of subdelegations under the grant with given Pck (Key)
+func (s *SmartContract) GetDelegationTree(ctx contractapi.TransactionContextInterface, pck string) (*DelegationTreeNode, error) {
+	delegation, err := s.IsDelegation(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp, err := txSeconds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	timenow := uint64(timestamp)
+
+	//the root of the requested tree can be a SubDelegation, then the grants above it are checked once
+	parentValid := true
+	for _, x := range delegation.DelegationChain {
+		if x == pck {
+			continue
+		}
+		temp, err := s.IsDelegation(ctx, x)
+		if err != nil {
+			return nil, err
+		}
+		status, err := s.grantStatus(ctx, temp, timenow)
+		if err != nil {
+			return nil, err
+		}
+		if status != "active" {
+			parentValid = false
+		}
+	}
+
+	return s.buildTreeNode(ctx, pck, timenow, parentValid)
+}
+
+//buildTreeNode builds the node of a grant and its children, parentValid is the validity of the
+//grants above it so the chain does not have to be read again for every node
+func (s *SmartContract) buildTreeNode(ctx contractapi.TransactionContextInterface, pck string, timenow uint64, parentValid bool) (*DelegationTreeNode, error) {
+	delegation, err := s.IsDelegation(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+
+	capacity, err := s.GetCapacity(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := s.grantStatus(ctx, delegation, timenow)
+	if err != nil {
+		return nil, err
+	}
+
+	node := &DelegationTreeNode{
+		Pck:         delegation.Pck,
+		Type:        delegation.Type,
+		Grandor:     delegation.Grandor,
+		Recipient:   delegation.Recipient,
+		Status:      status,
+		Valid:       parentValid && status == "active",
+		Issue:       delegation.Issue,
+		Expiry:      delegation.Expiry,
+		MaxDepth:    capacity.MaxDepth,
+		MaxChildren: capacity.MaxChildren,
+		Consumed:    capacity.Consumed,
+		Available:   capacity.Available,
+		Children:    []*DelegationTreeNode{},
+	}
+
+	allocations, err := s.GetAllocations(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, allocation := range allocations {
+		child, err := s.buildTreeNode(ctx, allocation.Child, timenow, node.Valid)
+		if err != nil {
+			return nil, err
+		}
+		node.Children = append(node.Children, child)
+	}
+
+	return node, nil
+}
+
+//--------------------------------------End Of Delegation Tree---------------------------------------
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+//***************************************************************************************************
+//**																							   **
+//**							The following section Manages the Usage Records                    **
+//**                                                                                               **
+//***************************************************************************************************
+
+//-------------------------------------Usage Records-------------------------------------------------
+//the resources a grant really uses are reported by the service that runs them. The organization that
+//owns the service at the root of the delegation chain submits periodic samples, each one signed by
+//the submitting identity as part of its transaction and recorded together with that identity. A sample
+//is stored once under its id and its period must not overlap the period of another sample of the grant,
+//so no hour can be counted twice. ChargingDel bills the cores and the storage of the recorded samples
+
+//object type of the composite key of the usage samples
+const usageObjectType = "usage"
+
+//UsageSample is the usage of a Delegation or SubDelegation over a period
+type UsageSample struct {
+	Grant     string `json:"grant"`     //pck of the Delegation or SubDelegation
+	SampleID  string `json:"sampleid"`  //id given by the service, unique per grant
+	Timestamp uint64 `json:"timestamp"` //end of the sampled period
+	Cores     uint64 `json:"cores"`     //cores used during the period
+	Hours     uint64 `json:"hours"`     //length of the period
+	Storage   uint64 `json:"storage"`   //storage in GB used during the period
+	Submitter string `json:"submitter"` //MSP of the identity that signed the sample
+	SignerID  string `json:"signerid"`  //id of the certificate that signed the sample
+	TxID      string `json:"txid"`      //transaction that recorded the sample
+	Type      string `json:"Type"`      //U for Usage
+}
+
+//rootService returns the Service at the root of the delegation chain of a grant
+func (s *SmartContract) rootService(ctx contractapi.TransactionContextInterface, delegation *Delegation) (*Service, error) {
+	rootpck := delegation.Pck
+	if len(delegation.DelegationChain) > 0 {
+		rootpck = delegation.DelegationChain[0]
+	}
+
+	root, err := s.IsDelegation(ctx, rootpck)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.IsService(ctx, root.Grandor)
+}
+
+//RecordUsage records a usage sample of the grant with given Pck (Key), only the organization that
+//owns the service at the root of the grant can report its usage
+func (s *SmartContract) RecordUsage(ctx contractapi.TransactionContextInterface, pck string, sampleid string, timestamp string, cores string, hours string, storage string) error {
+	delegation, err := s.IsDelegation(ctx, pck)
+	if err != nil {
+		return err
+	}
+	if delegation.Type != "D" && delegation.Type != "SD" {
+		return fmt.Errorf("%s is not a Delegation or SubDelegation", pck)
+	}
+	if sampleid == "" {
+		return fmt.Errorf("sampleid is required")
+	}
+
+	service, err := s.rootService(ctx, delegation)
+	if err != nil {
+		return err
+	}
+	if err := authorizeServiceOwner(ctx, service); err != nil {
+		return err
+	}
+
+	sample := UsageSample{
+		Grant:    pck,
+		SampleID: sampleid,
+		TxID:     ctx.GetStub().GetTxID(),
+		Type:     "U",
+	}
+	if sample.Timestamp, err = strconv.ParseUint(timestamp, 10, 64); err != nil {
+		return fmt.Errorf("timestamp must be a Unix time")
+	}
+	if sample.Cores, err = strconv.ParseUint(cores, 10, 64); err != nil {
+		return fmt.Errorf("cores must be a number")
+	}
+	if sample.Hours, err = strconv.ParseUint(hours, 10, 64); err != nil {
+		return fmt.Errorf("hours must be a number")
+	}
+	if sample.Storage, err = strconv.ParseUint(storage, 10, 64); err != nil {
+		return fmt.Errorf("storage must be a number")
+	}
+
+	//a grant the recipient has not accepted cannot be used
+	if delegation.Pending || delegation.Declined {
+		return fmt.Errorf("%s has not been accepted by its recipient", pck)
+	}
+
+	//the whole sampled period must fall in the validity window of the grant and stay within its scope
+	timenow, err := txSeconds(ctx)
+	if err != nil {
+		return err
+	}
+	if sample.Timestamp > uint64(timenow) {
+		return fmt.Errorf("Cannot record usage in the future")
+	}
+	//the hours are bounded by the validity first so they cannot wrap around when turned into seconds
+	if delegation.Expiry < delegation.Issue || sample.Hours > (delegation.Expiry-delegation.Issue)/3600 {
+		return fmt.Errorf("The sample of %s is outside the validity of the grant", pck)
+	}
+	if sample.Hours*3600 > sample.Timestamp || sample.Timestamp-sample.Hours*3600 < delegation.Issue || sample.Timestamp > delegation.Expiry {
+		return fmt.Errorf("The sample of %s is outside the validity of the grant", pck)
+	}
+	if delegation.Scope.MaxCores != 0 && sample.Cores > delegation.Scope.MaxCores {
+		return fmt.Errorf("%s grants at most %d cores", pck, delegation.Scope.MaxCores)
+	}
+	if delegation.Scope.Storage != 0 && sample.Storage > delegation.Scope.Storage {
+		return fmt.Errorf("%s grants at most %d storage", pck, delegation.Scope.Storage)
+	}
+
+	//deduplication by sample id
+	key, err := ctx.GetStub().CreateCompositeKey(usageObjectType, []string{pck, sampleid})
+	if err != nil {
+		return fmt.Errorf("Failed to create the usage key. %s", err.Error())
+	}
+	existing, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if existing != nil {
+		return fmt.Errorf("Sample %s of %s has already been recorded", sampleid, pck)
+	}
+
+	//the period of the sample must not overlap the period of a recorded sample
+	samples, err := s.GetUsage(ctx, pck)
+	if err != nil {
+		return err
+	}
+	start := sample.Timestamp - sample.Hours*3600
+	for _, recorded := range samples {
+		if start < recorded.Timestamp && recorded.Timestamp-recorded.Hours*3600 < sample.Timestamp {
+			return fmt.Errorf("The sample of %s overlaps sample %s", pck, recorded.SampleID)
+		}
+	}
+
+	if sample.Submitter, err = clientMSPID(ctx); err != nil {
+		return err
+	}
+	if sample.SignerID, err = cid.GetID(ctx.GetStub()); err != nil {
+		return fmt.Errorf("Failed to read the id of the client. %s", err.Error())
+	}
+
+	sampleAsBytes, _ := json.Marshal(sample)
+
+	return ctx.GetStub().PutState(key, sampleAsBytes)
+}
+
+//GetUsage returns every usage sample recorded for the grant with given Pck (Key)
+func (s *SmartContract) GetUsage(ctx contractapi.TransactionContextInterface, pck string) ([]*UsageSample, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(usageObjectType, []string{pck})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	defer iterator.Close()
+
+	samples := []*UsageSample{}
+	for iterator.HasNext() {
+		response, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+		}
+
+		sample := new(UsageSample)
+		_ = json.Unmarshal(response.Value, sample)
+
+		samples = append(samples, sample)
+	}
+
+	return samples, nil
+}
+
+//--------------------------------------End Of Usage Records-----------------------------------------
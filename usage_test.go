@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Kthanasis/Tenant-Service-Cloud-Management-using-Blockchain/client"
+)
+
+func TestRecordUsageKeepsSamplesInsideTheGrant(t *testing.T) {
+	l := initLedger(t)
+	now := time.Now()
+	l.ok("RegisterDelegation", l.RegisterDelegation("D1", "S1", "T1", 3, now.Add(-5*time.Hour), now.Add(time.Hour), nil))
+	l.ok("AcceptGrant", l.AcceptGrant("D1"))
+
+	start := uint64(now.Add(-time.Minute).Unix())
+	l.refused("a sample starting before the issue", l.RecordUsage("D1", client.UsageSample{SampleID: "early", Timestamp: start, Cores: 1, Hours: 6}))
+	l.ok("RecordUsage", l.RecordUsage("D1", client.UsageSample{SampleID: "a", Timestamp: start, Cores: 1, Hours: 2, Storage: 10}))
+	l.refused("an overlapping sample", l.RecordUsage("D1", client.UsageSample{SampleID: "b", Timestamp: start - 3600, Cores: 1, Hours: 2}))
+
+	//hours past 2^64/3600 wrap to a few seconds once converted
+	wrap := uint64(1<<64-1)/3600 + 1
+	l.refused("wrapping hours", l.RecordUsage("D1", client.UsageSample{SampleID: "wrap", Timestamp: start, Cores: 1, Hours: wrap}))
+}
+
+func TestChargingDelChargesStorageAndRefusesOverflow(t *testing.T) {
+	l := initLedger(t)
+	now := time.Now()
+	l.ok("RegisterDelegation", l.RegisterDelegation("D1", "S1", "S2", 3, now.Add(-3*time.Hour), now.Add(time.Hour), nil))
+	l.ok("AcceptGrant", l.AcceptGrant("D1"))
+	start := uint64(now.Add(-time.Minute).Unix())
+	l.ok("RecordUsage", l.RecordUsage("D1", client.UsageSample{SampleID: "a", Timestamp: start, Cores: 1, Hours: 1, Storage: 10}))
+
+	before, err := l.ChargingDel("D1")
+	l.ok("ChargingDel", err)
+	l.ok("SetServiceStoragePrice", l.SetServiceStoragePrice("S1", "0.5"))
+	after, err := l.ChargingDel("D1")
+	l.ok("ChargingDel", err)
+	if after.Amount <= before.Amount {
+		t.Fatalf("storage is not charged, %v then %v", before, after)
+	}
+
+	l.ok("RecordUsage", l.RecordUsage("D1", client.UsageSample{SampleID: "big", Timestamp: start - 3600, Cores: 1 << 62, Hours: 1}))
+	if charge, err := l.ChargingDel("D1"); err == nil {
+		t.Fatalf("an overflowing charge was returned, %v", charge)
+	}
+}
@@ -0,0 +1,740 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+//***************************************************************************************************
+//**																							   **
+//**							The following section Manages the Tenant Wallets                   **
+//**                                                                                               **
+//***************************************************************************************************
+
+//-------------------------------------Tenant Wallets------------------------------------------------
+//a tenant that opens a wallet pays in advance. Deposits raise its balance, debits against invoices and
+//the usage of its grants lower it, and the credit limit is how far below zero the balance may go.
+//Holds reserve part of the balance, every grant the tenant pays for holds its estimated cost until the
+//usage billed against it captures the hold. The held and available amounts are never stored, they are
+//recomputed from the holds. A grant is refused when its hold does not fit in what is available and is
+//suspended when its billed usage takes the balance past the credit limit. Tenants without a wallet are
+//billed as before, through their statements. A tenant opens its own wallet, deposits, debits, credit
+//limits and holds are managed by billing identities, and the hold of a grant only moves with its grant
+
+//object types of the composite keys of the wallets, their entries, their holds and the billed usage
+const (
+	walletObjectType      = "wallet"
+	walletEntryObjectType = "walletentry"
+	holdObjectType        = "hold"
+	billedObjectType      = "billed"
+)
+
+//the kinds of the wallet entries
+const (
+	entryDeposit = "deposit" //money paid in
+	entryDebit   = "debit"   //money taken against an invoice or another reference
+	entryUsage   = "usage"   //money taken for the billed usage of a grant
+)
+
+//Wallet is the prepaid balance of a tenant
+type Wallet struct {
+	Tenant      string `json:"tenant"`
+	Balance     Money  `json:"balance"`     //deposits minus debits, negative while on credit
+	CreditLimit Money  `json:"creditlimit"` //how far below zero the balance may go
+	Held        Money  `json:"held"`        //computed on read, sum of the holds
+	Available   Money  `json:"available"`   //computed on read, Balance - Held + CreditLimit
+	Type        string `json:"Type"`        //W for Wallet
+}
+
+//Hold is an amount reserved on a wallet
+type Hold struct {
+	Tenant    string `json:"tenant"`
+	HoldID    string `json:"holdid"` //pck of the grant or id given by the caller
+	Amount    Money  `json:"amount"` //what is still reserved, in the currency of the wallet
+	Reason    string `json:"reason"`
+	Timestamp int64  `json:"timestamp"` //Unix seconds of the transaction that placed the hold
+	Type      string `json:"Type"`      //H for Hold
+}
+
+//WalletEntry is a movement of the balance of a wallet
+type WalletEntry struct {
+	Tenant    string `json:"tenant"`
+	TxID      string `json:"txid"`      //transaction that moved the balance
+	Kind      string `json:"kind"`      //deposit, debit or usage
+	Amount    Money  `json:"amount"`    //positive for deposits, negative for debits
+	Reference string `json:"reference"` //invoice, grant or payment the entry is for
+	Balance   Money  `json:"balance"`   //balance after the entry
+	Timestamp int64  `json:"timestamp"` //Unix seconds of the transaction
+	Type      string `json:"Type"`      //WE for Wallet Entry
+}
+
+//walletKey returns the key of the wallet of a tenant
+func walletKey(ctx contractapi.TransactionContextInterface, tenant string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(walletObjectType, []string{tenant})
+	if err != nil {
+		return "", fmt.Errorf("Failed to create the wallet key. %s", err.Error())
+	}
+	return key, nil
+}
+
+//findWallet returns the wallet of a tenant with its held and available amounts, nil if it has none
+func (s *SmartContract) findWallet(ctx contractapi.TransactionContextInterface, tenant string) (*Wallet, error) {
+	key, err := walletKey(ctx, tenant)
+	if err != nil {
+		return nil, err
+	}
+	walletAsBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if walletAsBytes == nil {
+		return nil, nil
+	}
+
+	wallet := new(Wallet)
+	_ = json.Unmarshal(walletAsBytes, wallet)
+
+	holds, err := s.GetHolds(ctx, tenant)
+	if err != nil {
+		return nil, err
+	}
+	wallet.Held = Money{Currency: wallet.Balance.Currency}
+	for _, hold := range holds {
+		wallet.Held.Amount += hold.Amount.Amount
+	}
+	wallet.Available = Money{
+		Amount:   wallet.Balance.Amount - wallet.Held.Amount + wallet.CreditLimit.Amount,
+		Currency: wallet.Balance.Currency,
+	}
+
+	return wallet, nil
+}
+
+//putWallet stores a wallet, the computed amounts are left out
+func putWallet(ctx contractapi.TransactionContextInterface, wallet *Wallet) error {
+	key, err := walletKey(ctx, wallet.Tenant)
+	if err != nil {
+		return err
+	}
+
+	stored := *wallet
+	stored.Held, stored.Available = Money{}, Money{}
+	walletAsBytes, _ := json.Marshal(stored)
+
+	return ctx.GetStub().PutState(key, walletAsBytes)
+}
+
+//txSeconds returns the timestamp of the transaction in Unix seconds
+func txSeconds(ctx contractapi.TransactionContextInterface) (int64, error) {
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return 0, fmt.Errorf("Failed to read the transaction timestamp. %s", err.Error())
+	}
+	return timestamp.GetSeconds(), nil
+}
+
+//moveBalance adds amount to the balance of a wallet and records the entry of the movement
+func moveBalance(ctx contractapi.TransactionContextInterface, wallet *Wallet, kind string, amount int64, reference string) error {
+	wallet.Balance.Amount += amount
+	wallet.Available.Amount += amount
+
+	timestamp, err := txSeconds(ctx)
+	if err != nil {
+		return err
+	}
+
+	entry := WalletEntry{
+		Tenant:    wallet.Tenant,
+		TxID:      ctx.GetStub().GetTxID(),
+		Kind:      kind,
+		Amount:    Money{Amount: amount, Currency: wallet.Balance.Currency},
+		Reference: reference,
+		Balance:   wallet.Balance,
+		Timestamp: timestamp,
+		Type:      "WE",
+	}
+
+	//the padded timestamp keeps the entries of a wallet in the order they were made
+	key, err := ctx.GetStub().CreateCompositeKey(walletEntryObjectType, []string{wallet.Tenant, fmt.Sprintf("%020d", timestamp), entry.TxID})
+	if err != nil {
+		return fmt.Errorf("Failed to create the wallet entry key. %s", err.Error())
+	}
+	entryAsBytes, _ := json.Marshal(entry)
+	if err := ctx.GetStub().PutState(key, entryAsBytes); err != nil {
+		return err
+	}
+
+	return putWallet(ctx, wallet)
+}
+
+//toWallet converts an amount to the currency of a wallet with the recorded exchange rates
+func (s *SmartContract) toWallet(ctx contractapi.TransactionContextInterface, wallet *Wallet, amount Money) (Money, error) {
+	exchangerate, err := s.GetExchangeRate(ctx, amount.Currency, wallet.Balance.Currency)
+	if err != nil {
+		return Money{}, err
+	}
+	return amount.convert(wallet.Balance.Currency, exchangerate.Rate)
+}
+
+//registeredWallet returns the wallet of a registered tenant, failing when it has none
+func (s *SmartContract) registeredWallet(ctx contractapi.TransactionContextInterface, tenant string) (*Wallet, error) {
+	if err := s.registeredTenant(ctx, tenant); err != nil {
+		return nil, err
+	}
+	wallet, err := s.findWallet(ctx, tenant)
+	if err != nil {
+		return nil, err
+	}
+	if wallet == nil {
+		return nil, fmt.Errorf("%s has no wallet", tenant)
+	}
+	return wallet, nil
+}
+
+//OpenWallet opens a wallet in currency for the tenant with given Pck (Key), from then on the grants the
+//tenant pays for must fit in its balance
+func (s *SmartContract) OpenWallet(ctx contractapi.TransactionContextInterface, pck string, currency string) error {
+	if err := validCurrency(currency); err != nil {
+		return err
+	}
+	if err := s.registeredTenant(ctx, pck); err != nil {
+		return err
+	}
+	if err := s.authorizeTenant(ctx, pck); err != nil {
+		return err
+	}
+
+	wallet, err := s.findWallet(ctx, pck)
+	if err != nil {
+		return err
+	}
+	if wallet != nil {
+		return fmt.Errorf("%s already has a wallet", pck)
+	}
+
+	return putWallet(ctx, &Wallet{
+		Tenant:      pck,
+		Balance:     Money{Currency: currency},
+		CreditLimit: Money{Currency: currency},
+		Type:        "W",
+	})
+}
+
+//GetWallet returns the wallet of the tenant with given Pck (Key)
+func (s *SmartContract) GetWallet(ctx contractapi.TransactionContextInterface, pck string) (*Wallet, error) {
+	wallet, err := s.findWallet(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+	if wallet == nil {
+		return nil, fmt.Errorf("%s has no wallet", pck)
+	}
+	return wallet, nil
+}
+
+//Deposit pays amount into the wallet of the tenant with given Pck (Key), an amount in another currency
+//is converted with the recorded exchange rate
+func (s *SmartContract) Deposit(ctx contractapi.TransactionContextInterface, pck string, amount string, reference string) (*Wallet, error) {
+	if err := requireBilling(ctx); err != nil {
+		return nil, err
+	}
+	wallet, err := s.registeredWallet(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+
+	deposit, err := parseMoney(amount, wallet.Balance.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if deposit.Amount <= 0 {
+		return nil, fmt.Errorf("A deposit must be positive")
+	}
+	if deposit, err = s.toWallet(ctx, wallet, deposit); err != nil {
+		return nil, err
+	}
+
+	if err := moveBalance(ctx, wallet, entryDeposit, deposit.Amount, reference); err != nil {
+		return nil, err
+	}
+
+	return wallet, nil
+}
+
+//SetCreditLimit sets how far below zero the balance of the tenant with given Pck (Key) may go
+func (s *SmartContract) SetCreditLimit(ctx contractapi.TransactionContextInterface, pck string, limit string) (*Wallet, error) {
+	if err := requireBilling(ctx); err != nil {
+		return nil, err
+	}
+	wallet, err := s.registeredWallet(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+
+	creditlimit, err := parseMoney(limit, wallet.Balance.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if creditlimit.Currency != wallet.Balance.Currency {
+		return nil, fmt.Errorf("The wallet of %s is in %s", pck, wallet.Balance.Currency)
+	}
+	if creditlimit.Amount < 0 {
+		return nil, fmt.Errorf("A credit limit cannot be negative")
+	}
+
+	//lowering the limit below what the tenant already owes is allowed, its grants are suspended when billed
+	wallet.Available.Amount += creditlimit.Amount - wallet.CreditLimit.Amount
+	wallet.CreditLimit = creditlimit
+
+	if err := putWallet(ctx, wallet); err != nil {
+		return nil, err
+	}
+
+	return wallet, nil
+}
+
+//holdKey returns the key of a hold on the wallet of a tenant
+func holdKey(ctx contractapi.TransactionContextInterface, tenant string, holdid string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(holdObjectType, []string{tenant, holdid})
+	if err != nil {
+		return "", fmt.Errorf("Failed to create the hold key. %s", err.Error())
+	}
+	return key, nil
+}
+
+//findHold returns a hold on the wallet of a tenant, nil if there is none
+func findHold(ctx contractapi.TransactionContextInterface, tenant string, holdid string) (*Hold, error) {
+	key, err := holdKey(ctx, tenant, holdid)
+	if err != nil {
+		return nil, err
+	}
+	holdAsBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if holdAsBytes == nil {
+		return nil, nil
+	}
+
+	hold := new(Hold)
+	_ = json.Unmarshal(holdAsBytes, hold)
+
+	return hold, nil
+}
+
+//grantHold checks that holdid does not name a grant, the hold of a grant is placed when the grant is
+//registered and only captured or released as the grant is billed, settled or ends
+func (s *SmartContract) grantHold(ctx contractapi.TransactionContextInterface, holdid string) error {
+	entityAsBytes, err := ctx.GetStub().GetState(holdid)
+	if err != nil {
+		return fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if entityAsBytes == nil {
+		return nil
+	}
+
+	entity := new(Delegation)
+	_ = json.Unmarshal(entityAsBytes, entity)
+	if entity.Type == "D" || entity.Type == "SD" {
+		return fmt.Errorf("%s is a grant, its hold moves with the grant", holdid)
+	}
+
+	return nil
+}
+
+//putHold stores a hold, a hold with nothing left is removed
+func putHold(ctx contractapi.TransactionContextInterface, hold *Hold) error {
+	key, err := holdKey(ctx, hold.Tenant, hold.HoldID)
+	if err != nil {
+		return err
+	}
+	if hold.Amount.Amount <= 0 {
+		return ctx.GetStub().DelState(key)
+	}
+
+	holdAsBytes, _ := json.Marshal(hold)
+
+	return ctx.GetStub().PutState(key, holdAsBytes)
+}
+
+//placeHold reserves amount on a wallet, failing when it does not fit in what is available
+func placeHold(ctx contractapi.TransactionContextInterface, wallet *Wallet, holdid string, amount Money, reason string) error {
+	existing, err := findHold(ctx, wallet.Tenant, holdid)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("%s already has a hold %s", wallet.Tenant, holdid)
+	}
+	if amount.Amount > wallet.Available.Amount {
+		return fmt.Errorf("%s has %s available, %s cannot be held", wallet.Tenant, wallet.Available.String(), amount.String())
+	}
+
+	timestamp, err := txSeconds(ctx)
+	if err != nil {
+		return err
+	}
+
+	wallet.Held.Amount += amount.Amount
+	wallet.Available.Amount -= amount.Amount
+
+	return putHold(ctx, &Hold{Tenant: wallet.Tenant, HoldID: holdid, Amount: amount, Reason: reason, Timestamp: timestamp, Type: "H"})
+}
+
+//captureHold takes up to amount out of a hold as it is debited, returning what was captured. The hold is
+//updated in place, a transaction does not read back what it wrote so callers keep using the same hold
+func captureHold(ctx contractapi.TransactionContextInterface, wallet *Wallet, hold *Hold, amount int64) (int64, error) {
+	if hold == nil {
+		return 0, nil
+	}
+
+	captured := amount
+	if captured > hold.Amount.Amount {
+		captured = hold.Amount.Amount
+	}
+	hold.Amount.Amount -= captured
+	wallet.Held.Amount -= captured
+	wallet.Available.Amount += captured
+
+	return captured, putHold(ctx, hold)
+}
+
+//PlaceHold reserves amount on the wallet of the tenant with given Pck (Key) under holdid
+func (s *SmartContract) PlaceHold(ctx contractapi.TransactionContextInterface, pck string, holdid string, amount string, reason string) (*Wallet, error) {
+	if holdid == "" {
+		return nil, fmt.Errorf("holdid is required")
+	}
+	if err := requireBilling(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.grantHold(ctx, holdid); err != nil {
+		return nil, err
+	}
+	wallet, err := s.registeredWallet(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+
+	hold, err := parseMoney(amount, wallet.Balance.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if hold.Amount <= 0 {
+		return nil, fmt.Errorf("A hold must be positive")
+	}
+	if hold, err = s.toWallet(ctx, wallet, hold); err != nil {
+		return nil, err
+	}
+
+	if err := placeHold(ctx, wallet, holdid, hold, reason); err != nil {
+		return nil, err
+	}
+
+	return wallet, nil
+}
+
+//ReleaseHold gives back what is left of the hold holdid on the wallet of the tenant with given Pck (Key)
+func (s *SmartContract) ReleaseHold(ctx contractapi.TransactionContextInterface, pck string, holdid string) (*Wallet, error) {
+	if err := requireBilling(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.grantHold(ctx, holdid); err != nil {
+		return nil, err
+	}
+	wallet, err := s.GetWallet(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+
+	hold, err := findHold(ctx, pck, holdid)
+	if err != nil {
+		return nil, err
+	}
+	if hold == nil {
+		return nil, fmt.Errorf("%s has no hold %s", pck, holdid)
+	}
+
+	wallet.Held.Amount -= hold.Amount.Amount
+	wallet.Available.Amount += hold.Amount.Amount
+	hold.Amount.Amount = 0
+
+	if err := putHold(ctx, hold); err != nil {
+		return nil, err
+	}
+
+	return wallet, nil
+}
+
+//GetHolds returns every hold on the wallet of the tenant with given Pck (Key)
+func (s *SmartContract) GetHolds(ctx contractapi.TransactionContextInterface, pck string) ([]*Hold, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(holdObjectType, []string{pck})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	defer iterator.Close()
+
+	holds := []*Hold{}
+	for iterator.HasNext() {
+		response, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+		}
+
+		hold := new(Hold)
+		_ = json.Unmarshal(response.Value, hold)
+
+		holds = append(holds, hold)
+	}
+
+	return holds, nil
+}
+
+//Debit takes amount out of the wallet of the tenant with given Pck (Key) against reference, such as an
+//invoice. A hold placed under the same reference is captured first and the debit fails when it does
+//not fit in what is available
+func (s *SmartContract) Debit(ctx contractapi.TransactionContextInterface, pck string, amount string, reference string) (*Wallet, error) {
+	if reference == "" {
+		return nil, fmt.Errorf("reference is required")
+	}
+	if err := requireBilling(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.grantHold(ctx, reference); err != nil {
+		return nil, err
+	}
+	wallet, err := s.registeredWallet(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+
+	debit, err := parseMoney(amount, wallet.Balance.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if debit.Amount <= 0 {
+		return nil, fmt.Errorf("A debit must be positive")
+	}
+
+	if err := s.debitWallet(ctx, wallet, debit, reference); err != nil {
+		return nil, err
+	}
+
+	return wallet, nil
+}
+
+//debitWallet takes amount out of a wallet against reference, capturing the hold of the reference first
+func (s *SmartContract) debitWallet(ctx contractapi.TransactionContextInterface, wallet *Wallet, amount Money, reference string) error {
+	debit, err := s.toWallet(ctx, wallet, amount)
+	if err != nil {
+		return err
+	}
+
+	hold, err := findHold(ctx, wallet.Tenant, reference)
+	if err != nil {
+		return err
+	}
+	if _, err := captureHold(ctx, wallet, hold, debit.Amount); err != nil {
+		return err
+	}
+	if debit.Amount > wallet.Available.Amount {
+		return fmt.Errorf("%s has %s available, %s cannot be debited", wallet.Tenant, wallet.Available.String(), debit.String())
+	}
+
+	return moveBalance(ctx, wallet, entryDebit, -debit.Amount, reference)
+}
+
+//GetWalletEntries returns the movements of the wallet of the tenant with given Pck (Key), oldest first
+func (s *SmartContract) GetWalletEntries(ctx contractapi.TransactionContextInterface, pck string) ([]*WalletEntry, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(walletEntryObjectType, []string{pck})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	defer iterator.Close()
+
+	entries := []*WalletEntry{}
+	for iterator.HasNext() {
+		response, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+		}
+
+		entry := new(WalletEntry)
+		_ = json.Unmarshal(response.Value, entry)
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+//payingTenant returns the tenant that pays for a grant, whatever kind of grant it is a tenant recipient
+//pays itself, the owner pays for a service and the owner of the organization for a group
+func (s *SmartContract) payingTenant(ctx contractapi.TransactionContextInterface, recipient string) (string, error) {
+	if owner, isgroup, err := s.groupPayer(ctx, recipient); isgroup {
+		return owner, err
+	}
+
+	service, err := s.IsService(ctx, recipient)
+	if err != nil {
+		return "", err
+	}
+	if service.Type != "S" {
+		return recipient, nil
+	}
+
+	return service.Owner, nil
+}
+
+//grantEstimate is the most a grant can cost, its price per core and hour for the cores of its scope
+//over what is left of its validity at now. Grants without a core limit cost nothing in advance
+func grantEstimate(price Money, scope Scope, issue uint64, expiry uint64, now uint64) (Money, error) {
+	start := now
+	if issue > start {
+		start = issue
+	}
+	if scope.MaxCores == 0 || expiry <= start {
+		return Money{Currency: price.Currency}, nil
+	}
+
+	return price.coreTime(scope.MaxCores, expiry-start)
+}
+
+//admitGrant holds the estimated cost of a new grant on the wallet of the tenant that pays for it and
+//refuses the grant when the hold does not fit in what is available
+func (s *SmartContract) admitGrant(ctx contractapi.TransactionContextInterface, pck string, payer string, price Money, scope Scope, issue uint64, expiry uint64) error {
+	if payer == "" {
+		return nil
+	}
+	wallet, err := s.findWallet(ctx, payer)
+	if err != nil || wallet == nil {
+		return err
+	}
+
+	now, err := txSeconds(ctx)
+	if err != nil {
+		return err
+	}
+	estimate, err := grantEstimate(price, scope, issue, expiry, uint64(now))
+	if err != nil {
+		return err
+	}
+	if estimate, err = s.toWallet(ctx, wallet, estimate); err != nil {
+		return err
+	}
+
+	if estimate.Amount > wallet.Available.Amount || wallet.Available.Amount < 0 {
+		return fmt.Errorf("%s has %s available, %s needs %s", payer, wallet.Available.String(), pck, estimate.String())
+	}
+	if estimate.Amount == 0 {
+		return nil
+	}
+
+	return placeHold(ctx, wallet, pck, estimate, "grant")
+}
+
+//BillUsage debits the wallet of the tenant that pays for the grant with given Pck (Key) with the usage
+//charged since it was last billed. The hold of the grant is captured first and released once the grant
+//no longer holds its slot, and the grant is suspended when the debit takes the balance past the credit
+//limit
+func (s *SmartContract) BillUsage(ctx contractapi.TransactionContextInterface, pck string) (*Wallet, error) {
+	delegation, err := s.IsDelegation(ctx, pck)
+	if err != nil {
+		return nil, err
+	}
+	if delegation.Type != "D" && delegation.Type != "SD" {
+		return nil, fmt.Errorf("%s is not a Delegation or SubDelegation", pck)
+	}
+
+	payer, err := s.payingTenant(ctx, delegation.Recipient)
+	if err != nil {
+		return nil, err
+	}
+	wallet, err := s.findWallet(ctx, payer)
+	if err != nil {
+		return nil, err
+	}
+	if wallet == nil {
+		return nil, fmt.Errorf("%s is not paid from a wallet", pck)
+	}
+	hold, err := findHold(ctx, payer, pck)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.billGrant(ctx, delegation, wallet, hold); err != nil {
+		return nil, err
+	}
+
+	return wallet, nil
+}
+
+//billGrant debits wallet with the usage of delegation charged since it was last billed, capturing hold
+//first. The grant, the wallet and the hold are updated in place for the caller to carry on with them
+func (s *SmartContract) billGrant(ctx contractapi.TransactionContextInterface, delegation *Delegation, wallet *Wallet, hold *Hold) error {
+	pck := delegation.Pck
+	charge, err := s.ChargingDel(ctx, pck)
+	if err != nil {
+		return err
+	}
+
+	//what was billed before is kept in the currency of the service
+	key, err := ctx.GetStub().CreateCompositeKey(billedObjectType, []string{pck})
+	if err != nil {
+		return fmt.Errorf("Failed to create the billed key. %s", err.Error())
+	}
+	billedAsBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	billed := Money{Currency: charge.Currency}
+	if billedAsBytes != nil {
+		_ = json.Unmarshal(billedAsBytes, &billed)
+	}
+
+	if charge.Amount > billed.Amount {
+		due, err := s.toWallet(ctx, wallet, Money{Amount: charge.Amount - billed.Amount, Currency: charge.Currency})
+		if err != nil {
+			return err
+		}
+		if _, err := captureHold(ctx, wallet, hold, due.Amount); err != nil {
+			return err
+		}
+		if err := moveBalance(ctx, wallet, entryUsage, -due.Amount, pck); err != nil {
+			return err
+		}
+
+		billedAsBytes, _ = json.Marshal(charge)
+		if err := ctx.GetStub().PutState(key, billedAsBytes); err != nil {
+			return err
+		}
+	}
+
+	timenow, err := txSeconds(ctx)
+	if err != nil {
+		return err
+	}
+	if delegation.Suspended || delegation.Revoked || delegation.Declined || delegation.Expiry <= uint64(timenow) {
+		//nothing more can be used, whatever is left of the hold is given back
+		if _, err := captureHold(ctx, wallet, hold, wallet.Held.Amount); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	if wallet.Balance.Amount+wallet.CreditLimit.Amount < 0 {
+		delegation.Suspended = true
+		delegationAsBytes, _ := json.Marshal(delegation)
+		if err := ctx.GetStub().PutState(pck, delegationAsBytes); err != nil {
+			return err
+		}
+		if err := ctx.GetStub().SetEvent("GrantSuspendedForBalance", delegationAsBytes); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//--------------------------------------End Of Tenant Wallets----------------------------------------
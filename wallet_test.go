@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Kthanasis/Tenant-Service-Cloud-Management-using-Blockchain/client"
+)
+
+func TestWalletsNeedTheBillingRole(t *testing.T) {
+	l := initLedger(t)
+	l.ok("OpenWallet", l.OpenWallet("T4", "EUR"))
+	biller := certificate(t, "bill", false, "")
+
+	l.as("Org2MSP", biller)
+	me, err := l.WhoAmI()
+	l.ok("WhoAmI", err)
+	for what, call := range map[string]func() error{
+		"a deposit":       func() error { _, err := l.Deposit("T4", "100", "wire"); return err },
+		"a credit limit":  func() error { _, err := l.SetCreditLimit("T4", "100"); return err },
+		"a debit":         func() error { _, err := l.Debit("T4", "1", "INV1"); return err },
+		"a hold":          func() error { _, err := l.PlaceHold("T4", "H1", "1", ""); return err },
+		"a released hold": func() error { _, err := l.ReleaseHold("T4", "H1"); return err },
+	} {
+		l.refused(what+" without the billing role", call())
+	}
+
+	l.asAdmin()
+	l.ok("AssignRole billing", l.AssignRole(me.Identity, "Org2MSP", "billing", ""))
+	l.as("Org2MSP", biller)
+	wallet, err := l.Deposit("T4", "100", "wire")
+	l.ok("Deposit", err)
+	if wallet.Balance.String() != "100.00 EUR" {
+		t.Fatalf("a deposit of 100 left %v", wallet.Balance)
+	}
+}
+
+func TestTheHoldOfAGrantOnlyMovesWithTheGrant(t *testing.T) {
+	l := initLedger(t)
+	l.ok("OpenWallet", l.OpenWallet("T4", "EUR"))
+	_, err := l.Deposit("T4", "100", "wire")
+	l.ok("Deposit", err)
+	now := time.Now()
+	l.ok("RegisterDelegation", l.RegisterDelegation("D1", "S1", "T4", 1, now.Add(-time.Hour), now.Add(time.Hour), &client.Scope{MaxCores: 1}))
+
+	holds, err := l.GetHolds("T4")
+	l.ok("GetHolds", err)
+	if len(holds) != 1 || holds[0].HoldID != "D1" {
+		t.Fatalf("the grant holds %+v", holds)
+	}
+	_, err = l.ReleaseHold("T4", "D1")
+	l.refused("releasing the hold of a grant by hand", err)
+	_, err = l.PlaceHold("T4", "D1", "1", "")
+	l.refused("a hold under the pck of a grant", err)
+	_, err = l.Debit("T4", "1", "D1")
+	l.refused("a debit against the hold of a grant", err)
+}